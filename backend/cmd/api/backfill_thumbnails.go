@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"manpower-backend/internal/config"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/thumbnail"
+)
+
+// runBackfillThumbnails implements `api backfill-thumbnails`: generate
+// thumbnails and previews for stored files that don't have them yet.
+//
+//	api backfill-thumbnails -dry-run
+//	api backfill-thumbnails
+//
+// It uses the same STORAGE and PDF_RENDERER settings as the server, and is
+// safe to re-run: files that already have every variant are skipped.
+// Returns the exit code.
+func runBackfillThumbnails(args []string) int {
+	fs := flag.NewFlagSet("backfill-thumbnails", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only count the files missing thumbnails")
+	timeout := fs.Duration("timeout", 2*time.Hour, "give up after this long")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
+		return 1
+	}
+	db := database.New(&cfg.DB)
+	defer db.Close()
+
+	store, err := openStore(os.Getenv("STORAGE"), os.Getenv, cfg)
+	if err == nil {
+		store, err = encryptStore(store, db.GetPool(), cfg)
+	}
+	if err != nil {
+		log.Printf("Storage: %v", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	gen := thumbnail.New(db.GetPool(), store, cfg.Upload.PDFRenderer)
+	report, err := gen.Backfill(ctx, *dryRun)
+	if report != nil {
+		for _, e := range report.Errors {
+			fmt.Println(e)
+		}
+		if report.DryRun {
+			fmt.Printf("%d file(s) need thumbnails (dry run — nothing was written)\n", report.Files)
+		} else {
+			fmt.Printf("%d file(s): %d generated, %d unsupported, %d missing, %d failed\n",
+				report.Files, report.Generated, report.Unsupported, report.Missing, report.Failed)
+		}
+		if !gen.RendersPDF() {
+			fmt.Println("No PDF renderer found: PDFs were counted as unsupported (install poppler-utils or set PDF_RENDERER)")
+		}
+	}
+	if err != nil {
+		log.Printf("Thumbnail backfill failed: %v", err)
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -19,43 +19,75 @@ import (
 	"manpower-backend/internal/config"
 	"manpower-backend/internal/cron"
 	"manpower-backend/internal/database"
+	"manpower-backend/internal/filegc"
+	"manpower-backend/internal/filescan"
 	"manpower-backend/internal/handlers"
+	"manpower-backend/internal/keyring"
 	"manpower-backend/internal/middleware"
-	"manpower-backend/internal/storage"
+	"manpower-backend/internal/permissions"
+	"manpower-backend/internal/resumable"
+	"manpower-backend/internal/thumbnail"
+	"manpower-backend/internal/watermark"
 )
 
 func main() {
+	// Maintenance subcommands run instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate-storage":
+			os.Exit(runMigrateStorage(os.Args[2:]))
+		case "backfill-thumbnails":
+			os.Exit(runBackfillThumbnails(os.Args[2:]))
+		case "encrypt-storage":
+			os.Exit(runEncryptStorage(os.Args[2:]))
+		case "rotate-storage-keys":
+			os.Exit(runRotateStorageKeys(os.Args[2:]))
+		}
+	}
+
 	// 1. Load configuration from environment
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// JWT signing/verification keys (key files, or the shared secret as a fallback)
+	keys, err := keyring.Load(keyring.Options{
+		Dir:             cfg.JWT.KeysDir,
+		SigningKeyID:    cfg.JWT.SigningKeyID,
+		ActivationDelay: cfg.JWT.ActivationDelay,
+		HMACSecret:      cfg.JWTSecret,
+		HMACVerifyUntil: cfg.JWT.HS256Until,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	keys.StartAutoReload(time.Minute)
+
 	// 2. Connect to PostgreSQL
 	db := database.New(&cfg.DB)
 	defer db.Close()
 
-	// 3. Initialize file storage (R2 in production, local filesystem for dev)
-	var fileStore storage.Store
-	if os.Getenv("STORAGE") == "r2" {
-		fileStore, err = storage.NewR2Store(
-			os.Getenv("R2_ACCOUNT_ID"),
-			os.Getenv("R2_ACCESS_KEY"),
-			os.Getenv("R2_SECRET_KEY"),
-			os.Getenv("R2_BUCKET"),
-			os.Getenv("R2_PUBLIC_URL"),
-		)
-		if err != nil {
-			log.Fatalf("Failed to initialize R2 storage: %v", err)
-		}
-		log.Println("Using Cloudflare R2 storage")
-	} else {
-		fileStore, err = storage.NewLocalStore(cfg.Upload.Dir, cfg.Upload.BaseURL)
-		if err != nil {
-			log.Fatalf("Failed to initialize local storage: %v", err)
-		}
-		log.Println("Using local file storage")
+	// 3. Initialize file storage (R2 or any S3-compatible store in production, local filesystem for dev)
+	fileStore, err := openStore(os.Getenv("STORAGE"), os.Getenv, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize file storage: %v", err)
+	}
+	fileStore, err = encryptStore(fileStore, db.GetPool(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize file encryption: %v", err)
+	}
+	scanner, err := openScanner(cfg.Scan)
+	if err != nil {
+		log.Fatalf("Failed to initialize malware scanner: %v", err)
 	}
+	fileScans := filescan.New(db.GetPool(), fileStore, scanner)
+	thumbnails := thumbnail.New(db.GetPool(), fileStore, cfg.Upload.PDFRenderer)
+	uploadSessions := resumable.New(db.GetPool(), fileStore, cfg.Upload.SessionTTL)
+	fileCollector := filegc.New(db.GetPool(), fileStore, filegc.Options{
+		GraceAge:      cfg.FileGC.GraceAge,
+		QuarantineAge: cfg.FileGC.QuarantineAge,
+	})
 
 	// 4. Set up router with global middleware
 	r := chi.NewRouter()
@@ -70,27 +102,39 @@ func main() {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   corsOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Upload-Offset"},
+		ExposedHeaders:   []string{"Link", "Location", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	// 5. Initialize handlers with their dependencies
-	authHandler := handlers.NewAuthHandler(db, cfg.JWTSecret)
+	authHandler := handlers.NewAuthHandler(db, keys)
 	dashboardHandler := handlers.NewDashboardHandler(db)
-	employeeHandler := handlers.NewEmployeeHandler(db)
-	documentHandler := handlers.NewDocumentHandler(db)
-	companyHandler := handlers.NewCompanyHandler(db)
-	uploadHandler := handlers.NewUploadHandler(fileStore)
+	employeeHandler := handlers.NewEmployeeHandler(db, fileStore, cfg.Upload.URLTTL)
+	uploadHandler := handlers.NewUploadHandler(db, fileStore, fileScans, thumbnails, fileCollector, uploadSessions, cfg.Upload.SizeLimits, cfg.Upload.URLTTL)
+	documentHandler := handlers.NewDocumentHandler(db, fileStore, fileScans, watermark.New(cfg.Upload.PDFRenderer), uploadHandler, cfg.Upload.URLTTL)
+	companyHandler := handlers.NewCompanyHandler(db, fileStore, cfg.Upload.URLTTL)
+	extractHandler := handlers.NewExtractHandler(openOCR(cfg))
 	salaryHandler := handlers.NewSalaryHandler(db)
 	activityHandler := handlers.NewActivityHandler(db)
 	notificationHandler := handlers.NewNotificationHandler(db)
 	adminHandler := handlers.NewAdminHandler(db)
 	userMgmtHandler := handlers.NewUserManagementHandler(db)
+	roleHandler := handlers.NewRoleHandler(db)
+	loginEventHandler := handlers.NewLoginEventHandler(db)
+	sessionHandler := handlers.NewSessionHandler(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	ssoHandler := handlers.NewSSOHandler(db, authHandler, cfg.OIDC)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	impersonationHandler := handlers.NewImpersonationHandler(db, authHandler)
+	fileGCHandler := handlers.NewFileGCHandler(db, fileCollector)
 
 	// Start background cron jobs
 	cron.StartNotifier(db)
+	cron.StartFileGC(fileCollector, cfg.FileGC.Interval)
+	cron.StartFileScan(fileScans, cfg.Scan.Interval)
+	cron.StartUploadExpiry(uploadSessions)
 
 	// 6. Public routes (no authentication required)
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -100,34 +144,43 @@ func main() {
 		json.NewEncoder(w).Encode(db.Health())
 	})
 
-	// Auth routes — public (login and register don't need a token)
+	// Auth routes — public (login, register and SSO don't need a token)
 	// Rate-limited to prevent brute-force and registration spam
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.RateLimit(rate.Every(12*time.Second), 5)) // ~5 req/min per IP
 		r.Post("/api/auth/login", authHandler.Login)
 	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RateLimit(rate.Every(6*time.Second), 10)) // ~10 req/min per IP
+		r.Get("/api/auth/oidc/login", ssoHandler.Login)
+		r.Get("/api/auth/oidc/callback", ssoHandler.Callback)
+	})
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.RateLimit(rate.Every(20*time.Second), 3)) // ~3 req/min per IP
 		r.Post("/api/auth/register", authHandler.Register)
 	})
 
-	// Serve uploaded files (local storage serves from disk; R2 redirects to CDN)
+	r.Get("/api/auth/providers", ssoHandler.Providers)
+	r.Get("/.well-known/jwks.json", jwksHandler.Serve)
+
+	// Signed file URLs for local storage (R2 hands out presigned URLs instead).
+	// No token needed: the HMAC signature was minted after a scope check.
 	r.Get("/api/files/*", uploadHandler.ServeFile)
 
-	// 7. Protected routes (require valid JWT + inject company scope)
+	// 7. Protected routes (require valid JWT or API key, resolve permissions, inject company scope)
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.Auth(cfg.JWTSecret))
+		r.Use(middleware.Auth(keys, db.GetPool()))
+		r.Use(middleware.LoadPermissions(db.GetPool()))
 		r.Use(middleware.InjectCompanyScope(db.GetPool()))
 
-		// ── Read endpoints (all roles, company-scoped via handlers) ────
+		// ── Self-service (all authenticated users) ─────────────────────
 		r.Get("/api/auth/me", authHandler.GetMe)
-		r.Post("/api/upload", uploadHandler.Upload)
-
-		// Dashboard
-		r.Get("/api/dashboard/metrics", dashboardHandler.GetMetrics)
-		r.Get("/api/dashboard/expiring", dashboardHandler.GetExpiryAlerts)
-		r.Get("/api/dashboard/company-summary", dashboardHandler.GetCompanySummary)
-		r.Get("/api/dashboard/compliance", dashboardHandler.GetComplianceStats)
+		r.Delete("/api/admin/impersonate", impersonationHandler.Stop)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireDirectLogin)
+			r.Get("/api/auth/sessions", sessionHandler.List)
+			r.Delete("/api/auth/sessions/{id}", sessionHandler.Revoke)
+		})
 
 		// Notifications (user-scoped)
 		r.Get("/api/notifications", notificationHandler.List)
@@ -135,85 +188,178 @@ func main() {
 		r.Patch("/api/notifications/read-all", notificationHandler.MarkAllRead)
 		r.Patch("/api/notifications/{id}/read", notificationHandler.MarkRead)
 
-		// Activity log
-		r.Get("/api/activity", activityHandler.List)
-
-		// Companies (read)
-		r.Get("/api/companies", companyHandler.List)
-		r.Get("/api/companies/{id}", companyHandler.GetByID)
-
-		// Employees (read)
-		r.Get("/api/employees", employeeHandler.List)
-		r.Get("/api/employees/export", employeeHandler.Export)
-		r.Route("/api/employees/{id}", func(r chi.Router) {
-			r.Get("/", employeeHandler.GetByID)
-			r.Get("/documents", documentHandler.ListByEmployee)
-			r.Get("/dependency-alerts", dashboardHandler.GetDependencyAlerts)
-			r.Get("/salary", salaryHandler.ListByEmployee)
-		})
-
-		// Salary & documents (read)
-		r.Get("/api/salary", salaryHandler.List)
-		r.Get("/api/salary/summary", salaryHandler.Summary)
-		r.Get("/api/salary/export", salaryHandler.Export)
-		r.Get("/api/documents/{id}", documentHandler.GetByID)
-		r.Get("/api/documents/{id}/download", documentHandler.Download)
-
 		// Document types (read — needed for forms)
 		r.Get("/api/document-types", adminHandler.ListDocumentTypes)
 
-		// ── Writer endpoints (company_owner + admin + super_admin) ──────
+		// ── Dashboard & activity ────────────────────────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.DashboardRead))
+			r.Get("/api/dashboard/metrics", dashboardHandler.GetMetrics)
+			r.Get("/api/dashboard/expiring", dashboardHandler.GetExpiryAlerts)
+			r.Get("/api/dashboard/company-summary", dashboardHandler.GetCompanySummary)
+			r.Get("/api/dashboard/compliance", dashboardHandler.GetComplianceStats)
+		})
+		r.With(middleware.RequirePermission(permissions.ActivityRead)).
+			Get("/api/activity", activityHandler.List)
+
+		// ── Companies (scoped via handlers) ─────────────────────────────
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.RequireMinRole("company_owner"))
+			r.Use(middleware.RequirePermission(permissions.CompaniesRead))
+			r.Get("/api/companies", companyHandler.List)
+			r.Get("/api/companies/{id}", companyHandler.GetByID)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.CompaniesWrite))
+			r.Post("/api/companies", companyHandler.Create)
+			r.Put("/api/companies/{id}", companyHandler.Update)
+			r.Delete("/api/companies/{id}", companyHandler.Delete)
+		})
 
-			// Employee write (scoped via handler checks)
+		// ── Employees (scoped via handler checks) ───────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.EmployeesRead))
+			r.Get("/api/employees", employeeHandler.List)
+			r.Get("/api/employees/{id}", employeeHandler.GetByID)
+			r.Get("/api/employees/{id}/employment", employeeHandler.Employment)
+			r.Get("/api/employees/{id}/history", employeeHandler.History)
+			r.Get("/api/employees/{id}/dependency-alerts", dashboardHandler.GetDependencyAlerts)
+		})
+		r.With(middleware.RequirePermission(permissions.EmployeesExport)).
+			Get("/api/employees/export", employeeHandler.Export)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.EmployeesWrite))
 			r.Post("/api/employees", employeeHandler.Create)
+			r.Post("/api/employees/import", employeeHandler.Import)
 			r.Put("/api/employees/{id}", employeeHandler.Update)
+			r.Patch("/api/employees/{id}/exit", employeeHandler.Exit)
+			r.Post("/api/employees/{id}/transfer", employeeHandler.Transfer)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.EmployeesDelete))
 			r.Delete("/api/employees/{id}", employeeHandler.Delete)
 			r.Post("/api/employees/batch-delete", employeeHandler.BatchDelete)
-			r.Patch("/api/employees/{id}/exit", employeeHandler.Exit)
+		})
 
-			// Document write
+		// ── Documents ───────────────────────────────────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.DocumentsRead))
+			r.Get("/api/employees/{id}/documents", documentHandler.ListByEmployee)
+			r.Get("/api/documents/{id}", documentHandler.GetByID)
+			r.Get("/api/documents/{id}/history", documentHandler.History)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.DocumentsDownload))
+			r.Get("/api/documents/{id}/download", documentHandler.Download)
+			r.Get("/api/documents/{id}/file-url", documentHandler.FileURL)
+			r.Get("/api/documents/{id}/downloads", documentHandler.Downloads)
+			r.Post("/api/documents/archive", documentHandler.Archive)
+		})
+		r.With(middleware.RequirePermission(permissions.DocumentsExport)).
+			Get("/api/documents/export", documentHandler.Export)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.FilesUpload))
+			r.Post("/api/upload", uploadHandler.Upload)
+			r.Get("/api/upload/{sha256}", uploadHandler.Lookup)
+			r.Post("/api/uploads", uploadHandler.CreateSession)
+			r.Get("/api/uploads/{id}", uploadHandler.GetSession)
+			r.Patch("/api/uploads/{id}", uploadHandler.PatchSession)
+			r.Post("/api/uploads/{id}/finalize", uploadHandler.FinalizeSession)
+			r.Delete("/api/uploads/{id}", uploadHandler.DeleteSession)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.DocumentsWrite))
 			r.Post("/api/employees/{employeeId}/documents", documentHandler.Create)
+			r.Put("/api/documents/{id}", documentHandler.Update)
+			r.Post("/api/documents/{id}/renew", documentHandler.Renew)
+			r.Post("/api/documents/{id}/files", documentHandler.AddFile)
+			r.Put("/api/documents/{id}/files/order", documentHandler.ReorderFiles)
+			r.Delete("/api/documents/{id}/files/{fileId}", documentHandler.RemoveFile)
+			r.Post("/api/documents/import", documentHandler.Import)
+			r.Post("/api/documents/extract", extractHandler.Extract)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.DocumentsDelete))
+			r.Delete("/api/documents/{id}", documentHandler.Delete)
 			r.Post("/api/documents/batch-delete", documentHandler.BatchDelete)
-			r.Route("/api/documents/{id}", func(r chi.Router) {
-				r.Put("/", documentHandler.Update)
-				r.Delete("/", documentHandler.Delete)
-				r.Post("/renew", documentHandler.Renew)
-			})
-
-			// Salary write
-			r.Post("/api/salary/generate", salaryHandler.Generate)
+		})
+
+		// ── Salary ──────────────────────────────────────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.SalaryRead))
+			r.Get("/api/salary", salaryHandler.List)
+			r.Get("/api/salary/summary", salaryHandler.Summary)
+			r.Get("/api/employees/{id}/salary", salaryHandler.ListByEmployee)
+		})
+		r.With(middleware.RequirePermission(permissions.SalaryExport)).
+			Get("/api/salary/export", salaryHandler.Export)
+		r.With(middleware.RequirePermission(permissions.SalaryWrite)).
+			Post("/api/salary/generate", salaryHandler.Generate)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.SalaryApprove))
 			r.Patch("/api/salary/bulk-status", salaryHandler.BulkUpdateStatus)
 			r.Patch("/api/salary/{id}/status", salaryHandler.UpdateStatus)
 		})
 
-		// ── Admin endpoints (admin + super_admin) ──────────────────────
+		// ── API keys (not with a key or while impersonating) ──────────
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.RequireMinRole("admin"))
-
-			// Company write (admin-only)
-			r.Post("/api/companies", companyHandler.Create)
-			r.Put("/api/companies/{id}", companyHandler.Update)
-			r.Delete("/api/companies/{id}", companyHandler.Delete)
+			r.Use(middleware.RequireDirectLogin)
+			r.Use(middleware.RequirePermission(permissions.APIKeysManage))
+			r.Get("/api/api-keys", apiKeyHandler.List)
+			r.Post("/api/api-keys", apiKeyHandler.Create)
+			r.Delete("/api/api-keys/{id}", apiKeyHandler.Revoke)
+		})
 
-			// User management
+		// ── User management ─────────────────────────────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireDirectLogin)
+			r.Use(middleware.RequirePermission(permissions.UsersManage))
 			r.Get("/api/users", userMgmtHandler.List)
 			r.Put("/api/users/{id}/role", userMgmtHandler.UpdateRole)
 			r.Delete("/api/users/{id}", userMgmtHandler.Delete)
+			r.Post("/api/users/{id}/unlock", userMgmtHandler.Unlock)
+			r.Delete("/api/users/{id}/sessions", userMgmtHandler.RevokeSessions)
 			r.Get("/api/users/{id}/companies", userMgmtHandler.GetUserCompanies)
 			r.Put("/api/users/{id}/companies", userMgmtHandler.SetUserCompanies)
+			r.Get("/api/roles", roleHandler.List)
+			r.Get("/api/permissions", roleHandler.ListPermissions)
+			r.Get("/api/admin/login-events", loginEventHandler.List)
+		})
+
+		// ── Impersonation (support) ────────────────────────────────────
+		r.With(middleware.RequireDirectLogin, middleware.RequirePermission(permissions.UsersImpersonate)).
+			Post("/api/admin/impersonate/{userId}", impersonationHandler.Start)
+
+		// ── Role management ─────────────────────────────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireDirectLogin)
+			r.Use(middleware.RequirePermission(permissions.RolesManage))
+			r.Post("/api/roles", roleHandler.Create)
+			r.Put("/api/roles/{name}", roleHandler.Update)
+			r.Delete("/api/roles/{name}", roleHandler.Delete)
+		})
+
+		// ── Admin settings ──────────────────────────────────────────────
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequirePermission(permissions.SettingsManage))
+
+			// Sign-in methods (SSO / password fallback)
+			r.With(middleware.RequireDirectLogin).Get("/api/admin/auth-settings", ssoHandler.GetSettings)
+			r.With(middleware.RequireDirectLogin).Put("/api/admin/auth-settings", ssoHandler.UpdateSettings)
+
+			// Orphaned file cleanup
+			r.Get("/api/admin/storage/gc", fileGCHandler.ListRuns)
+			r.Post("/api/admin/storage/gc", fileGCHandler.Run)
 
-			// Admin settings: document types
+			// Document types
 			r.Post("/api/admin/document-types", adminHandler.CreateDocumentType)
 			r.Put("/api/admin/document-types/{id}", adminHandler.UpdateDocumentType)
 			r.Delete("/api/admin/document-types/{id}", adminHandler.DeleteDocumentType)
 
-			// Admin settings: compliance rules
+			// Compliance rules
 			r.Get("/api/admin/compliance-rules", adminHandler.ListComplianceRules)
 			r.Put("/api/admin/compliance-rules", adminHandler.UpsertComplianceRules)
 
-			// Admin settings: document dependencies
+			// Document dependencies
 			r.Get("/api/admin/dependencies", adminHandler.ListDependencies)
 			r.Post("/api/admin/dependencies", adminHandler.CreateDependency)
 			r.Put("/api/admin/dependencies/{id}", adminHandler.UpdateDependency)
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"manpower-backend/internal/config"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/filemigrate"
+)
+
+// runMigrateStorage implements `api migrate-storage`: copy every file the
+// database references from one store to another and repoint the references.
+//
+//	api migrate-storage -from local -to r2 -dry-run
+//	api migrate-storage -from local -to s3 -report migration.json
+//
+// The source store is configured by the usual variables (UPLOAD_DIR, R2_*,
+// S3_*); the destination by the same names prefixed with DEST_ (e.g.
+// DEST_S3_BUCKET), falling back to the unprefixed value. Returns the exit code.
+func runMigrateStorage(args []string) int {
+	fs := flag.NewFlagSet("migrate-storage", flag.ContinueOnError)
+	from := fs.String("from", "local", "source storage: local, r2 or s3")
+	to := fs.String("to", "", "destination storage: local, r2 or s3")
+	dryRun := fs.Bool("dry-run", false, "check every file without copying or touching the database")
+	reportPath := fs.String("report", "", "also write the full report as JSON to this file")
+	timeout := fs.Duration("timeout", 2*time.Hour, "give up after this long")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *to == "" {
+		fmt.Fprintln(os.Stderr, "migrate-storage: -to is required")
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
+		return 1
+	}
+
+	db := database.New(&cfg.DB)
+	defer db.Close()
+
+	// With encryption on, files are decrypted from the source and
+	// re-encrypted (under new data keys) for the destination
+	src, err := openStore(*from, os.Getenv, cfg)
+	if err == nil {
+		src, err = encryptStore(src, db.GetPool(), cfg)
+	}
+	if err != nil {
+		log.Printf("Source: %v", err)
+		return 1
+	}
+	dst, err := openStore(*to, destEnv, cfg)
+	if err == nil {
+		dst, err = encryptStore(dst, db.GetPool(), cfg)
+	}
+	if err != nil {
+		log.Printf("Destination: %v", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report, err := filemigrate.Run(ctx, db.GetPool(), src, dst, filemigrate.Options{DryRun: *dryRun})
+	if report != nil {
+		printReport(report)
+		if *reportPath != "" {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			if werr := os.WriteFile(*reportPath, data, 0644); werr != nil {
+				log.Printf("Failed to write report: %v", werr)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Storage migration failed: %v", err)
+		return 1
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// destEnv reads DEST_<key>, falling back to <key>.
+func destEnv(key string) string {
+	if v, ok := os.LookupEnv("DEST_" + key); ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// printReport lists problem files, then the totals.
+func printReport(r *filemigrate.Report) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	problems := 0
+	for _, it := range r.Items {
+		switch it.Status {
+		case filemigrate.StatusCopied, filemigrate.StatusPresent, filemigrate.StatusWouldCopy:
+			continue
+		}
+		if problems == 0 {
+			fmt.Fprintln(tw, "STATUS\tKEY\tREFERENCED BY\tDETAIL")
+		}
+		problems++
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", it.Status, it.Key, it.Sources, it.Error)
+	}
+	tw.Flush()
+
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run — nothing was written)"
+	}
+	fmt.Printf("\n%d file(s)%s: %d copied, %d already present, %d to copy, %d missing, %d mismatched, %d errors\n",
+		len(r.Items), mode,
+		r.Counts[filemigrate.StatusCopied], r.Counts[filemigrate.StatusPresent], r.Counts[filemigrate.StatusWouldCopy],
+		r.Counts[filemigrate.StatusMissing], r.Counts[filemigrate.StatusMismatch], r.Counts[filemigrate.StatusError])
+	if !r.DryRun {
+		fmt.Printf("%d bytes copied, %d database reference(s) rewritten\n", r.Bytes, r.RowsUpdated)
+	}
+}
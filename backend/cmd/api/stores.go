@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/config"
+	"manpower-backend/internal/filecrypt"
+	"manpower-backend/internal/filescan"
+	"manpower-backend/internal/ocr"
+	"manpower-backend/internal/storage"
+)
+
+// openStore builds a file store of the given kind ("local", "r2" or "s3").
+// Settings come from env, so the same code serves the API (os.Getenv) and
+// the destination side of migrate-storage (DEST_-prefixed variables).
+func openStore(kind string, env func(string) string, cfg *config.Config) (storage.Store, error) {
+	switch kind {
+	case "r2":
+		store, err := storage.NewR2Store(
+			env("R2_ACCOUNT_ID"),
+			env("R2_ACCESS_KEY"),
+			env("R2_SECRET_KEY"),
+			env("R2_BUCKET"),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("initialize R2 storage: %w", err)
+		}
+		log.Printf("Using Cloudflare R2 storage (bucket %s)", env("R2_BUCKET"))
+		return store, nil
+	case "s3":
+		store, err := storage.NewS3Store(storage.S3Options{
+			Endpoint:     env("S3_ENDPOINT"),
+			Region:       env("S3_REGION"),
+			Bucket:       env("S3_BUCKET"),
+			AccessKey:    env("S3_ACCESS_KEY"),
+			SecretKey:    env("S3_SECRET_KEY"),
+			UsePathStyle: env("S3_USE_PATH_STYLE") == "true",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("initialize S3 storage: %w", err)
+		}
+		log.Printf("Using S3-compatible storage (bucket %s)", env("S3_BUCKET"))
+		return store, nil
+	case "", "local":
+		dir := cfg.Upload.Dir
+		if v := env("UPLOAD_DIR"); v != "" {
+			dir = v
+		}
+		store, err := storage.NewLocalStore(dir, cfg.Upload.BaseURL, []byte(cfg.Upload.URLSecret))
+		if err != nil {
+			return nil, fmt.Errorf("initialize local storage: %w", err)
+		}
+		log.Printf("Using local file storage (%s)", dir)
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q (want local, r2 or s3)", kind)
+	}
+}
+
+// encryptStore wraps store with encryption at rest when
+// STORAGE_ENCRYPTION_KEYS is set, and returns it unchanged otherwise.
+// Existing plaintext files stay readable either way.
+func encryptStore(store storage.Store, pool *pgxpool.Pool, cfg *config.Config) (storage.Store, error) {
+	if !cfg.Crypt.Enabled() {
+		return store, nil
+	}
+	keys, err := filecrypt.ParseMasterKeys(cfg.Crypt.MasterKeys, cfg.Crypt.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_ENCRYPTION_KEYS: %w", err)
+	}
+	urls, err := storage.NewURLSigner(cfg.Upload.BaseURL, []byte(cfg.Upload.URLSecret))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Encrypting stored files (master key %s)", keys.Primary())
+	return filecrypt.New(store, pool, keys, urls), nil
+}
+
+// openScanner builds the malware scanner selected by FILE_SCANNER.
+func openScanner(cfg config.ScanConfig) (filescan.Scanner, error) {
+	if cfg.Driver != "clamd" {
+		log.Println("Malware scanning disabled (FILE_SCANNER=none): uploads are marked clean")
+		return filescan.Noop{}, nil
+	}
+	clamd, err := filescan.NewClamd(cfg.ClamdAddress, cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("initialize clamd scanner: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := clamd.Ping(ctx); err != nil {
+		// Not fatal: uploads stay pending until clamd is back
+		log.Printf("WARNING: clamd at %s is not reachable: %v", cfg.ClamdAddress, err)
+	} else {
+		log.Printf("Scanning uploads with clamd at %s", cfg.ClamdAddress)
+	}
+	return clamd, nil
+}
+
+// openOCR builds the document text reader selected by OCR_ENGINE. PDFs
+// with a text layer are read without an engine.
+func openOCR(cfg *config.Config) *ocr.Reader {
+	var engine ocr.Engine = ocr.None{}
+	if cfg.OCR.Engine == "tesseract" {
+		t, err := ocr.NewTesseract(cfg.OCR.TesseractPath, cfg.OCR.Languages)
+		if err != nil {
+			// Not fatal: extraction falls back to PDF text layers
+			log.Printf("WARNING: OCR disabled: %v", err)
+		} else {
+			log.Printf("Extracting document fields with tesseract (%s)", cfg.OCR.Languages)
+			engine = t
+		}
+	}
+	return ocr.NewReader(engine, cfg.OCR.PDFText, cfg.Upload.PDFRenderer)
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,7 +15,13 @@ type Config struct {
 	Port      string
 	DB        DBConfig
 	JWTSecret string
+	JWT       JWTConfig
 	Upload    UploadConfig
+	FileGC    FileGCConfig
+	Scan      ScanConfig
+	Crypt     EncryptionConfig
+	OCR       OCRConfig
+	OIDC      OIDCConfig
 }
 
 // DBConfig holds PostgreSQL connection details.
@@ -27,8 +36,77 @@ type DBConfig struct {
 
 // UploadConfig holds file upload settings.
 type UploadConfig struct {
-	Dir     string // Local directory for file uploads
-	BaseURL string // URL prefix for serving uploaded files
+	Dir       string        // Local directory for file uploads
+	BaseURL   string        // URL prefix for serving uploaded files
+	URLSecret string        // HMAC key for local signed file URLs
+	URLTTL    time.Duration // lifetime of signed file URLs
+
+	PDFRenderer string // pdftoppm binary for PDF previews; "" disables them
+
+	SizeLimits map[string]int64 // per-category maximum file size in bytes
+	SessionTTL time.Duration    // resumable uploads expire this long after their last chunk
+}
+
+// FileGCConfig holds orphaned file cleanup settings (see package filegc).
+type FileGCConfig struct {
+	Interval      time.Duration // how often the scheduled run happens; 0 disables it
+	GraceAge      time.Duration // leave unreferenced files younger than this (in-flight uploads)
+	QuarantineAge time.Duration // delete quarantined files older than this
+}
+
+// ScanConfig holds malware scanning settings (see package filescan).
+type ScanConfig struct {
+	Driver       string        // "clamd", or "none" to mark every file clean
+	ClamdAddress string        // tcp://host:port or unix:///path/to/clamd.sock
+	Timeout      time.Duration // per-file scan timeout
+	Interval     time.Duration // how often pending files are rescanned; 0 disables it
+}
+
+// EncryptionConfig holds encryption at rest settings (see package
+// filecrypt). Files are stored in plaintext when MasterKeys is empty.
+type EncryptionConfig struct {
+	MasterKeys string // "id:base64key,..." — 32-byte AES keys
+	KeyID      string // master key that wraps new data keys; default the first listed
+}
+
+// Enabled reports whether stored files are encrypted.
+func (c EncryptionConfig) Enabled() bool {
+	return c.MasterKeys != ""
+}
+
+// OCRConfig holds document text extraction settings (see package ocr).
+type OCRConfig struct {
+	Engine        string // "tesseract", or "none" to read PDF text layers only
+	TesseractPath string // tesseract binary
+	Languages     string // tesseract -l argument, e.g. "eng" or "eng+mrz"
+	PDFText       string // pdftotext binary for PDF text layers; "" disables them
+}
+
+// JWTConfig holds asymmetric signing key settings (see package keyring).
+type JWTConfig struct {
+	KeysDir         string        // directory of <kid>.pem key files
+	SigningKeyID    string        // optional: force a specific key to sign
+	ActivationDelay time.Duration // new keys only verify for this long before signing
+	HS256Until      time.Time     // JWT_SECRET stops verifying tokens after this, once key files exist; zero for never
+}
+
+// OIDCConfig holds single sign-on settings. SSO is enabled when both
+// IssuerURL and ClientID are set.
+type OIDCConfig struct {
+	IssuerURL       string
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string   // this API's callback, registered at the IdP
+	Scopes          []string // default: openid email profile
+	DisplayName     string   // label for the login button
+	JITProvisioning bool     // create unknown users on first sign-in
+	DefaultRole     string   // role given to JIT-provisioned users
+	PostLoginURL    string   // frontend page that receives the token
+}
+
+// Enabled reports whether an identity provider is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
 }
 
 // Load reads configuration from environment variables (with .env fallback).
@@ -48,8 +126,9 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Upload: UploadConfig{
-			Dir:     getEnv("UPLOAD_DIR", "./uploads"),
-			BaseURL: "", // Set below after port is known
+			Dir:         getEnv("UPLOAD_DIR", "./uploads"),
+			BaseURL:     "", // Set below after port is known
+			PDFRenderer: getEnv("PDF_RENDERER", "pdftoppm"),
 		},
 	}
 
@@ -58,12 +137,105 @@ func Load() (*Config, error) {
 		fmt.Sprintf("http://localhost:%s/api/files", cfg.Port),
 	)
 
+	// Signed file URLs: a dedicated secret, falling back to the JWT secret
+	cfg.Upload.URLSecret = getEnv("FILE_URL_SECRET", cfg.JWTSecret)
+	urlTTL, err := time.ParseDuration(getEnv("FILE_URL_TTL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FILE_URL_TTL: %w", err)
+	}
+	cfg.Upload.URLTTL = urlTTL
+
+	// Per-category size limits, in MB: "documents=50,photos=5"
+	cfg.Upload.SizeLimits = map[string]int64{}
+	for _, item := range strings.Split(getEnv("UPLOAD_SIZE_LIMITS", "documents=50,photos=5"), ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		category, mb, ok := strings.Cut(item, "=")
+		n, err := strconv.ParseInt(strings.TrimSpace(mb), 10, 64)
+		if !ok || err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid UPLOAD_SIZE_LIMITS entry %q: want category=megabytes", item)
+		}
+		cfg.Upload.SizeLimits[strings.TrimSpace(category)] = n << 20
+	}
+
+	for _, d := range []struct {
+		env, def string
+		dst      *time.Duration
+	}{
+		{"FILE_GC_INTERVAL", "24h", &cfg.FileGC.Interval},
+		{"FILE_GC_GRACE_AGE", "24h", &cfg.FileGC.GraceAge},
+		{"FILE_GC_QUARANTINE_AGE", "168h", &cfg.FileGC.QuarantineAge},
+		{"CLAMD_TIMEOUT", "60s", &cfg.Scan.Timeout},
+		{"FILE_SCAN_INTERVAL", "5m", &cfg.Scan.Interval},
+		{"UPLOAD_SESSION_TTL", "24h", &cfg.Upload.SessionTTL},
+	} {
+		v, err := time.ParseDuration(getEnv(d.env, d.def))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", d.env, err)
+		}
+		*d.dst = v
+	}
+
+	cfg.Scan.Driver = getEnv("FILE_SCANNER", "none")
+	cfg.Scan.ClamdAddress = getEnv("CLAMD_ADDRESS", "tcp://localhost:3310")
+	if cfg.Scan.Driver != "none" && cfg.Scan.Driver != "clamd" {
+		return nil, fmt.Errorf("invalid FILE_SCANNER %q: want clamd or none", cfg.Scan.Driver)
+	}
+
+	cfg.Crypt = EncryptionConfig{
+		MasterKeys: getEnv("STORAGE_ENCRYPTION_KEYS", ""),
+		KeyID:      getEnv("STORAGE_ENCRYPTION_KEY_ID", ""),
+	}
+
+	cfg.OCR = OCRConfig{
+		Engine:        getEnv("OCR_ENGINE", "none"),
+		TesseractPath: getEnv("TESSERACT_PATH", "tesseract"),
+		Languages:     getEnv("OCR_LANGUAGES", "eng"),
+		PDFText:       getEnv("PDF_TEXT_EXTRACTOR", "pdftotext"),
+	}
+	if cfg.OCR.Engine != "none" && cfg.OCR.Engine != "tesseract" {
+		return nil, fmt.Errorf("invalid OCR_ENGINE %q: want tesseract or none", cfg.OCR.Engine)
+	}
+
+	activation, err := time.ParseDuration(getEnv("JWT_KEY_ACTIVATION_DELAY", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEY_ACTIVATION_DELAY: %w", err)
+	}
+	cfg.JWT = JWTConfig{
+		KeysDir:         getEnv("JWT_KEYS_DIR", ""),
+		SigningKeyID:    getEnv("JWT_SIGNING_KEY_ID", ""),
+		ActivationDelay: activation,
+	}
+	if v := getEnv("JWT_HS256_VERIFY_UNTIL", ""); v != "" {
+		if cfg.JWT.HS256Until, err = time.Parse(time.RFC3339, v); err != nil {
+			if cfg.JWT.HS256Until, err = time.Parse("2006-01-02", v); err != nil {
+				return nil, fmt.Errorf("invalid JWT_HS256_VERIFY_UNTIL %q: want a date (2006-01-02) or RFC 3339 time", v)
+			}
+		}
+	}
+
+	cfg.OIDC = OIDCConfig{
+		IssuerURL:       getEnv("OIDC_ISSUER_URL", ""),
+		ClientID:        getEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret:    getEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:     getEnv("OIDC_REDIRECT_URL", fmt.Sprintf("http://localhost:%s/api/auth/oidc/callback", cfg.Port)),
+		Scopes:          strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		DisplayName:     getEnv("OIDC_DISPLAY_NAME", "Single sign-on"),
+		JITProvisioning: getEnv("OIDC_JIT_PROVISIONING", "false") == "true",
+		DefaultRole:     getEnv("OIDC_DEFAULT_ROLE", "viewer"),
+		PostLoginURL:    getEnv("OIDC_POST_LOGIN_URL", getEnv("FRONTEND_URL", "http://localhost:3000")+"/auth/sso"),
+	}
+
 	// Required fields
 	if cfg.DB.Password == "" {
 		return nil, fmt.Errorf("DB_PASSWORD environment variable is required")
 	}
-	if cfg.JWTSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET environment variable is required")
+	if cfg.JWTSecret == "" && cfg.JWT.KeysDir == "" {
+		return nil, fmt.Errorf("JWT_KEYS_DIR or JWT_SECRET environment variable is required")
+	}
+	if cfg.Upload.URLSecret == "" {
+		return nil, fmt.Errorf("FILE_URL_SECRET environment variable is required when JWT_SECRET is not set")
 	}
 
 	return cfg, nil
@@ -0,0 +1,35 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"manpower-backend/internal/filegc"
+)
+
+// StartFileGC runs orphaned file cleanup every interval in the background,
+// first one interval after startup. An interval of 0 disables the schedule;
+// admins can still trigger runs by hand.
+func StartFileGC(c *filegc.Collector, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("[cron] file cleanup schedule disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+			if _, err := c.Run(ctx, "", false); err != nil && !errors.Is(err, filegc.ErrRunning) {
+				log.Printf("[cron] file cleanup failed: %v", err)
+			}
+			cancel()
+		}
+	}()
+
+	log.Printf("[cron] file cleanup started – runs every %s", interval)
+}
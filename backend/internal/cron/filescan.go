@@ -0,0 +1,40 @@
+package cron
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"manpower-backend/internal/filescan"
+)
+
+// rescanBatch caps how many pending files one tick scans.
+const rescanBatch = 100
+
+// StartFileScan scans pending files (uploaded while the scanner was down, or
+// before scanning existed) every interval in the background. An interval of 0
+// disables it.
+func StartFileScan(s *filescan.Service, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("[cron] pending file scan disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			n, err := s.RescanPending(ctx, rescanBatch)
+			if err != nil {
+				log.Printf("[cron] pending file scan failed after %d file(s): %v", n, err)
+			} else if n > 0 {
+				log.Printf("[cron] scanned %d pending file(s)", n)
+			}
+			cancel()
+		}
+	}()
+
+	log.Printf("[cron] pending file scan started – runs every %s", interval)
+}
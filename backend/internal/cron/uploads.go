@@ -0,0 +1,31 @@
+package cron
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"manpower-backend/internal/resumable"
+)
+
+// StartUploadExpiry deletes abandoned resumable uploads and their chunks
+// every hour in the background.
+func StartUploadExpiry(m *resumable.Manager) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			n, err := m.Expire(ctx)
+			if err != nil {
+				log.Printf("[cron] upload expiry failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[cron] expired %d abandoned upload(s)", n)
+			}
+			cancel()
+		}
+	}()
+
+	log.Println("[cron] upload expiry started – runs every hour")
+}
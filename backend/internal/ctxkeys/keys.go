@@ -11,11 +11,21 @@ type Key string
 const (
 	UserID       Key = "userID"
 	UserRole     Key = "userRole"
+	SessionID    Key = "sessionID"
 	CompanyScope Key = "companyScope"
+	Permissions  Key = "permissions"
+
+	// Set only while a super admin is impersonating another user; UserID is
+	// then the impersonated user.
+	ImpersonatorID Key = "impersonatorID"
+
+	// Set only for requests authenticated with an API key.
+	APIKeyID        Key = "apiKeyID"
+	APIKeyCompanies Key = "apiKeyCompanies" // []string; absent when the key covers all companies
 )
 
 // GetCompanyScope returns the list of company IDs the current user has access to.
-// Returns nil for admin/super_admin (meaning "all companies").
+// Returns nil for users holding companies.all (meaning "all companies").
 func GetCompanyScope(ctx context.Context) []string {
 	v := ctx.Value(CompanyScope)
 	if v == nil {
@@ -25,23 +35,36 @@ func GetCompanyScope(ctx context.Context) []string {
 	return ids
 }
 
-// IsGlobalScope returns true if the user has access to all companies (admin/super_admin).
+// IsGlobalScope returns true if the user has access to all companies.
 func IsGlobalScope(ctx context.Context) bool {
 	return ctx.Value(CompanyScope) == nil
 }
 
-// ValidRoles lists all valid role strings.
-var ValidRoles = map[string]bool{
-	"viewer":        true,
-	"company_owner": true,
-	"admin":         true,
-	"super_admin":   true,
+// GetPermissions returns the effective permission keys of the current user.
+func GetPermissions(ctx context.Context) []string {
+	perms, _ := ctx.Value(Permissions).([]string)
+	return perms
+}
+
+// HasPermission reports whether the current user holds the given permission.
+func HasPermission(ctx context.Context, perm string) bool {
+	for _, p := range GetPermissions(ctx) {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKey reports whether the request was authenticated with an API key
+// rather than a user session.
+func IsAPIKey(ctx context.Context) bool {
+	id, _ := ctx.Value(APIKeyID).(string)
+	return id != ""
 }
 
-// RoleLevel maps role names to permission levels.
-var RoleLevel = map[string]int{
-	"viewer":        1,
-	"company_owner": 2,
-	"admin":         3,
-	"super_admin":   4,
+// IsImpersonating reports whether the request uses an impersonation token.
+func IsImpersonating(ctx context.Context) bool {
+	id, _ := ctx.Value(ImpersonatorID).(string)
+	return id != ""
 }
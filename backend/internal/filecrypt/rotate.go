@@ -0,0 +1,105 @@
+package filecrypt
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// rotateBatch is how many data keys are re-wrapped per query.
+const rotateBatch = 500
+
+// RotationReport summarises a Rotate run.
+type RotationReport struct {
+	Primary   string   `json:"primary"`
+	Rewrapped int      `json:"rewrapped"`
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// Rotate re-wraps every data key that is not wrapped by the primary master
+// key. Files are not read or rewritten. Once it reports no failures, the
+// old master keys can be removed from config.
+//
+// Zero-downtime rotation:
+//  1. Add the new key to STORAGE_ENCRYPTION_KEYS on every instance and make
+//     it primary (STORAGE_ENCRYPTION_KEY_ID, or list it first); new files
+//     use it, the old key still unwraps.
+//  2. Run `api rotate-storage-keys`.
+//  3. Remove the old key.
+func (s *Store) Rotate(ctx context.Context) (*RotationReport, error) {
+	report := &RotationReport{Primary: s.keys.Primary()}
+
+	// Keyset pagination: rows that fail stay behind without being retried
+	after := "00000000-0000-0000-0000-000000000000"
+	for {
+		rows, err := s.pool.Query(ctx, `
+			SELECT id::text, master_key_id, wrapped_key FROM file_keys
+			WHERE master_key_id <> $1 AND id > $2::uuid
+			ORDER BY id LIMIT $3
+		`, s.keys.Primary(), after, rotateBatch)
+		if err != nil {
+			return report, fmt.Errorf("list data keys: %w", err)
+		}
+		type row struct {
+			id, master string
+			wrapped    []byte
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.master, &r.wrapped); err != nil {
+				rows.Close()
+				return report, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return report, err
+		}
+		if len(batch) == 0 {
+			return report, nil
+		}
+
+		for _, r := range batch {
+			after = r.id
+			if err := s.rewrap(ctx, r.id, r.master, r.wrapped); err != nil {
+				report.Failed++
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", r.id, err))
+				continue
+			}
+			report.Rewrapped++
+		}
+	}
+}
+
+func (s *Store) rewrap(ctx context.Context, id, masterID string, wrapped []byte) error {
+	keyID, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+	dataKey, err := s.keys.unwrap(masterID, keyID, wrapped)
+	if err != nil {
+		return err
+	}
+	newMaster, rewrapped, err := s.keys.wrap(keyID, dataKey)
+	if err != nil {
+		return err
+	}
+	// Only if nobody rotated it meanwhile
+	_, err = s.pool.Exec(ctx, `
+		UPDATE file_keys SET master_key_id = $2, wrapped_key = $3, rotated_at = NOW()
+		WHERE id = $1 AND master_key_id = $4
+	`, id, newMaster, rewrapped, masterID)
+	return err
+}
+
+func parseUUID(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != keyIDSize {
+		return nil, fmt.Errorf("invalid data key id %q", s)
+	}
+	return b, nil
+}
@@ -0,0 +1,227 @@
+package filecrypt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/storage"
+)
+
+// Store is a storage.Store that encrypts files before handing them to the
+// store it wraps, and decrypts them on the way out.
+//
+// Signed URLs point at the API (GET /api/files/<key>, checked with Verify)
+// rather than at the backend, which only ever holds ciphertext. List reports
+// stored (encrypted) sizes; Open and Stat report plaintext sizes.
+type Store struct {
+	inner storage.Store
+	pool  *pgxpool.Pool
+	keys  *MasterKeys
+	urls  *storage.URLSigner
+}
+
+// New wraps inner. Data keys are kept in file_keys via pool, wrapped by
+// keys; urls signs the API file URLs.
+func New(inner storage.Store, pool *pgxpool.Pool, keys *MasterKeys, urls *storage.URLSigner) *Store {
+	return &Store{inner: inner, pool: pool, keys: keys, urls: urls}
+}
+
+// Inner returns the wrapped store.
+func (s *Store) Inner() storage.Store { return s.inner }
+
+// Save encrypts file under a new data key and saves it at path. The data
+// key is recorded first, so a saved file always has its key; a failed save
+// removes the key again.
+func (s *Store) Save(ctx context.Context, path string, file io.Reader, contentType string) (*storage.FileInfo, error) {
+	dataKey := make([]byte, 32)
+	keyID := make([]byte, keyIDSize)
+	prefix := make([]byte, prefixSize)
+	for _, b := range [][]byte{dataKey, keyID, prefix} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	keyID[6] = keyID[6]&0x0f | 0x40 // a valid (v4) UUID for the id column
+	keyID[8] = keyID[8]&0x3f | 0x80
+
+	masterID, wrapped, err := s.keys.wrap(keyID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO file_keys (id, master_key_id, wrapped_key) VALUES ($1, $2, $3)`,
+		uuidString(keyID), masterID, wrapped,
+	); err != nil {
+		return nil, fmt.Errorf("store data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	h := newHeader(keyID, prefix)
+	enc := newEncryptReader(file, aead, h)
+	info, err := s.saveSealed(ctx, path, io.MultiReader(bytes.NewReader(h.raw), enc), contentType)
+	if err != nil {
+		s.deleteKey(keyID)
+		return nil, err
+	}
+	info.FileSize = enc.written
+	return info, nil
+}
+
+// saveSealed spools the ciphertext to a temporary file and saves that: object
+// stores want a seekable body of known length, which a stream being
+// encrypted is not.
+func (s *Store) saveSealed(ctx context.Context, path string, sealed io.Reader, contentType string) (*storage.FileInfo, error) {
+	tmp, err := os.CreateTemp("", "filecrypt-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, sealed); err != nil {
+		return nil, fmt.Errorf("encrypt file: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.inner.Save(ctx, path, tmp, contentType)
+}
+
+// Open returns the decrypted file. Plaintext files (stored before
+// encryption was enabled) are returned as they are.
+func (s *Store) Open(ctx context.Context, path string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	rc, info, err := s.inner.Open(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	br := bufio.NewReaderSize(rc, sealedSegLen)
+	h, encrypted, err := readHeader(br)
+	if err != nil {
+		rc.Close()
+		return nil, nil, err
+	}
+	if !encrypted {
+		return readCloser{br, rc}, info, nil
+	}
+
+	aead, err := s.dataKey(ctx, h.keyID)
+	if err != nil {
+		rc.Close()
+		return nil, nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	plain := *info
+	plain.Size = plainSize(info.Size)
+	return readCloser{newDecryptReader(br, aead, h), rc}, &plain, nil
+}
+
+// Stat returns the file's metadata with its plaintext size. It reads the
+// header to tell encrypted files from plaintext ones.
+func (s *Store) Stat(ctx context.Context, path string) (*storage.ObjectInfo, error) {
+	rc, info, err := s.inner.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	_, encrypted, err := readHeader(bufio.NewReaderSize(rc, headerSize))
+	if err != nil {
+		return nil, err
+	}
+	if encrypted {
+		info.Size = plainSize(info.Size)
+	}
+	return info, nil
+}
+
+// List implements storage.Store; sizes are the stored (encrypted) sizes.
+func (s *Store) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	return s.inner.List(ctx, prefix, fn)
+}
+
+// Delete removes the file and then its data key, so any copy of the
+// ciphertext left in backups becomes unreadable.
+func (s *Store) Delete(ctx context.Context, path string) error {
+	var keyID []byte
+	if rc, _, err := s.inner.Open(ctx, path); err == nil {
+		if h, encrypted, _ := readHeader(bufio.NewReaderSize(rc, headerSize)); encrypted {
+			keyID = h.keyID
+		}
+		rc.Close()
+	} else if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err := s.inner.Delete(ctx, path); err != nil {
+		return err
+	}
+	if keyID != nil {
+		s.deleteKey(keyID)
+	}
+	return nil
+}
+
+// SignedURL returns a URL served (and decrypted) by the API.
+func (s *Store) SignedURL(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	return s.urls.Sign(path, ttl), nil
+}
+
+// Verify checks the expiry and signature of a URL minted by SignedURL.
+func (s *Store) Verify(path, expires, sig string) error {
+	return s.urls.Verify(path, expires, sig)
+}
+
+// KeyFromURL resolves legacy URLs the way the wrapped store does.
+func (s *Store) KeyFromURL(raw string) string {
+	return storage.KeyFor(s.inner, raw)
+}
+
+// dataKey loads and unwraps a file's data key.
+func (s *Store) dataKey(ctx context.Context, keyID []byte) (cipher.AEAD, error) {
+	var masterID string
+	var wrapped []byte
+	err := s.pool.QueryRow(ctx,
+		`SELECT master_key_id, wrapped_key FROM file_keys WHERE id = $1`, uuidString(keyID),
+	).Scan(&masterID, &wrapped)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("data key %s not found: %w", uuidString(keyID), ErrCorrupt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load data key: %w", err)
+	}
+	dataKey, err := s.keys.unwrap(masterID, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(dataKey)
+}
+
+// deleteKey removes a data key. Failures only leave an unused row behind.
+func (s *Store) deleteKey(keyID []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.pool.Exec(ctx, `DELETE FROM file_keys WHERE id = $1`, uuidString(keyID))
+}
+
+// readCloser reads from r and closes c.
+type readCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+func (rc readCloser) Close() error { return rc.c.Close() }
+
+func uuidString(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,191 @@
+package filecrypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// File format constants.
+const (
+	magic        = "MPENC1"
+	keyIDSize    = 16
+	prefixSize   = 7
+	headerSize   = len(magic) + keyIDSize + prefixSize
+	segmentSize  = 64 << 10 // plaintext bytes per segment
+	tagSize      = 16       // GCM tag added to every segment
+	sealedSegLen = segmentSize + tagSize
+)
+
+// header is the parsed start of an encrypted file.
+type header struct {
+	keyID  []byte
+	prefix []byte
+	raw    []byte // the whole header, authenticated with every segment
+}
+
+func newHeader(keyID, prefix []byte) *header {
+	raw := make([]byte, 0, headerSize)
+	raw = append(append(append(raw, magic...), keyID...), prefix...)
+	return &header{keyID: keyID, prefix: prefix, raw: raw}
+}
+
+// readHeader reads the header from r. ok is false (and nothing is consumed)
+// when the file does not start with the magic, i.e. it is plaintext.
+func readHeader(r *bufio.Reader) (h *header, ok bool, err error) {
+	peek, err := r.Peek(len(magic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	if !bytes.Equal(peek, []byte(magic)) {
+		return nil, false, nil
+	}
+	raw := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, false, ErrCorrupt
+	}
+	return &header{
+		keyID:  raw[len(magic) : len(magic)+keyIDSize],
+		prefix: raw[len(magic)+keyIDSize:],
+		raw:    raw,
+	}, true, nil
+}
+
+// nonce for segment i: prefix | big-endian i | 1 for the last segment, else 0.
+func (h *header) nonce(i uint32, last bool) []byte {
+	n := make([]byte, 0, prefixSize+5)
+	n = append(n, h.prefix...)
+	n = binary.BigEndian.AppendUint32(n, i)
+	if last {
+		return append(n, 1)
+	}
+	return append(n, 0)
+}
+
+// plainSize is the plaintext size of an encrypted file of storedSize bytes.
+func plainSize(storedSize int64) int64 {
+	body := storedSize - int64(headerSize)
+	segments := (body + sealedSegLen - 1) / sealedSegLen
+	if size := body - segments*tagSize; size > 0 {
+		return size
+	}
+	return 0
+}
+
+// encryptReader yields the sealed segments of src (without the header).
+type encryptReader struct {
+	src  *bufio.Reader
+	aead cipher.AEAD
+	h    *header
+
+	seg     uint32
+	plain   []byte
+	out     []byte // sealed bytes not yet returned
+	done    bool
+	written int64 // plaintext bytes consumed
+}
+
+func newEncryptReader(src io.Reader, aead cipher.AEAD, h *header) *encryptReader {
+	return &encryptReader{
+		src:   bufio.NewReaderSize(src, segmentSize),
+		aead:  aead,
+		h:     h,
+		plain: make([]byte, segmentSize),
+	}
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.seal(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// seal encrypts the next segment. A short read ends the stream; a full one
+// is last only if nothing follows it.
+func (e *encryptReader) seal() error {
+	n, err := io.ReadFull(e.src, e.plain)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	last := n < segmentSize
+	if !last {
+		if _, err := e.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	e.out = e.aead.Seal(e.out[:0], e.h.nonce(e.seg, last), e.plain[:n], e.h.raw)
+	e.seg++
+	e.written += int64(n)
+	e.done = last
+	return nil
+}
+
+// decryptReader yields the plaintext of sealed segments read from src (the
+// header already consumed).
+type decryptReader struct {
+	src  *bufio.Reader
+	aead cipher.AEAD
+	h    *header
+
+	seg    uint32
+	sealed []byte
+	out    []byte // plaintext not yet returned
+	done   bool
+}
+
+func newDecryptReader(src *bufio.Reader, aead cipher.AEAD, h *header) *decryptReader {
+	return &decryptReader{src: src, aead: aead, h: h, sealed: make([]byte, sealedSegLen)}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.src, d.sealed)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if n < tagSize {
+		return ErrCorrupt // truncated: the last segment is missing
+	}
+	last := n < sealedSegLen
+	if !last {
+		if _, err := d.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := d.aead.Open(d.out[:0], d.h.nonce(d.seg, last), d.sealed[:n], d.h.raw)
+	if err != nil {
+		return ErrCorrupt
+	}
+	d.out = plain
+	d.seg++
+	d.done = last
+	return nil
+}
@@ -0,0 +1,324 @@
+// Package filegc finds stored files that nothing in the database references
+// and reclaims their space.
+//
+// Uploads are saved before the document or employee that uses them exists,
+// and deleting or replacing a document leaves its file behind (the same key
+// may still be shared by a renewed copy), so storage only ever grows. A run:
+//
+//  1. Lists every object and collects the keys referenced by
+//     documents.file_url, document_files.file_url and employees.photo_url.
+//     Files the malware scan flagged count as unreferenced, so they stay in
+//     (or go to) quarantine.
+//     Thumbnails and previews (file_derivatives) live as long as their original.
+//     Chunks of resumable uploads (staging/) are left to their own expiry.
+//  2. Moves unreferenced objects older than the grace age (in-flight uploads
+//     are younger) to quarantine/<key>. Objects uploaded or reused within the
+//     grace age (see Claim) count as in flight too.
+//  3. Moves quarantined objects back if something references them again, and
+//     deletes those that have been in quarantine longer than the quarantine age.
+//
+// Runs are serialised across instances with a Postgres advisory lock and
+// recorded in file_gc_runs.
+package filegc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/resumable"
+	"manpower-backend/internal/storage"
+)
+
+// QuarantinePrefix is where orphans wait before deletion.
+const QuarantinePrefix = "quarantine/"
+
+// lockID is the Postgres advisory lock key held during a run.
+const lockID = 736201
+
+// ErrRunning is returned when another run holds the lock.
+var ErrRunning = errors.New("a file cleanup run is already in progress")
+
+// Options tune the collector.
+type Options struct {
+	GraceAge      time.Duration // unreferenced files younger than this are left alone
+	QuarantineAge time.Duration // quarantined files older than this are deleted
+}
+
+// Report is the outcome of one run. Byte counts are what was (or, in a dry
+// run, would have been) moved or deleted.
+type Report struct {
+	ID               string    `json:"id"`
+	DryRun           bool      `json:"dryRun"`
+	StartedAt        time.Time `json:"startedAt"`
+	FinishedAt       time.Time `json:"finishedAt"`
+	ObjectsScanned   int       `json:"objectsScanned"`
+	OrphansFound     int       `json:"orphansFound"` // unreferenced, outside quarantine, past the grace age
+	Quarantined      int       `json:"quarantined"`
+	Restored         int       `json:"restored"`
+	Deleted          int       `json:"deleted"`
+	BytesQuarantined int64     `json:"bytesQuarantined"`
+	BytesReclaimed   int64     `json:"bytesReclaimed"`
+	Errors           []string  `json:"errors"`
+}
+
+// Collector runs garbage collection against one store.
+type Collector struct {
+	pool  *pgxpool.Pool
+	store storage.Store
+	opts  Options
+}
+
+// New creates a Collector.
+func New(pool *pgxpool.Pool, store storage.Store, opts Options) *Collector {
+	return &Collector{pool: pool, store: store, opts: opts}
+}
+
+// Options returns the collector's settings.
+func (c *Collector) Options() Options {
+	return c.opts
+}
+
+// Run performs one collection. triggeredBy is the admin's user ID, or "" for
+// the scheduled job. In a dry run nothing is moved or deleted.
+func (c *Collector) Run(ctx context.Context, triggeredBy string, dryRun bool) (*Report, error) {
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, lockID).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("take gc lock: %w", err)
+	}
+	if !locked {
+		return nil, ErrRunning
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	report := &Report{DryRun: dryRun, StartedAt: time.Now(), Errors: []string{}}
+	var by interface{}
+	if triggeredBy != "" {
+		by = triggeredBy
+	}
+	if err := c.pool.QueryRow(ctx,
+		`INSERT INTO file_gc_runs (triggered_by, dry_run) VALUES ($1, $2) RETURNING id`, by, dryRun,
+	).Scan(&report.ID); err != nil {
+		return nil, fmt.Errorf("record gc run: %w", err)
+	}
+
+	runErr := c.collect(ctx, report)
+	if runErr != nil {
+		report.Errors = append(report.Errors, runErr.Error())
+	}
+	report.FinishedAt = time.Now()
+
+	errs, _ := json.Marshal(report.Errors)
+	if _, err := c.pool.Exec(context.Background(), `
+		UPDATE file_gc_runs SET finished_at = NOW(),
+			objects_scanned = $2, orphans_found = $3, quarantined = $4, restored = $5, deleted = $6,
+			bytes_quarantined = $7, bytes_reclaimed = $8, errors = $9::jsonb
+		WHERE id = $1
+	`, report.ID, report.ObjectsScanned, report.OrphansFound, report.Quarantined, report.Restored, report.Deleted,
+		report.BytesQuarantined, report.BytesReclaimed, string(errs)); err != nil {
+		log.Printf("[filegc] failed to record run %s: %v", report.ID, err)
+	}
+
+	log.Printf("[filegc] run %s: %d objects, %d orphans, %d quarantined, %d restored, %d deleted, %d bytes reclaimed (dry run: %v)",
+		report.ID, report.ObjectsScanned, report.OrphansFound, report.Quarantined, report.Restored, report.Deleted,
+		report.BytesReclaimed, dryRun)
+	return report, runErr
+}
+
+func (c *Collector) collect(ctx context.Context, report *Report) error {
+	// List before loading references, so a file that gets referenced mid-run
+	// is either in the reference set or younger than the grace age.
+	var live, quarantined []storage.ObjectInfo
+	err := c.store.List(ctx, "", func(obj storage.ObjectInfo) error {
+		if strings.HasPrefix(obj.Key, resumable.StagingPrefix) {
+			return nil
+		}
+		if strings.HasPrefix(obj.Key, QuarantinePrefix) {
+			quarantined = append(quarantined, obj)
+		} else {
+			live = append(live, obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("list stored files: %w", err)
+	}
+	report.ObjectsScanned = len(live) + len(quarantined)
+
+	referenced, err := c.referencedKeys(ctx)
+	if err != nil {
+		return err
+	}
+	claimed, err := c.claimedKeys(ctx, report.DryRun)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, obj := range live {
+		if referenced[obj.Key] || claimed[obj.Key] || now.Sub(obj.ModTime) < c.opts.GraceAge {
+			continue
+		}
+		report.OrphansFound++
+		report.Quarantined++
+		report.BytesQuarantined += obj.Size
+		if report.DryRun {
+			continue
+		}
+		if err := c.move(ctx, obj.Key, QuarantinePrefix+obj.Key); err != nil {
+			report.Quarantined--
+			report.BytesQuarantined -= obj.Size
+			report.Errors = append(report.Errors, fmt.Sprintf("quarantine %s: %v", obj.Key, err))
+		}
+	}
+
+	for _, obj := range quarantined {
+		original := strings.TrimPrefix(obj.Key, QuarantinePrefix)
+		switch {
+		case referenced[original]:
+			report.Restored++
+			if report.DryRun {
+				continue
+			}
+			if err := c.move(ctx, obj.Key, original); err != nil {
+				report.Restored--
+				report.Errors = append(report.Errors, fmt.Sprintf("restore %s: %v", original, err))
+			}
+		case now.Sub(obj.ModTime) >= c.opts.QuarantineAge:
+			report.Deleted++
+			report.BytesReclaimed += obj.Size
+			if report.DryRun {
+				continue
+			}
+			if err := c.store.Delete(ctx, obj.Key); err != nil {
+				report.Deleted--
+				report.BytesReclaimed -= obj.Size
+				report.Errors = append(report.Errors, fmt.Sprintf("delete %s: %v", obj.Key, err))
+			}
+		}
+	}
+	return nil
+}
+
+// referencedKeys returns every storage key the database points at, except
+// infected ones, plus the derivatives of those keys.
+func (c *Collector) referencedKeys(ctx context.Context) (map[string]bool, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT ref FROM (
+			SELECT file_url AS ref FROM documents WHERE COALESCE(file_url, '') <> ''
+			UNION
+			SELECT file_url FROM document_files WHERE COALESCE(file_url, '') <> ''
+			UNION
+			SELECT photo_url FROM employees WHERE COALESCE(photo_url, '') <> ''
+		) refs
+		WHERE NOT EXISTS (SELECT 1 FROM file_scans fs WHERE fs.key = refs.ref AND fs.status = 'infected')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query file references: %w", err)
+	}
+	defer rows.Close()
+
+	keys := map[string]bool{}
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("scan file reference: %w", err)
+		}
+		keys[storage.KeyFor(c.store, ref)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	derived, err := c.pool.Query(ctx, `SELECT key, derivative_key FROM file_derivatives`)
+	if err != nil {
+		return nil, fmt.Errorf("query file derivatives: %w", err)
+	}
+	defer derived.Close()
+	for derived.Next() {
+		var key, dst string
+		if err := derived.Scan(&key, &dst); err != nil {
+			return nil, fmt.Errorf("scan file derivative: %w", err)
+		}
+		if keys[key] {
+			keys[dst] = true
+		}
+	}
+	return keys, derived.Err()
+}
+
+// Claim records that userID has just uploaded (or reused) the object at key,
+// which may be older than the grace age, so runs leave it alone for the
+// grace age as if it had just been written. The row is also what lets the
+// user attach the file.
+func (c *Collector) Claim(ctx context.Context, key, userID string) error {
+	if _, err := c.pool.Exec(ctx, `
+		INSERT INTO file_uploads (key, user_id) VALUES ($1, $2)
+		ON CONFLICT (key, user_id) DO UPDATE SET uploaded_at = NOW()
+	`, key, userID); err != nil {
+		return fmt.Errorf("claim %s: %w", key, err)
+	}
+	return nil
+}
+
+// claimedKeys returns the keys claimed within the grace age. Older claims
+// are pruned, except in a dry run.
+func (c *Collector) claimedKeys(ctx context.Context, dryRun bool) (map[string]bool, error) {
+	grace := int(c.opts.GraceAge.Seconds())
+	if !dryRun {
+		if _, err := c.pool.Exec(ctx,
+			`DELETE FROM file_uploads WHERE uploaded_at <= NOW() - $1 * INTERVAL '1 second'`, grace,
+		); err != nil {
+			return nil, fmt.Errorf("prune upload claims: %w", err)
+		}
+	}
+
+	rows, err := c.pool.Query(ctx,
+		`SELECT DISTINCT key FROM file_uploads WHERE uploaded_at > NOW() - $1 * INTERVAL '1 second'`, grace)
+	if err != nil {
+		return nil, fmt.Errorf("query upload claims: %w", err)
+	}
+	defer rows.Close()
+
+	keys := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan upload claim: %w", err)
+		}
+		keys[key] = true
+	}
+	return keys, rows.Err()
+}
+
+// move copies src to dst and then removes src. The new object's modification
+// time marks when it entered (or left) quarantine.
+func (c *Collector) move(ctx context.Context, src, dst string) error {
+	r, info, err := c.store.Open(ctx, src)
+	if err != nil {
+		return err
+	}
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	_, err = c.store.Save(ctx, dst, r, contentType)
+	r.Close()
+	if err != nil {
+		return err
+	}
+	return c.store.Delete(ctx, src)
+}
@@ -0,0 +1,238 @@
+// Package filemigrate copies the files referenced by the database from one
+// storage.Store to another and points the references at the copies.
+//
+// Every distinct reference in documents.file_url, document_files.file_url and
+// employees.photo_url is resolved to a storage key (legacy full URLs
+// included), copied, read back from the destination and compared by SHA-256.
+// References whose copy verified are rewritten to the bare key in a single
+// transaction; anything missing or broken is left untouched and listed in
+// the report.
+package filemigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/storage"
+)
+
+// Result statuses.
+const (
+	StatusCopied    = "copied"     // copied and verified
+	StatusPresent   = "present"    // already in the destination with the same checksum
+	StatusWouldCopy = "would_copy" // dry run: source exists, copy pending
+	StatusMissing   = "missing"    // not found in the source store
+	StatusMismatch  = "mismatch"   // destination checksum differs after copying
+	StatusError     = "error"      // any other failure
+)
+
+// Options control a run.
+type Options struct {
+	DryRun bool // only check sources and destinations; write nothing
+}
+
+// Item is the outcome for one referenced file.
+type Item struct {
+	Ref     string   `json:"ref"`     // value as stored in the database
+	Key     string   `json:"key"`     // resolved storage key
+	Sources []string `json:"sources"` // e.g. "documents:<id>", "employees:<id>"
+	Status  string   `json:"status"`
+	Size    int64    `json:"size,omitempty"`
+	SHA256  string   `json:"sha256,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Report summarises a run.
+type Report struct {
+	DryRun      bool           `json:"dryRun"`
+	Items       []Item         `json:"items"`
+	Counts      map[string]int `json:"counts"`
+	Bytes       int64          `json:"bytes"`       // bytes copied
+	RowsUpdated int64          `json:"rowsUpdated"` // database references rewritten
+}
+
+// OK reports whether every referenced file is now in the destination.
+func (r *Report) OK() bool {
+	for _, it := range r.Items {
+		if it.Status != StatusCopied && it.Status != StatusPresent && it.Status != StatusWouldCopy {
+			return false
+		}
+	}
+	return true
+}
+
+// Run copies every referenced file from src to dst.
+func Run(ctx context.Context, pool *pgxpool.Pool, src, dst storage.Store, opts Options) (*Report, error) {
+	items, err := collect(ctx, pool, src)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun, Counts: map[string]int{}}
+	for _, it := range items {
+		copyOne(ctx, src, dst, it, opts.DryRun)
+		report.Counts[it.Status]++
+		if it.Status == StatusCopied {
+			report.Bytes += it.Size
+		}
+		report.Items = append(report.Items, *it)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	n, err := rewrite(ctx, pool, report.Items)
+	if err != nil {
+		return report, fmt.Errorf("rewrite references: %w", err)
+	}
+	report.RowsUpdated = n
+	return report, nil
+}
+
+// collect gathers distinct file references, grouped by resolved key.
+func collect(ctx context.Context, pool *pgxpool.Pool, src storage.Store) ([]*Item, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT 'documents', id::text, file_url FROM documents WHERE COALESCE(file_url, '') <> ''
+		UNION ALL
+		SELECT 'document_files', id::text, file_url FROM document_files WHERE COALESCE(file_url, '') <> ''
+		UNION ALL
+		SELECT 'employees', id::text, photo_url FROM employees WHERE COALESCE(photo_url, '') <> ''
+		ORDER BY 3, 1, 2
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query file references: %w", err)
+	}
+	defer rows.Close()
+
+	byRef := map[string]*Item{}
+	var items []*Item
+	for rows.Next() {
+		var table, id, ref string
+		if err := rows.Scan(&table, &id, &ref); err != nil {
+			return nil, fmt.Errorf("scan file reference: %w", err)
+		}
+		it, ok := byRef[ref]
+		if !ok {
+			it = &Item{Ref: ref, Key: storage.KeyFor(src, ref)}
+			byRef[ref] = it
+			items = append(items, it)
+		}
+		it.Sources = append(it.Sources, table+":"+id)
+	}
+	return items, rows.Err()
+}
+
+// copyOne copies and verifies a single file, recording the outcome on it.
+func copyOne(ctx context.Context, src, dst storage.Store, it *Item, dryRun bool) {
+	if it.Key == "" {
+		it.Status, it.Error = StatusError, "reference does not resolve to a storage key"
+		return
+	}
+
+	srcSum, srcInfo, err := checksum(ctx, src, it.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		it.Status = StatusMissing
+		return
+	}
+	if err != nil {
+		it.Status, it.Error = StatusError, "read source: "+err.Error()
+		return
+	}
+	it.Size, it.SHA256 = srcInfo.Size, srcSum
+
+	// Skip files a previous run already copied
+	if dstSum, _, err := checksum(ctx, dst, it.Key); err == nil && dstSum == srcSum {
+		it.Status = StatusPresent
+		return
+	}
+
+	if dryRun {
+		it.Status = StatusWouldCopy
+		return
+	}
+
+	r, info, err := src.Open(ctx, it.Key)
+	if err != nil {
+		it.Status, it.Error = StatusError, "open source: "+err.Error()
+		return
+	}
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	_, err = dst.Save(ctx, it.Key, r, contentType)
+	r.Close()
+	if err != nil {
+		it.Status, it.Error = StatusError, "write destination: "+err.Error()
+		return
+	}
+
+	dstSum, _, err := checksum(ctx, dst, it.Key)
+	if err != nil {
+		it.Status, it.Error = StatusError, "read back destination: "+err.Error()
+		return
+	}
+	if dstSum != srcSum {
+		it.Status, it.Error = StatusMismatch, "destination sha256 "+dstSum
+		return
+	}
+	it.Status = StatusCopied
+}
+
+// checksum streams a file and returns its hex SHA-256.
+func checksum(ctx context.Context, store storage.Store, key string) (string, *storage.ObjectInfo, error) {
+	r, info, err := store.Open(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", nil, err
+	}
+	info.Size = n
+	return hex.EncodeToString(h.Sum(nil)), info, nil
+}
+
+// rewrite points every verified reference at its bare key, in one transaction.
+func rewrite(ctx context.Context, pool *pgxpool.Pool, items []Item) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var total int64
+	for _, it := range items {
+		if (it.Status != StatusCopied && it.Status != StatusPresent) || it.Ref == it.Key {
+			continue
+		}
+		for _, q := range []string{
+			`UPDATE documents SET file_url = $2 WHERE file_url = $1`,
+			`UPDATE document_files SET file_url = $2 WHERE file_url = $1`,
+			`UPDATE employees SET photo_url = $2 WHERE photo_url = $1`,
+		} {
+			tag, err := tx.Exec(ctx, q, it.Ref, it.Key)
+			if err != nil {
+				return 0, err
+			}
+			total += tag.RowsAffected()
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	log.Printf("[filemigrate] rewrote %d file reference(s)", total)
+	return total, nil
+}
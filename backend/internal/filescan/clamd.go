@@ -0,0 +1,145 @@
+package filescan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the INSTREAM chunk size; clamd's default StreamMaxLength
+// (25 MB) bounds the total.
+const clamdChunkSize = 64 << 10
+
+// Clamd scans files with a ClamAV daemon over TCP or a unix socket, using the
+// INSTREAM command.
+type Clamd struct {
+	network string // "tcp" or "unix"
+	address string
+	timeout time.Duration
+}
+
+// NewClamd creates a clamd scanner. address is "tcp://host:port",
+// "unix:///path/to/clamd.sock" or a bare "host:port". timeout bounds each
+// scan, connection included.
+func NewClamd(address string, timeout time.Duration) (*Clamd, error) {
+	c := &Clamd{network: "tcp", address: address, timeout: timeout}
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		c.network, c.address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		c.address = strings.TrimPrefix(address, "tcp://")
+	}
+	if c.address == "" {
+		return nil, errors.New("clamd address is required")
+	}
+	return c, nil
+}
+
+// Name implements Scanner.
+func (c *Clamd) Name() string { return "clamd" }
+
+// Ping checks that the daemon is reachable.
+func (c *Clamd) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("clamd ping: %w", err)
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return fmt.Errorf("clamd ping: %w", err)
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd ping: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+// Scan implements Scanner.
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+
+	// Stream as <uint32 length><data> chunks, terminated by a zero length
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, rerr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return nil, fmt.Errorf("clamd: send: %w", err)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return nil, fmt.Errorf("clamd: read file: %w", rerr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, fmt.Errorf("clamd: send: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	return parseReply(reply)
+}
+
+func (c *Clamd) dial(ctx context.Context) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: connect %s: %w", c.address, err)
+	}
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// readReply reads one NUL-terminated reply (the "z" command prefix).
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", fmt.Errorf("read reply: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseReply interprets "stream: OK", "stream: <signature> FOUND" or
+// "<message> ERROR".
+func parseReply(reply string) (*Result, error) {
+	body := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case body == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(body, " FOUND")}, nil
+	case strings.HasSuffix(body, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(body, " ERROR"))
+	default:
+		return nil, fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+}
@@ -0,0 +1,42 @@
+// Package filescan checks stored files for malware.
+//
+// Uploads are scanned before they are saved. Every stored key has a row in
+// file_scans with status pending, clean or infected:
+//
+//   - clean files are served normally;
+//   - infected files are kept only under quarantine/ (where the orphan
+//     cleanup deletes them after the quarantine age) and admins are notified;
+//   - pending files — uploaded while the scanner was unreachable, or from
+//     before scanning existed — are refused for download until the
+//     background rescan has checked them.
+package filescan
+
+import (
+	"context"
+	"io"
+)
+
+// Scan statuses.
+const (
+	StatusPending  = "pending"
+	StatusClean    = "clean"
+	StatusInfected = "infected"
+)
+
+// QuarantinePrefix is where infected files are kept. It matches the orphan
+// cleanup's quarantine so they are eventually deleted.
+const QuarantinePrefix = "quarantine/"
+
+// Result is a scanner's verdict on one file.
+type Result struct {
+	Infected  bool
+	Signature string // name of the detected malware, when infected
+}
+
+// Scanner inspects file contents. Implementations must be safe for
+// concurrent use. An error means no verdict (the scanner is unavailable or
+// the file could not be read), not that the file is infected.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
@@ -0,0 +1,223 @@
+package filescan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/permissions"
+	"manpower-backend/internal/storage"
+)
+
+// Service records scan results for one store and acts on them.
+type Service struct {
+	pool    *pgxpool.Pool
+	store   storage.Store
+	scanner Scanner
+}
+
+// New creates a Service.
+func New(pool *pgxpool.Pool, store storage.Store, scanner Scanner) *Service {
+	return &Service{pool: pool, store: store, scanner: scanner}
+}
+
+// Scan runs the scanner over r and returns the resulting status. A scanner
+// failure is logged and yields StatusPending, leaving the file to the
+// background rescan.
+func (s *Service) Scan(ctx context.Context, r io.Reader) (status, signature string) {
+	res, err := s.scanner.Scan(ctx, r)
+	if err != nil {
+		log.Printf("[filescan] %s scan failed, file left pending: %v", s.scanner.Name(), err)
+		return StatusPending, ""
+	}
+	if res.Infected {
+		return StatusInfected, res.Signature
+	}
+	return StatusClean, ""
+}
+
+// Record stores the status of the file at key. A pending status never
+// overwrites an existing verdict.
+func (s *Service) Record(ctx context.Context, key, sum, status, signature string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO file_scans (key, sha256, status, signature, scanner, scanned_at)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5, CASE WHEN $3 = 'pending' THEN NULL ELSE NOW() END)
+		ON CONFLICT (key) DO UPDATE SET
+			sha256 = COALESCE(EXCLUDED.sha256, file_scans.sha256),
+			status = EXCLUDED.status, signature = EXCLUDED.signature,
+			scanner = EXCLUDED.scanner, scanned_at = EXCLUDED.scanned_at
+		WHERE EXCLUDED.status <> 'pending'
+	`, key, sum, status, signature, s.scanner.Name())
+	if err != nil {
+		return fmt.Errorf("record scan of %s: %w", key, err)
+	}
+	return nil
+}
+
+// Status returns the scan status of the file at key. Files that have never
+// been scanned are pending.
+func (s *Service) Status(ctx context.Context, key string) (string, error) {
+	var status string
+	err := s.pool.QueryRow(ctx, `SELECT status FROM file_scans WHERE key = $1`, key).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return StatusPending, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("query scan status of %s: %w", key, err)
+	}
+	return status, nil
+}
+
+// Quarantine stores a rejected upload under QuarantinePrefix instead of its
+// key, records it as infected and notifies admins.
+func (s *Service) Quarantine(ctx context.Context, key, sum string, r io.Reader, contentType, signature, fileName string) error {
+	if _, err := s.store.Save(ctx, QuarantinePrefix+key, r, contentType); err != nil {
+		return fmt.Errorf("quarantine %s: %w", key, err)
+	}
+	if err := s.Record(ctx, key, sum, StatusInfected, signature); err != nil {
+		return err
+	}
+	s.notifyAdmins(ctx, key, signature, fileName)
+	return nil
+}
+
+// RescanPending scans up to limit pending files: those recorded as pending
+// and referenced files that have never been scanned. It stops at the first
+// scanner failure, since the scanner is most likely down.
+func (s *Service) RescanPending(ctx context.Context, limit int) (int, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT key FROM file_scans WHERE status = 'pending'
+		UNION
+		SELECT d.file_url FROM documents d
+		WHERE COALESCE(d.file_url, '') <> ''
+		  AND NOT EXISTS (SELECT 1 FROM file_scans fs WHERE fs.key = d.file_url)
+		UNION
+		SELECT f.file_url FROM document_files f
+		WHERE COALESCE(f.file_url, '') <> ''
+		  AND NOT EXISTS (SELECT 1 FROM file_scans fs WHERE fs.key = f.file_url)
+		UNION
+		SELECT e.photo_url FROM employees e
+		WHERE COALESCE(e.photo_url, '') <> ''
+		  AND NOT EXISTS (SELECT 1 FROM file_scans fs WHERE fs.key = e.photo_url)
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("query pending files: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan pending file: %w", err)
+		}
+		keys = append(keys, storage.KeyFor(s.store, ref))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	scanned := 0
+	for _, key := range keys {
+		if err := s.rescan(ctx, key); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				log.Printf("[filescan] pending file %s is missing from storage", key)
+				continue
+			}
+			return scanned, err
+		}
+		scanned++
+	}
+	return scanned, nil
+}
+
+// rescan scans one stored file and quarantines it if infected.
+func (s *Service) rescan(ctx context.Context, key string) error {
+	r, info, err := s.store.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	hasher := sha256.New()
+	tee := io.TeeReader(r, hasher)
+	res, err := s.scanner.Scan(ctx, tee)
+	if err != nil {
+		return fmt.Errorf("%s scan of %s: %w", s.scanner.Name(), key, err)
+	}
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return fmt.Errorf("read %s: %w", key, err)
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+
+	if !res.Infected {
+		return s.Record(ctx, key, sum, StatusClean, "")
+	}
+
+	log.Printf("[filescan] %s is infected (%s); quarantining", key, res.Signature)
+	if err := s.Record(ctx, key, sum, StatusInfected, res.Signature); err != nil {
+		return err
+	}
+	if err := s.move(ctx, key, QuarantinePrefix+key, info.ContentType); err != nil {
+		return fmt.Errorf("quarantine %s: %w", key, err)
+	}
+	s.notifyAdmins(ctx, key, res.Signature, path.Base(key))
+	return nil
+}
+
+// move copies src to dst and then removes src.
+func (s *Service) move(ctx context.Context, src, dst, contentType string) error {
+	r, _, err := s.store.Open(ctx, src)
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	_, err = s.store.Save(ctx, dst, r, contentType)
+	r.Close()
+	if err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, src)
+}
+
+// notifyAdmins sends a notification to every user who can manage settings.
+// If a document uses the file, the notification links to it.
+func (s *Service) notifyAdmins(ctx context.Context, key, signature, fileName string) {
+	var docID *string
+	_ = s.pool.QueryRow(ctx,
+		`SELECT id::text FROM documents d
+		 WHERE d.file_url = $1 OR EXISTS (SELECT 1 FROM document_files f WHERE f.document_id = d.id AND f.file_url = $1)
+		 ORDER BY d.created_at DESC LIMIT 1`, key,
+	).Scan(&docID)
+
+	entityType := "file"
+	message := fmt.Sprintf("An upload of %s was rejected: %s detected. The file has been quarantined.", fileName, signature)
+	if docID != nil {
+		entityType = "document"
+		message = fmt.Sprintf("The file of a document (%s) failed a malware scan: %s detected. The file has been quarantined.", fileName, signature)
+	}
+
+	tag, err := s.pool.Exec(ctx, `
+		INSERT INTO notifications (user_id, title, message, type, entity_type, entity_id)
+		SELECT u.id, $3, $4, 'malware_detected', $5, $6
+		FROM users u
+		WHERE u.role = $1
+		   OR u.role IN (SELECT role FROM role_permissions WHERE permission = $2)
+	`, permissions.SuperAdminRole, permissions.SettingsManage, "🦠 Malware detected", message, entityType, docID)
+	if err != nil {
+		log.Printf("[filescan] failed to notify admins about %s: %v", key, err)
+		return
+	}
+	log.Printf("[filescan] notified %d admin(s) about %s", tag.RowsAffected(), key)
+}
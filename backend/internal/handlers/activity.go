@@ -36,10 +36,12 @@ func (h *ActivityHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := pool.Query(ctx, `
 		SELECT a.id, a.user_id, COALESCE(u.name, 'System') AS user_name,
+			a.impersonator_id::text, imp.name,
 			a.action, a.entity_type, a.entity_id, a.details,
 			a.created_at::text
 		FROM activity_log a
 		LEFT JOIN users u ON a.user_id::uuid = u.id
+		LEFT JOIN users imp ON imp.id = a.impersonator_id
 		ORDER BY a.created_at DESC
 		LIMIT $1
 	`, limit)
@@ -55,6 +57,7 @@ func (h *ActivityHandler) List(w http.ResponseWriter, r *http.Request) {
 		var a models.ActivityLog
 		if err := rows.Scan(
 			&a.ID, &a.UserID, &a.UserName,
+			&a.ImpersonatorID, &a.ImpersonatorName,
 			&a.Action, &a.EntityType, &a.EntityID, &a.Details,
 			&a.CreatedAt,
 		); err != nil {
@@ -165,7 +165,7 @@ func (h *AdminHandler) CreateDocumentType(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	go logActivity(pool, userID, "created", "document_type", dt.ID, map[string]interface{}{
+	go logActivity(r.Context(), pool, userID, "created", "document_type", dt.ID, map[string]interface{}{
 		"docType":     dt.DocType,
 		"displayName": dt.DisplayName,
 	})
@@ -256,7 +256,7 @@ func (h *AdminHandler) UpdateDocumentType(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	go logActivity(pool, userID, "updated", "document_type", dt.ID, map[string]interface{}{
+	go logActivity(r.Context(), pool, userID, "updated", "document_type", dt.ID, map[string]interface{}{
 		"docType":     dt.DocType,
 		"displayName": dt.DisplayName,
 	})
@@ -298,7 +298,7 @@ func (h *AdminHandler) DeleteDocumentType(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	go logActivity(pool, userID, "deleted", "document_type", id, map[string]interface{}{
+	go logActivity(r.Context(), pool, userID, "deleted", "document_type", id, map[string]interface{}{
 		"docType": docType,
 	})
 
@@ -446,7 +446,7 @@ func (h *AdminHandler) UpsertComplianceRules(w http.ResponseWriter, r *http.Requ
 	if req.CompanyID != nil {
 		scope = *req.CompanyID
 	}
-	go logActivity(pool, userID, "updated", "compliance_rules", scope, map[string]interface{}{
+	go logActivity(r.Context(), pool, userID, "updated", "compliance_rules", scope, map[string]interface{}{
 		"ruleCount": len(req.Rules),
 		"companyId": req.CompanyID,
 	})
@@ -537,7 +537,7 @@ func (h *AdminHandler) CreateDependency(w http.ResponseWriter, r *http.Request)
 	}
 
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	go logActivity(pool, userID, "created", "dependency", dep.ID, map[string]interface{}{
+	go logActivity(r.Context(), pool, userID, "created", "dependency", dep.ID, map[string]interface{}{
 		"blocking": req.BlockingDocType, "blocked": req.BlockedDocType,
 	})
 
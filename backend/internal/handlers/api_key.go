@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/middleware"
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/permissions"
+)
+
+// apiKeyPrefixLen is how much of a key is kept in clear for display.
+const apiKeyPrefixLen = 12
+
+// APIKeyHandler lets users manage their own API keys.
+type APIKeyHandler struct {
+	db database.Service
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(db database.Service) *APIKeyHandler {
+	return &APIKeyHandler{db: db}
+}
+
+// List handles GET /api/api-keys — the caller's active keys.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	rows, err := pool.Query(ctx, `
+		SELECT k.id, k.name, k.prefix, k.access, k.all_companies,
+			COALESCE(ARRAY(SELECT company_id::text FROM api_key_companies WHERE api_key_id = k.id), '{}'),
+			k.expires_at::text, k.last_used_at::text, k.last_used_ip, k.created_at::text
+		FROM api_keys k
+		WHERE k.user_id = $1 AND k.revoked_at IS NULL
+		ORDER BY k.created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Error fetching API keys for %s: %v", userID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(
+			&k.ID, &k.Name, &k.Prefix, &k.Access, &k.AllCompanies, &k.CompanyIDs,
+			&k.ExpiresAt, &k.LastUsedAt, &k.LastUsedIP, &k.CreatedAt,
+		); err != nil {
+			log.Printf("Error scanning API key: %v", err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{"data": keys})
+}
+
+// Create handles POST /api/api-keys. The plaintext key is returned only in
+// this response; afterwards only its prefix is visible.
+// A key can only cover companies the caller can access.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	if req.AllCompanies && !ctxkeys.HasPermission(r.Context(), permissions.CompaniesAll) {
+		JSONError(w, http.StatusForbidden, "Only users with access to every company can create an all-companies key")
+		return
+	}
+	if req.AllCompanies {
+		req.CompanyIDs = nil
+	}
+	for _, companyID := range req.CompanyIDs {
+		if !checkCompanyAccess(r.Context(), companyID) {
+			JSONError(w, http.StatusForbidden, "You do not have access to one of the selected companies")
+			return
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		log.Printf("Failed to generate API key: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	plain := middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	var expiresAt interface{}
+	if req.ExpiresAt != nil && *req.ExpiresAt != "" {
+		expiresAt = *req.ExpiresAt
+	}
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var k models.APIKey
+	err = tx.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, access, all_companies, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::date)
+		RETURNING id, name, prefix, access, all_companies, expires_at::text, created_at::text
+	`, userID, req.Name, plain[:apiKeyPrefixLen], middleware.HashAPIKey(plain), req.Access, req.AllCompanies, expiresAt,
+	).Scan(&k.ID, &k.Name, &k.Prefix, &k.Access, &k.AllCompanies, &k.ExpiresAt, &k.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create API key: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	if len(req.CompanyIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO api_key_companies (api_key_id, company_id)
+			SELECT $1, UNNEST($2::uuid[])
+			ON CONFLICT DO NOTHING
+		`, k.ID, req.CompanyIDs); err != nil {
+			log.Printf("Failed to restrict API key %s to companies: %v", k.ID, err)
+			JSONError(w, http.StatusUnprocessableEntity, "One or more companies do not exist")
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	k.CompanyIDs = req.CompanyIDs
+	if k.CompanyIDs == nil {
+		k.CompanyIDs = []string{}
+	}
+
+	go logActivity(r.Context(), pool, userID, "created", "api_key", k.ID, map[string]interface{}{
+		"name":         k.Name,
+		"access":       k.Access,
+		"allCompanies": k.AllCompanies,
+		"companyIds":   k.CompanyIDs,
+	})
+
+	JSON(w, http.StatusCreated, map[string]interface{}{
+		"data":    models.CreatedAPIKey{APIKey: k, Key: plain},
+		"message": "API key created. Copy it now — it will not be shown again.",
+	})
+}
+
+// Revoke handles DELETE /api/api-keys/{id}. Revoked keys stop working immediately.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	keyID := chi.URLParam(r, "id")
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	var name string
+	err := pool.QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING name
+	`, keyID, userID).Scan(&name)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	go logActivity(r.Context(), pool, userID, "revoked", "api_key", keyID, map[string]interface{}{
+		"name": name,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{"message": "API key revoked"})
+}
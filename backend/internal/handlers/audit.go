@@ -7,20 +7,28 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/ctxkeys"
 )
 
 // logActivity records a user action in the activity_log table.
 // This is called after every successful mutation (create, update, delete)
 // to provide a full audit trail visible on the Activity page.
 //
+// While a super admin is impersonating, the entry is attributed to the
+// impersonated user and also records the impersonator.
+//
 // Parameters:
+//   - reqCtx:     context of the originating request (only read for values, so it may be done)
 //   - pool:       database connection pool
 //   - userID:     ID of the user who performed the action (from JWT context)
 //   - action:     short verb describing what happened ("created", "updated", "deleted", "renewed", "toggled_primary")
 //   - entityType: the kind of entity affected ("employee", "document", "salary")
 //   - entityID:   UUID of the affected entity
 //   - details:    optional key-value pairs with additional context (nil is fine)
-func logActivity(pool *pgxpool.Pool, userID, action, entityType, entityID string, details map[string]interface{}) {
+func logActivity(reqCtx context.Context, pool *pgxpool.Pool, userID, action, entityType, entityID string, details map[string]interface{}) {
+	impersonatorID, _ := reqCtx.Value(ctxkeys.ImpersonatorID).(string)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -35,9 +43,9 @@ func logActivity(pool *pgxpool.Pool, userID, action, entityType, entityID string
 	}
 
 	_, err := pool.Exec(ctx, `
-		INSERT INTO activity_log (user_id, action, entity_type, entity_id, details)
-		VALUES ($1::uuid, $2, $3, $4::uuid, $5::jsonb)
-	`, nilIfEmptyStr(userID), action, entityType, entityID, nilIfEmptyStr(string(detailsJSON)))
+		INSERT INTO activity_log (user_id, action, entity_type, entity_id, details, impersonator_id)
+		VALUES ($1::uuid, $2, $3, $4::uuid, $5::jsonb, $6::uuid)
+	`, nilIfEmptyStr(userID), action, entityType, entityID, nilIfEmptyStr(string(detailsJSON)), nilIfEmptyStr(impersonatorID))
 
 	if err != nil {
 		// Log but don't fail the request — audit is best-effort.
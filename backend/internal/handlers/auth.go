@@ -13,20 +13,22 @@ import (
 
 	"manpower-backend/internal/ctxkeys"
 	"manpower-backend/internal/database"
+	"manpower-backend/internal/keyring"
 	"manpower-backend/internal/models"
+	"manpower-backend/internal/permissions"
 )
 
 // AuthHandler manages user registration, login, and profile retrieval.
 type AuthHandler struct {
-	db        database.Service
-	jwtSecret []byte
+	db   database.Service
+	keys *keyring.Keyring
 }
 
-// NewAuthHandler creates an AuthHandler with the given database and JWT signing key.
-func NewAuthHandler(db database.Service, jwtSecret string) *AuthHandler {
+// NewAuthHandler creates an AuthHandler with the given database and JWT keyring.
+func NewAuthHandler(db database.Service, keys *keyring.Keyring) *AuthHandler {
 	return &AuthHandler{
-		db:        db,
-		jwtSecret: []byte(jwtSecret),
+		db:   db,
+		keys: keys,
 	}
 }
 
@@ -48,6 +50,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	// Self-registration creates password accounts, so it follows the same switch
+	if !passwordLoginEnabled(ctx, pool) {
+		JSONError(w, http.StatusForbidden, "Registration is disabled; sign in with single sign-on")
+		return
+	}
+
 	// All new users are registered as "viewer" for security.
 	// Admin role is granted by existing admins via User Management.
 	role := "viewer"
@@ -60,11 +73,6 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-
-	pool := h.db.GetPool()
-
 	// Insert user — UNIQUE constraint on email prevents duplicates
 	var user models.User
 	err = pool.QueryRow(ctx, `
@@ -87,8 +95,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token for immediate login after registration
-	token, err := h.generateToken(user.ID, user.Role)
+	// Open a session and generate a JWT for immediate login after registration
+	sessionID, err := createSession(ctx, pool, r, user.ID)
+	if err != nil {
+		log.Printf("Failed to create session: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Account created but login failed")
+		return
+	}
+	token, err := h.generateToken(user.ID, user.Role, sessionID)
 	if err != nil {
 		log.Printf("Failed to generate token: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Account created but login failed")
@@ -102,6 +116,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 }
 
 // Login authenticates a user with email + password and returns a JWT token.
+// Admins can disable password login in favour of SSO (see SSOHandler).
+// Every attempt is recorded in login_events; consecutive failures lock the
+// account progressively (see lockoutDuration).
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -122,29 +139,87 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	pool := h.db.GetPool()
 
-	// Fetch user by email (including password hash for verification)
+	pwEnabled := passwordLoginEnabled(ctx, pool)
+
+	// Every failure gets the same response, so it doesn't reveal which
+	// emails exist or, with password sign-in disabled, which accounts are the
+	// super_admins that keep it. Only login_events records the reason.
+	reject := func(userID, reason string) {
+		recordLoginEvent(pool, r, userID, req.Email, false, reason)
+		if !pwEnabled {
+			JSONError(w, http.StatusForbidden, "Password sign-in is disabled; use single sign-on")
+			return
+		}
+		JSONError(w, http.StatusUnauthorized, "Invalid email or password")
+	}
+
+	// Fetch user by email (including password hash and lockout state)
 	var user models.User
+	var lockedUntil *time.Time
 	err := pool.QueryRow(ctx, `
-		SELECT id, email, password_hash, name, role, created_at::text, updated_at::text
+		SELECT id, email, password_hash, name, role, created_at::text, updated_at::text, locked_until
 		FROM users WHERE email = $1
 	`, req.Email,
 	).Scan(
 		&user.ID, &user.Email, &user.PasswordHash,
-		&user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt, &lockedUntil,
 	)
 	if err != nil {
-		// Generic message to prevent email enumeration attacks
-		JSONError(w, http.StatusUnauthorized, "Invalid email or password")
+		reject("", loginReasonUnknownEmail)
+		return
+	}
+
+	// When admins have switched to SSO only, super_admin keeps password access
+	// as a break-glass in case the identity provider is unavailable.
+	if user.Role != permissions.SuperAdminRole && !pwEnabled {
+		reject(user.ID, loginReasonPasswordOff)
+		return
+	}
+
+	// Locked accounts are rejected before the password is checked, so a
+	// distributed attack can't keep guessing while the lock is in place.
+	if lockedUntil != nil && lockedUntil.After(time.Now()) {
+		reject(user.ID, loginReasonAccountLocked)
 		return
 	}
 
 	// Compare password against stored hash
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		JSONError(w, http.StatusUnauthorized, "Invalid email or password")
+		var failures int
+		if err := pool.QueryRow(ctx, `
+			UPDATE users SET failed_login_count = failed_login_count + 1
+			WHERE id = $1
+			RETURNING failed_login_count
+		`, user.ID).Scan(&failures); err != nil {
+			log.Printf("Failed to record failed login for %s: %v", user.ID, err)
+		}
+		if d := lockoutDuration(failures); d > 0 {
+			if _, err := pool.Exec(ctx,
+				`UPDATE users SET locked_until = NOW() + $2 * INTERVAL '1 second' WHERE id = $1`,
+				user.ID, int(d.Seconds()),
+			); err != nil {
+				log.Printf("Failed to lock account %s: %v", user.ID, err)
+			}
+		}
+		reject(user.ID, loginReasonInvalidPassword)
 		return
 	}
 
-	token, err := h.generateToken(user.ID, user.Role)
+	// Successful login clears the failure counter
+	if _, err := pool.Exec(ctx,
+		`UPDATE users SET failed_login_count = 0, locked_until = NULL WHERE id = $1`, user.ID,
+	); err != nil {
+		log.Printf("Failed to reset failed logins for %s: %v", user.ID, err)
+	}
+	recordLoginEvent(pool, r, user.ID, req.Email, true, loginReasonSuccess)
+
+	sessionID, err := createSession(ctx, pool, r, user.ID)
+	if err != nil {
+		log.Printf("Failed to create session: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Login failed")
+		return
+	}
+	token, err := h.generateToken(user.ID, user.Role, sessionID)
 	if err != nil {
 		log.Printf("Failed to generate token: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Login failed")
@@ -157,8 +232,11 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetMe returns the profile of the currently authenticated user.
-// For scoped users (company_owner, viewer), includes their assigned company IDs.
+// GetMe returns the profile of the currently authenticated user, including the
+// effective permission list so the frontend can hide actions and the user's
+// recent sign-ins. While impersonating, the impersonator is included so the
+// frontend can show a banner.
+// For scoped users (without companies.all), includes their assigned company IDs.
 func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
 
@@ -183,12 +261,29 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 
 	type MeResponse struct {
 		models.User
-		CompanyIDs []string `json:"companyIds,omitempty"`
+		CompanyIDs    []string            `json:"companyIds,omitempty"`
+		Permissions   []string            `json:"permissions"`
+		RecentSignIns []models.LoginEvent `json:"recentSignIns"`
+		Impersonator  *models.User        `json:"impersonator,omitempty"` // set while a super admin is impersonating
+	}
+
+	resp := MeResponse{
+		User:          user,
+		Permissions:   ctxkeys.GetPermissions(r.Context()),
+		RecentSignIns: recentSignIns(ctx, pool, userID, 10),
 	}
 
-	resp := MeResponse{User: user}
+	if impersonatorID, _ := r.Context().Value(ctxkeys.ImpersonatorID).(string); impersonatorID != "" {
+		var imp models.User
+		if err := pool.QueryRow(ctx, `
+			SELECT id, email, name, role, created_at::text, updated_at::text
+			FROM users WHERE id = $1
+		`, impersonatorID).Scan(&imp.ID, &imp.Email, &imp.Name, &imp.Role, &imp.CreatedAt, &imp.UpdatedAt); err == nil {
+			resp.Impersonator = &imp
+		}
+	}
 
-	if user.Role == "company_owner" || user.Role == "viewer" {
+	if !ctxkeys.HasPermission(r.Context(), permissions.CompaniesAll) {
 		rows, err := pool.Query(ctx,
 			`SELECT company_id::text FROM user_companies WHERE user_id = $1`, userID)
 		if err == nil {
@@ -208,18 +303,19 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusOK, resp)
 }
 
-// generateToken creates a signed JWT with user ID and role as claims.
-// Tokens expire after 7 days.
-func (h *AuthHandler) generateToken(userID, role string) (string, error) {
+// generateToken creates a JWT with user ID, role and session ID as claims,
+// signed by the keyring's current signing key.
+// Tokens expire together with their session (tokenTTL).
+func (h *AuthHandler) generateToken(userID, role, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"userId": userID,
 		"role":   role,
-		"exp":    time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"sid":    sessionID,
+		"exp":    time.Now().Add(tokenTTL).Unix(),
 		"iat":    time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.jwtSecret)
+	return h.keys.Sign(claims)
 }
 
 // isDuplicateKeyError checks if a PostgreSQL error is a unique constraint violation.
@@ -230,3 +326,13 @@ func isDuplicateKeyError(err error) bool {
 	msg := err.Error()
 	return strings.Contains(msg, "duplicate key") || strings.Contains(msg, "23505")
 }
+
+// isForeignKeyError checks if a PostgreSQL error is a foreign key violation,
+// e.g. deleting a row that others still reference.
+func isForeignKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "violates foreign key constraint") || strings.Contains(msg, "23503")
+}
@@ -13,16 +13,19 @@ import (
 	"manpower-backend/internal/ctxkeys"
 	"manpower-backend/internal/database"
 	"manpower-backend/internal/models"
+	"manpower-backend/internal/storage"
 )
 
 // CompanyHandler handles company-related HTTP requests.
 type CompanyHandler struct {
-	db database.Service
+	db    database.Service
+	files fileLinks
 }
 
-// NewCompanyHandler creates a new CompanyHandler with the provided database service.
-func NewCompanyHandler(db database.Service) *CompanyHandler {
-	return &CompanyHandler{db: db}
+// NewCompanyHandler creates a new CompanyHandler with the provided database
+// service. The file store signs employee photo URLs, valid for urlTTL.
+func NewCompanyHandler(db database.Service, store storage.Store, urlTTL time.Duration) *CompanyHandler {
+	return &CompanyHandler{db: db, files: fileLinks{store: store, ttl: urlTTL}}
 }
 
 // ── List ───────────────────────────────────────────────────────
@@ -209,6 +212,7 @@ func (h *CompanyHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		Trade            string  `json:"trade"`
 		Status           string  `json:"status"`
 		PhotoURL         *string `json:"photoUrl"`
+		ThumbnailURL     *string `json:"thumbnailUrl,omitempty"`
 		Nationality      *string `json:"nationality"`
 		ComplianceStatus string  `json:"complianceStatus"`
 		UrgentDocType    *string `json:"urgentDocType"`
@@ -224,6 +228,20 @@ func (h *CompanyHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		employees = append(employees, emp)
 	}
 
+	var photoKeys []string
+	for _, emp := range employees {
+		if emp.PhotoURL != nil && *emp.PhotoURL != "" {
+			photoKeys = append(photoKeys, h.files.key(*emp.PhotoURL))
+		}
+	}
+	thumbs := h.files.previews(ctx, h.db.GetPool(), photoKeys)
+	for i := range employees {
+		if p := employees[i].PhotoURL; p != nil && *p != "" {
+			employees[i].ThumbnailURL = thumbs[h.files.key(*p)].thumbnail
+		}
+		h.files.signPtr(ctx, employees[i].PhotoURL)
+	}
+
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"data": map[string]interface{}{
 			"company":   company,
@@ -370,7 +388,10 @@ func (h *CompanyHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 // ── Delete ─────────────────────────────────────────────────────
 
-// Delete removes a company and cascades to its employees and documents.
+// Delete removes a company. Companies that employment periods or salary
+// records refer to (any past or present employee) are kept, with 409:
+// deleting them would lose the payroll history of employees who have moved
+// on.
 func (h *CompanyHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -379,7 +400,25 @@ func (h *CompanyHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	pool := h.db.GetPool()
 
+	var hasHistory bool
+	if err := pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM employment_history WHERE company_id = $1)
+			OR EXISTS (SELECT 1 FROM salary_records WHERE company_id = $1)
+	`, id).Scan(&hasHistory); err != nil {
+		log.Printf("Error checking history of company %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to delete company")
+		return
+	}
+	if hasHistory {
+		JSONError(w, http.StatusConflict, "Company has employment or payroll history and cannot be deleted")
+		return
+	}
+
 	result, err := pool.Exec(ctx, "DELETE FROM companies WHERE id = $1", id)
+	if isForeignKeyError(err) {
+		JSONError(w, http.StatusConflict, "Company has employment or payroll history and cannot be deleted")
+		return
+	}
 	if err != nil {
 		log.Printf("Error deleting company: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Failed to delete company")
@@ -2,14 +2,13 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -17,17 +16,31 @@ import (
 	"manpower-backend/internal/compliance"
 	"manpower-backend/internal/ctxkeys"
 	"manpower-backend/internal/database"
+	"manpower-backend/internal/filescan"
 	"manpower-backend/internal/models"
+	"manpower-backend/internal/storage"
+	"manpower-backend/internal/watermark"
 )
 
 // DocumentHandler handles document-related HTTP requests.
+// Documents carry the storage key of their file in fileUrl; the file itself is
+// only reachable through Download or a signed URL from FileURL.
 type DocumentHandler struct {
-	db database.Service
+	db      database.Service
+	scans   *filescan.Service
+	stamps  *watermark.Stamper
+	uploads *UploadHandler
+	files   fileLinks
 }
 
-// NewDocumentHandler creates a new DocumentHandler.
-func NewDocumentHandler(db database.Service) *DocumentHandler {
-	return &DocumentHandler{db: db}
+// NewDocumentHandler creates a new DocumentHandler. Files are only handed out
+// once scans has found them clean; signed file URLs are valid for urlTTL.
+// stamps watermarks the copies issued through Download, and scans imported
+// in bulk are stored through uploads like any other upload.
+func NewDocumentHandler(db database.Service, store storage.Store, scans *filescan.Service,
+	stamps *watermark.Stamper, uploads *UploadHandler, urlTTL time.Duration) *DocumentHandler {
+	return &DocumentHandler{db: db, scans: scans, stamps: stamps, uploads: uploads,
+		files: fileLinks{store: store, ttl: urlTTL}}
 }
 
 // ── Column lists & scan helpers ──────────────────────────────────
@@ -36,13 +49,13 @@ func NewDocumentHandler(db database.Service) *DocumentHandler {
 const docCols = `d.id, d.employee_id, d.document_type,
 	d.document_number, COALESCE(d.issue_date::text, ''), COALESCE(d.expiry_date::text, ''),
 	COALESCE(d.metadata::text, '{}'),
-	d.file_url, d.file_name, d.file_size, d.file_type,
+	d.file_url, d.file_name, d.file_size, d.file_type, d.file_sha256,
 	d.last_updated, d.created_at`
 
 const docRetCols = `id, employee_id, document_type,
 	document_number, COALESCE(issue_date::text, ''), COALESCE(expiry_date::text, ''),
 	COALESCE(metadata::text, '{}'),
-	file_url, file_name, file_size, file_type,
+	file_url, file_name, file_size, file_type, file_sha256,
 	last_updated, created_at`
 
 // scanDocument reads all Document columns from a row/rows scanner.
@@ -56,7 +69,7 @@ func scanDocument(scanner interface {
 		&doc.ID, &doc.EmployeeID, &doc.DocumentType,
 		&docNumber, &issueDateRaw, &expiryRaw,
 		&metadataRaw,
-		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType,
+		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType, &doc.FileSHA256,
 		&doc.LastUpdated, &doc.CreatedAt,
 	)
 	if err != nil {
@@ -90,7 +103,7 @@ func scanDocumentWithRule(scanner interface {
 		&doc.ID, &doc.EmployeeID, &doc.DocumentType,
 		&docNumber, &issueDateRaw, &expiryRaw,
 		&metadataRaw,
-		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType,
+		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType, &doc.FileSHA256,
 		&doc.LastUpdated, &doc.CreatedAt,
 		&rule.GracePeriodDays, &rule.FinePerDay, &rule.FineType, &rule.FineCap,
 		dtMandatory,
@@ -126,7 +139,7 @@ func scanDocumentWithRuleAndDisplayName(scanner interface {
 		&doc.ID, &doc.EmployeeID, &doc.DocumentType,
 		&docNumber, &issueDateRaw, &expiryRaw,
 		&metadataRaw,
-		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType,
+		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType, &doc.FileSHA256,
 		&doc.LastUpdated, &doc.CreatedAt,
 		&rule.GracePeriodDays, &rule.FinePerDay, &rule.FineType, &rule.FineCap,
 		dtMandatory,
@@ -269,25 +282,58 @@ func (h *DocumentHandler) Create(w http.ResponseWriter, r *http.Request) {
 		metadata = json.RawMessage(`{}`)
 	}
 
+	// The first file is also the document's own (primary) file
+	files := req.Files
+	if len(files) == 0 && req.FileURL != "" {
+		files = []models.AddDocumentFileRequest{{
+			FileURL: req.FileURL, FileName: req.FileName, FileSize: req.FileSize, FileType: req.FileType,
+		}}
+	}
+	var primary models.AddDocumentFileRequest
+	if len(files) > 0 {
+		primary = files[0]
+	}
+	fileKey := h.files.key(primary.FileURL)
+	if err := h.files.checkAttachable(ctx, pool, fileURLs(files)...); err != nil {
+		writeAttachError(w, err)
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	var doc models.Document
-	err := pool.QueryRow(ctx, fmt.Sprintf(`
+	row := tx.QueryRow(ctx, fmt.Sprintf(`
 		INSERT INTO documents (
 			employee_id, document_type, document_number, issue_date, expiry_date,
-			metadata, file_url, file_name, file_size, file_type
+			metadata, file_url, file_name, file_size, file_type, file_sha256
 		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
 		RETURNING %s
 	`, docRetCols),
 		employeeID, req.DocumentType,
 		req.DocumentNumber, req.IssueDate, req.ExpiryDate,
 		string(metadata),
-		req.FileURL, req.FileName, req.FileSize, req.FileType,
+		fileKey, primary.FileName, primary.FileSize, primary.FileType, h.files.checksum(fileKey),
 	)
-	if err2 := scanDocument(err, &doc); err2 != nil {
-		log.Printf("Error creating document: %v", err2)
+	if err := scanDocument(row, &doc); err != nil {
+		log.Printf("Error creating document: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Failed to create document")
 		return
 	}
+	if err := h.files.insertFiles(ctx, tx, doc.ID, 0, files); err != nil {
+		log.Printf("Error attaching files to document %s: %v", doc.ID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to create document")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to commit document")
+		return
+	}
 
 	// Populate IsMandatory from document_types
 	_ = pool.QueryRow(ctx,
@@ -297,7 +343,7 @@ func (h *DocumentHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "created", "document", doc.ID, map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "created", "document", doc.ID, map[string]interface{}{
 		"type": doc.DocumentType, "employeeId": employeeID,
 	})
 
@@ -319,6 +365,7 @@ func (h *DocumentHandler) Create(w http.ResponseWriter, r *http.Request) {
 		rulePtr = &rule
 	}
 
+	h.files.withFiles(ctx, pool, &doc)
 	result := enrichWithCompliance(&doc, rulePtr, h.documentDisplayName(ctx, doc.DocumentType))
 	JSON(w, http.StatusCreated, map[string]interface{}{
 		"data":    result,
@@ -395,6 +442,11 @@ func (h *DocumentHandler) ListByEmployee(w http.ResponseWriter, r *http.Request)
 		}
 		documents = append(documents, enrichWithCompliance(&doc, rulePtr, dn))
 	}
+	docPtrs := make([]*models.Document, len(documents))
+	for i := range documents {
+		docPtrs[i] = &documents[i].Document
+	}
+	h.files.withFiles(ctx, pool, docPtrs...)
 
 	mandatoryTotal := 0
 	mandatoryComplete := 0
@@ -464,7 +516,7 @@ func (h *DocumentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		&doc.ID, &doc.EmployeeID, &doc.DocumentType,
 		&docNumber, &issueDateRaw, &expiryRaw,
 		&metadataRaw,
-		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType,
+		&doc.FileURL, &doc.FileName, &doc.FileSize, &doc.FileType, &doc.FileSHA256,
 		&doc.LastUpdated, &doc.CreatedAt,
 		&rule.GracePeriodDays, &rule.FinePerDay, &rule.FineType, &rule.FineCap,
 		&doc.IsMandatory,
@@ -491,6 +543,7 @@ func (h *DocumentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		rulePtr = &rule
 	}
 
+	h.files.withFiles(ctx, pool, &doc)
 	result := enrichWithCompliance(&doc, rulePtr, displayName)
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"data": map[string]interface{}{
@@ -502,80 +555,232 @@ func (h *DocumentHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 }
 
 // Download handles GET /api/documents/{id}/download — serves the file with Content-Disposition: attachment.
+// Each file is copied to a temporary file and checked against its recorded
+// SHA-256 before it is sent: a single file that fails is refused with 500. A
+// document with several attachments is streamed as a ZIP of all of them,
+// verified entry by entry, with the connection broken if one fails; or
+// ?file=<fileId> picks one.
+//
+// With ?recipient=&purpose= (and optionally repeated ?redact=[page:]x,y,w,h)
+// it serves an issued copy instead: watermarked with who it is for, when and
+// why, with the given regions blacked out. Copies are made in memory, after
+// the originals are verified. Every download is recorded in
+// document_downloads.
 func (h *DocumentHandler) Download(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
 		JSONError(w, http.StatusBadRequest, "Document ID is required")
 		return
 	}
+	issue, msg := parseIssuedCopy(r)
+	if msg != "" {
+		JSONError(w, http.StatusBadRequest, msg)
+		return
+	}
 
 	if !checkDocumentAccess(r.Context(), h.db.GetPool(), id) {
 		JSONError(w, http.StatusForbidden, "Access denied to this document")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	timeout := 10 * time.Second
+	if issue != nil {
+		timeout = 60 * time.Second // PDFs are rendered page by page
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
 	defer cancel()
 
-	var fileURL, fileName, fileType string
-	err := h.db.GetPool().QueryRow(ctx,
-		`SELECT file_url, COALESCE(file_name, ''), COALESCE(file_type, 'application/octet-stream') FROM documents WHERE id = $1`,
-		id,
-	).Scan(&fileURL, &fileName, &fileType)
+	pool := h.db.GetPool()
+	var employeeID, docType string
+	err := pool.QueryRow(ctx,
+		`SELECT employee_id, document_type FROM documents WHERE id = $1`, id,
+	).Scan(&employeeID, &docType)
 	if err != nil {
 		log.Printf("Error fetching document %s for download: %v", id, err)
 		JSONError(w, http.StatusNotFound, "Document not found")
 		return
 	}
-	if fileURL == "" {
+	files, err := documentFiles(ctx, pool, id)
+	if err != nil {
+		log.Printf("Error fetching files of document %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch document files")
+		return
+	}
+	fileID := r.URL.Query().Get("file")
+	if fileID != "" {
+		files = pickFile(files, fileID)
+	}
+	if len(files) == 0 {
 		JSONError(w, http.StatusNotFound, "No file attached to this document")
 		return
 	}
 
-	disposition := "attachment"
-	if fileName != "" {
-		disposition = fmt.Sprintf("attachment; filename=%q", fileName)
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	var issuedBy string
+	if issue != nil {
+		pool.QueryRow(ctx, `SELECT name FROM users WHERE id = $1`, userID).Scan(&issuedBy)
 	}
-	w.Header().Set("Content-Disposition", disposition)
-	w.Header().Set("Content-Type", fileType)
 
-	// Local storage: fileUrl is like "http://host/api/files/documents/123_file.pdf" — serve from uploads dir
-	if idx := strings.Index(fileURL, "/api/files/"); idx >= 0 {
-		pathAfter := strings.TrimPrefix(fileURL[idx:], "/api/files/")
-		pathAfter = strings.TrimPrefix(pathAfter, "/")
-		if pathAfter == "" || strings.Contains(pathAfter, "..") {
-			JSONError(w, http.StatusBadRequest, "Invalid file path")
+	// Refuse files that are not scanned clean before the first byte goes out
+	for i := range files {
+		if err := h.checkScan(ctx, h.files.key(files[i].FileURL)); err != nil {
+			writeFileError(w, id, err)
 			return
 		}
-		uploadDir := os.Getenv("UPLOAD_DIR")
-		if uploadDir == "" {
-			uploadDir = "./uploads"
+	}
+	logID := ""
+	if len(files) == 1 {
+		logID = files[0].ID
+	}
+
+	if issue != nil {
+		// Issued copies are stamped whole, in memory, before anything is sent
+		bodies := make([][]byte, len(files))
+		types := make([]string, len(files))
+		var sum string
+		for i := range files {
+			data, fileSum, err := h.readFile(ctx, id, &files[i])
+			if err != nil {
+				writeFileError(w, id, err)
+				return
+			}
+			if bodies[i], types[i], err = h.stampCopy(ctx, issue, issuedBy, data); err != nil {
+				writeFileError(w, id, err)
+				return
+			}
+			sum = fileSum
+		}
+		w.Header().Set("X-Copy-Reference", issue.Reference)
+		if len(files) == 1 {
+			digest := sha256.Sum256(bodies[0])
+			h.logDownload(ctx, id, logID, employeeID, docType, userID, issue, sum, hex.EncodeToString(digest[:]))
+			setDownloadHeaders(w, files[0].FileName, types[0], int64(len(bodies[0])), digest[:])
+			if _, err := w.Write(bodies[0]); err != nil {
+				log.Printf("Error streaming file for document %s: %v", id, err)
+			}
+			return
+		}
+		sent, err := streamZip(w, docType, files, func(i int, dst io.Writer) error {
+			_, err := dst.Write(bodies[i])
+			return err
+		})
+		if err != nil {
+			log.Printf("Error streaming files of document %s: %v", id, err)
+			return
 		}
-		localPath := filepath.Join(uploadDir, filepath.Clean(filepath.FromSlash(pathAfter)))
-		f, err := os.Open(localPath)
+		h.logDownload(ctx, id, logID, employeeID, docType, userID, issue, "", sent)
+		return
+	}
+
+	// Originals are copied to temporary files and verified before they are
+	// sent, so a file that fails its checksum is refused, not delivered
+	ctx, cancel = context.WithTimeout(r.Context(), 10*time.Minute)
+	defer cancel()
+
+	if len(files) == 1 {
+		f := &files[0]
+		sp, size, sum, err := h.spoolFile(ctx, id, f)
 		if err != nil {
-			log.Printf("Error opening file %s: %v", localPath, err)
-			JSONError(w, http.StatusNotFound, "File not found")
+			writeFileError(w, id, err)
 			return
 		}
-		defer f.Close()
-		io.Copy(w, f)
+		defer sp.Close()
+
+		digest, _ := hex.DecodeString(sum)
+		setDownloadHeaders(w, f.FileName, f.FileType, size, digest)
+		if _, err := io.Copy(w, sp); err != nil {
+			log.Printf("Error streaming file for document %s: %v", id, err)
+			return
+		}
+		h.logDownload(ctx, id, logID, employeeID, docType, userID, nil, sum, sum)
+		return
+	}
+
+	sent, err := streamZip(w, docType, files, func(i int, dst io.Writer) error {
+		sp, _, _, err := h.spoolFile(ctx, id, &files[i])
+		if err != nil {
+			return err
+		}
+		defer sp.Close()
+		_, err = io.Copy(dst, sp)
+		return err
+	})
+	if err != nil {
+		// Earlier entries are already out: break the connection so the
+		// client sees a failed transfer rather than a short archive
+		log.Printf("Error streaming files of document %s: %v", id, err)
+		panic(http.ErrAbortHandler)
+	}
+	h.logDownload(ctx, id, logID, employeeID, docType, userID, nil, "", sent)
+}
+
+// FileURL handles GET /api/documents/{id}/file-url — mints a short-lived
+// signed URL for viewing the document's file in the browser: the primary
+// file, or the attachment given by ?file=<fileId>.
+func (h *DocumentHandler) FileURL(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if !checkDocumentAccess(r.Context(), h.db.GetPool(), id) {
+		JSONError(w, http.StatusForbidden, "Access denied to this document")
 		return
 	}
 
-	// External URL (e.g. R2): proxy and stream with attachment
-	resp, err := http.Get(fileURL)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var fileURL string
+	err := h.db.GetPool().QueryRow(ctx,
+		`SELECT COALESCE(file_url, '') FROM documents WHERE id = $1`, id,
+	).Scan(&fileURL)
+	if fileID := r.URL.Query().Get("file"); fileID != "" && err == nil {
+		err = h.db.GetPool().QueryRow(ctx,
+			`SELECT file_url FROM document_files WHERE id = $1 AND document_id = $2`, fileID, id,
+		).Scan(&fileURL)
+	}
 	if err != nil {
-		log.Printf("Error fetching external file %s: %v", fileURL, err)
-		JSONError(w, http.StatusBadGateway, "Failed to fetch file")
+		JSONError(w, http.StatusNotFound, "Document not found")
+		return
+	}
+	if fileURL == "" {
+		JSONError(w, http.StatusNotFound, "No file attached to this document")
+		return
+	}
+	if !h.requireScanned(ctx, w, fileURL) {
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		JSONError(w, http.StatusBadGateway, "Failed to fetch file")
+
+	signed := h.files.sign(ctx, fileURL)
+	if signed == "" {
+		JSONError(w, http.StatusInternalServerError, "Failed to create file URL")
 		return
 	}
-	io.Copy(w, resp.Body)
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"url":       signed,
+			"expiresAt": time.Now().Add(h.files.ttl).UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// requireScanned writes an error and returns false unless the malware scan
+// found the file clean.
+func (h *DocumentHandler) requireScanned(ctx context.Context, w http.ResponseWriter, fileURL string) bool {
+	status, err := h.scans.Status(ctx, h.files.key(fileURL))
+	if err != nil {
+		log.Printf("Error fetching scan status: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to check file")
+		return false
+	}
+	switch status {
+	case filescan.StatusClean:
+		return true
+	case filescan.StatusInfected:
+		JSONError(w, http.StatusForbidden, "File was flagged as malware and has been quarantined")
+	default:
+		JSONError(w, http.StatusConflict, "File has not been scanned for malware yet. Try again shortly.")
+	}
+	return false
 }
 
 // ── Update ───────────────────────────────────────────────────────
@@ -635,9 +840,14 @@ func (h *DocumentHandler) Update(w http.ResponseWriter, r *http.Request) {
 		argIdx++
 	}
 	if req.FileURL != nil {
-		setClauses = append(setClauses, fmt.Sprintf("file_url = $%d", argIdx))
-		args = append(args, *req.FileURL)
-		argIdx++
+		if err := h.files.checkAttachable(ctx, pool, *req.FileURL); err != nil {
+			writeAttachError(w, err)
+			return
+		}
+		fileKey := h.files.key(*req.FileURL)
+		setClauses = append(setClauses, fmt.Sprintf("file_url = $%d, file_sha256 = $%d", argIdx, argIdx+1))
+		args = append(args, fileKey, h.files.checksum(fileKey))
+		argIdx += 2
 	}
 	if req.FileName != nil {
 		setClauses = append(setClauses, fmt.Sprintf("file_name = $%d", argIdx))
@@ -677,12 +887,50 @@ func (h *DocumentHandler) Update(w http.ResponseWriter, r *http.Request) {
 	`, setStr, argIdx, docRetCols)
 	args = append(args, id)
 
-	var doc models.Document
-	if err := scanDocument(pool.QueryRow(ctx, query, args...), &doc); err != nil {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// The row before the update, for the change set
+	var before, doc models.Document
+	if err := scanDocument(tx.QueryRow(ctx,
+		fmt.Sprintf(`SELECT %s FROM documents d WHERE d.id = $1 FOR UPDATE`, docCols), id), &before); err != nil {
+		JSONError(w, http.StatusNotFound, "Document not found")
+		return
+	}
+	if err := scanDocument(tx.QueryRow(ctx, query, args...), &doc); err != nil {
 		log.Printf("Error updating document %s: %v", id, err)
 		JSONError(w, http.StatusNotFound, "Document not found")
 		return
 	}
+	// Setting the file directly replaces the first attachment
+	if req.FileURL != nil || req.FileName != nil || req.FileSize != nil || req.FileType != nil {
+		if err := mirrorPrimaryFile(ctx, tx, id); err != nil {
+			log.Printf("Error updating files of document %s: %v", id, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to update document")
+			return
+		}
+		if err := scanDocument(tx.QueryRow(ctx,
+			fmt.Sprintf(`SELECT %s FROM documents d WHERE d.id = $1`, docCols), id), &doc); err != nil {
+			log.Printf("Error re-reading document %s: %v", id, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to update document")
+			return
+		}
+	}
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	changes := diffFields(documentFields(&before), documentFields(&doc))
+	if err := recordChanges(ctx, tx, userID, "updated", "document", id, changes); err != nil {
+		log.Printf("Error recording changes of document %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to update document")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to commit document update")
+		return
+	}
 
 	// Populate IsMandatory from document_types
 	_ = pool.QueryRow(ctx,
@@ -691,9 +939,8 @@ func (h *DocumentHandler) Update(w http.ResponseWriter, r *http.Request) {
 	).Scan(&doc.IsMandatory)
 
 	// Audit trail
-	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "updated", "document", doc.ID, map[string]interface{}{
-		"type": doc.DocumentType,
+	logActivity(r.Context(), pool, userID, "updated", "document", doc.ID, map[string]interface{}{
+		"type": doc.DocumentType, "fields": changedFields(changes),
 	})
 
 	// Fetch compliance rule for enrichment
@@ -714,6 +961,7 @@ func (h *DocumentHandler) Update(w http.ResponseWriter, r *http.Request) {
 		rulePtr = &rule
 	}
 
+	h.files.withFiles(ctx, pool, &doc)
 	result := enrichWithCompliance(&doc, rulePtr, h.documentDisplayName(ctx, doc.DocumentType))
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"data":    result,
@@ -755,7 +1003,7 @@ func (h *DocumentHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "deleted", "document", id, nil)
+	logActivity(r.Context(), pool, userID, "deleted", "document", id, nil)
 
 	JSON(w, http.StatusOK, map[string]string{
 		"message": "Document deleted successfully",
@@ -794,7 +1042,7 @@ func (h *DocumentHandler) BatchDelete(w http.ResponseWriter, r *http.Request) {
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
 	for _, id := range req.IDs {
-		logActivity(pool, userID, "deleted", "document", id, nil)
+		logActivity(r.Context(), pool, userID, "deleted", "document", id, nil)
 	}
 
 	JSON(w, http.StatusOK, map[string]interface{}{
@@ -828,6 +1076,9 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 		FileName       string          `json:"fileName,omitempty"`
 		FileSize       int64           `json:"fileSize,omitempty"`
 		FileType       string          `json:"fileType,omitempty"`
+		// Files replaces every attachment; without it (and without fileUrl)
+		// the renewed document keeps the old one's files.
+		Files []models.AddDocumentFileRequest `json:"files,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
@@ -837,6 +1088,16 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 		JSONError(w, http.StatusUnprocessableEntity, "New expiry date is required")
 		return
 	}
+	if len(req.Files) > models.MaxDocumentFiles {
+		JSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("A document can have at most %d files", models.MaxDocumentFiles))
+		return
+	}
+	for _, f := range req.Files {
+		if f.FileURL == "" {
+			JSONError(w, http.StatusUnprocessableEntity, "Every file needs a fileUrl")
+			return
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
@@ -860,9 +1121,18 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 	if req.IssueDate != nil {
 		issueDate = req.IssueDate
 	}
-	fileURL := oldDoc.FileURL
+	if len(req.Files) > 0 {
+		req.FileURL, req.FileName, req.FileSize, req.FileType =
+			req.Files[0].FileURL, req.Files[0].FileName, req.Files[0].FileSize, req.Files[0].FileType
+	}
+	if err := h.files.checkAttachable(ctx, pool, append(fileURLs(req.Files), req.FileURL)...); err != nil {
+		writeAttachError(w, err)
+		return
+	}
+	fileURL, fileSHA256 := oldDoc.FileURL, oldDoc.FileSHA256
 	if req.FileURL != "" {
-		fileURL = req.FileURL
+		fileURL = h.files.key(req.FileURL)
+		fileSHA256 = h.files.checksum(fileURL)
 	}
 	fileName := oldDoc.FileName
 	if req.FileName != "" {
@@ -894,15 +1164,15 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 		INSERT INTO documents (
 			employee_id, document_type, document_number, issue_date, expiry_date,
 			metadata,
-			file_url, file_name, file_size, file_type
+			file_url, file_name, file_size, file_type, file_sha256
 		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
 		RETURNING %s
 	`, docRetCols),
 		oldDoc.EmployeeID, oldDoc.DocumentType,
 		docNumber, issueDate, req.ExpiryDate,
 		string(metadata),
-		fileURL, fileName, fileSize, fileType,
+		fileURL, fileName, fileSize, fileType, fileSHA256,
 	)
 
 	if err := scanDocument(newRow, &newDoc); err != nil {
@@ -911,6 +1181,28 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Attachments: the new files, the single new file, or the old ones
+	switch {
+	case len(req.Files) > 0:
+		err = h.files.insertFiles(ctx, tx, newDoc.ID, 0, req.Files)
+	case req.FileURL != "":
+		err = mirrorPrimaryFile(ctx, tx, newDoc.ID)
+	default:
+		_, err = tx.Exec(ctx, `
+			INSERT INTO document_files (document_id, position, label, file_url, file_name, file_size, file_type, file_sha256)
+			SELECT $2, position, label, file_url, file_name, file_size, file_type, file_sha256
+			FROM document_files WHERE document_id = $1
+		`, oldID, newDoc.ID)
+		if err == nil {
+			err = mirrorPrimaryFile(ctx, tx, newDoc.ID) // old document without attachment rows
+		}
+	}
+	if err != nil {
+		log.Printf("Error attaching files to renewed document: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to create renewed document")
+		return
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		JSONError(w, http.StatusInternalServerError, "Failed to commit renewal")
 		return
@@ -924,7 +1216,7 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "renewed", "document", newDoc.ID, map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "renewed", "document", newDoc.ID, map[string]interface{}{
 		"previousDocId": oldID, "type": oldDoc.DocumentType, "newExpiry": req.ExpiryDate,
 	})
 
@@ -946,6 +1238,7 @@ func (h *DocumentHandler) Renew(w http.ResponseWriter, r *http.Request) {
 		rulePtr = &rule
 	}
 
+	h.files.withFiles(ctx, pool, &newDoc)
 	result := enrichWithCompliance(&newDoc, rulePtr, h.documentDisplayName(ctx, newDoc.DocumentType))
 	JSON(w, http.StatusCreated, map[string]interface{}{
 		"data":    result,
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/storage"
+)
+
+// maxArchiveFiles caps the files in one archive; larger selections have to
+// be split by the caller.
+const maxArchiveFiles = 1000
+
+// archiveEntry is one file of a selected document and where it goes in the ZIP.
+type archiveEntry struct {
+	doc      models.DocumentWithCompliance
+	file     models.DocumentFile
+	parts    int // how many files the document has
+	employee string
+	company  string
+	path     string // inside the ZIP; "" when the file was left out
+	note     string // why the file was left out
+}
+
+// ── Archive ──────────────────────────────────────────────────────
+
+// Archive handles POST /api/documents/archive — streams a ZIP of the files of
+// every matching document, laid out as Company/Employee/DocType_Number.ext,
+// with a manifest.csv listing every selected document. Files that are not
+// scanned clean, are missing, or fail their checksum are left out and listed
+// in the manifest with the reason. Included files are recorded in the
+// download log.
+func (h *DocumentHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	var req models.DocumentArchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	pool := h.db.GetPool()
+	if req.CompanyID != "" && !checkCompanyAccess(r.Context(), req.CompanyID) {
+		JSONError(w, http.StatusForbidden, "Access denied to this company")
+		return
+	}
+	for _, id := range req.EmployeeIDs {
+		if !checkEmployeeAccess(r.Context(), pool, id) {
+			JSONError(w, http.StatusForbidden, "Access denied to employee "+id)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	entries, err := h.archiveEntries(ctx, &req)
+	cancel()
+	if err != nil {
+		log.Printf("Error selecting documents for archive: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch documents")
+		return
+	}
+	if len(entries) == 0 {
+		JSONError(w, http.StatusNotFound, "No documents with files match the filter")
+		return
+	}
+	if len(entries) > maxArchiveFiles {
+		JSONError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("%d files match; narrow the filter to at most %d", len(entries), maxArchiveFiles))
+		return
+	}
+
+	// The response is streamed: from here on errors can only be logged
+	ctx, cancel = context.WithTimeout(r.Context(), 10*time.Minute)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", "documents_"+time.Now().Format("20060102_150405")+".zip"))
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	zw := zip.NewWriter(w)
+	used := map[string]int{}
+	included := 0
+	for i := range entries {
+		e := &entries[i]
+		data, sum, err := h.readFile(ctx, e.doc.ID, &e.file)
+		if err != nil {
+			e.note = archiveNote(err)
+			if e.note == "" {
+				log.Printf("Error reading file of document %s for archive: %v", e.doc.ID, err)
+				e.note = "file could not be read"
+			}
+			continue
+		}
+		e.path = uniqueArchivePath(used, archivePath(e))
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     e.path,
+			Method:   zip.Deflate,
+			Modified: e.file.CreatedAt,
+		})
+		if err == nil {
+			_, err = fw.Write(data)
+		}
+		if err != nil {
+			log.Printf("Error writing document %s to archive: %v", e.doc.ID, err)
+			return // client went away
+		}
+		included++
+		h.logDownload(ctx, e.doc.ID, e.file.ID, e.doc.EmployeeID, e.doc.DocumentType, userID, nil, sum, sum)
+	}
+
+	if err := writeArchiveManifest(zw, entries); err != nil {
+		log.Printf("Error writing archive manifest: %v", err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Error finishing archive: %v", err)
+		return
+	}
+
+	logActivity(r.Context(), pool, userID, "archived", "document", "bulk", map[string]interface{}{
+		"employeeIds":   req.EmployeeIDs,
+		"companyId":     req.CompanyID,
+		"documentTypes": req.DocumentTypes,
+		"statuses":      req.Statuses,
+		"selected":      len(entries),
+		"included":      included,
+	})
+}
+
+// archiveEntries selects the documents with a file that match req, within
+// the caller's company scope, ordered by company, employee and slot.
+func (h *DocumentHandler) archiveEntries(ctx context.Context, req *models.DocumentArchiveRequest) ([]archiveEntry, error) {
+	where := "WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+	if len(req.EmployeeIDs) > 0 {
+		where += fmt.Sprintf(" AND d.employee_id = ANY($%d::uuid[])", argIdx)
+		args = append(args, req.EmployeeIDs)
+		argIdx++
+	}
+	if req.CompanyID != "" {
+		where += fmt.Sprintf(" AND e.company_id = $%d", argIdx)
+		args = append(args, req.CompanyID)
+		argIdx++
+	}
+	if len(req.DocumentTypes) > 0 {
+		where += fmt.Sprintf(" AND d.document_type = ANY($%d)", argIdx)
+		args = append(args, req.DocumentTypes)
+		argIdx++
+	}
+	where, args, _ = appendCompanyScope(ctx, where, args, argIdx, "e.company_id")
+
+	rows, err := h.db.GetPool().Query(ctx, fmt.Sprintf(`
+		SELECT %s,
+			COALESCE(cr.grace_period_days, gr.grace_period_days, 0),
+			COALESCE(cr.fine_per_day, gr.fine_per_day, 0),
+			COALESCE(cr.fine_type, gr.fine_type, 'daily'),
+			COALESCE(cr.fine_cap, gr.fine_cap, 0),
+			COALESCE(cr.is_mandatory, dt.is_mandatory),
+			dt.display_name,
+			e.name, c.name,
+			f.id, f.position, f.label, f.file_url, f.file_name, f.file_size, f.file_type, f.file_sha256, f.created_at,
+			COUNT(*) OVER (PARTITION BY d.id)
+		FROM documents d
+		JOIN document_files f ON f.document_id = d.id
+		JOIN employees e ON d.employee_id = e.id
+		JOIN companies c ON e.company_id = c.id
+		LEFT JOIN compliance_rules cr ON cr.doc_type = d.document_type AND cr.company_id = e.company_id
+		LEFT JOIN compliance_rules gr ON gr.doc_type = d.document_type AND gr.company_id IS NULL
+		LEFT JOIN document_types dt ON dt.doc_type = d.document_type AND dt.is_active = TRUE
+		%s
+		ORDER BY c.name, e.name, e.id, COALESCE(dt.sort_order, 100), d.document_type, d.created_at, f.position
+	`, docCols, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := map[string]bool{}
+	for _, s := range req.Statuses {
+		statuses[s] = true
+	}
+
+	var entries []archiveEntry
+	for rows.Next() {
+		var doc models.Document
+		var rule ComplianceRule
+		var dtMandatory *bool
+		var displayName *string
+		var employee, company string
+		var file models.DocumentFile
+		var parts int
+		extra := append([]interface{}{&employee, &company}, documentFileDest(&file)...)
+		row := extraColumns{rows, append(extra, &parts)}
+		if err := scanDocumentWithRuleAndDisplayName(row, &doc, &rule, &dtMandatory, &displayName); err != nil {
+			return nil, err
+		}
+		var rulePtr *ComplianceRule
+		if rule.FinePerDay > 0 || rule.GracePeriodDays > 0 {
+			rulePtr = &rule
+		}
+		dn := ""
+		if displayName != nil {
+			dn = *displayName
+		}
+		dwc := enrichWithCompliance(&doc, rulePtr, dn)
+		if len(statuses) > 0 && !statuses[dwc.Status] {
+			continue
+		}
+		entries = append(entries, archiveEntry{doc: dwc, file: file, parts: parts, employee: employee, company: company})
+	}
+	return entries, rows.Err()
+}
+
+// extraColumns scans the document columns into dest and the columns
+// selected after them into extra.
+type extraColumns struct {
+	row interface {
+		Scan(dest ...interface{}) error
+	}
+	extra []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.row.Scan(append(dest, e.extra...)...)
+}
+
+// archiveNote is the manifest note for a file readFile refused, or "" for
+// unexpected errors.
+func archiveNote(err error) string {
+	switch {
+	case errors.Is(err, errFileInfected), errors.Is(err, errFilePending), errors.Is(err, errFileChecksum):
+		return err.Error()
+	case errors.Is(err, storage.ErrNotFound):
+		return "file not found"
+	}
+	return ""
+}
+
+// archivePath is the entry's place in the ZIP: Company/Employee/DocType_Number.ext,
+// with the file's label (or number) added for documents with several files.
+func archivePath(e *archiveEntry) string {
+	name := e.doc.DisplayName
+	if e.doc.DocumentNumber != nil && *e.doc.DocumentNumber != "" {
+		name += "_" + *e.doc.DocumentNumber
+	}
+	if e.parts > 1 {
+		name += "_" + archivePart(e)
+	}
+	return archiveSegment(e.company) + "/" + archiveSegment(e.employee) + "/" + archiveSegment(name) + strings.ToLower(fileExt(e.file))
+}
+
+// archivePart names a file among its document's files: its label, or
+// "2 of 3"; "" for an unlabelled single file.
+func archivePart(e *archiveEntry) string {
+	switch {
+	case e.file.Label != nil && *e.file.Label != "":
+		return *e.file.Label
+	case e.parts > 1:
+		return fmt.Sprintf("%d of %d", e.file.Position+1, e.parts)
+	}
+	return ""
+}
+
+// archiveSegment makes s safe as one path segment on every OS.
+func archiveSegment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+	s = strings.Trim(s, ". ")
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+// uniqueArchivePath appends " (2)", " (3)", ... before the extension when p
+// is already taken.
+func uniqueArchivePath(used map[string]int, p string) string {
+	used[p]++
+	if used[p] == 1 {
+		return p
+	}
+	ext := path.Ext(p)
+	for n := used[p]; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(p, ext), n, ext)
+		if used[candidate] == 0 {
+			used[candidate] = 1
+			return candidate
+		}
+	}
+}
+
+// writeArchiveManifest adds manifest.csv, listing every selected document.
+// Cells are escaped against formula injection like CSV exports.
+func writeArchiveManifest(zw *zip.Writer, entries []archiveEntry) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "manifest.csv", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(fw)
+	cw.Write([]string{"Company", "Employee", "Document Type", "Document Number",
+		"Issue Date", "Expiry Date", "Status", "Attachment", "File", "SHA-256", "Note"})
+	for i := range entries {
+		e := &entries[i]
+		record := []string{
+			e.company, e.employee, e.doc.DisplayName,
+			nilStringDefault(e.doc.DocumentNumber, ""),
+			nilStringDefault(e.doc.IssueDate, ""),
+			nilStringDefault(e.doc.ExpiryDate, ""),
+			e.doc.Status, archivePart(e), e.path,
+			nilStringDefault(e.file.FileSHA256, ""),
+			e.note,
+		}
+		// Names and numbers are user input; see csvText
+		for j := range record {
+			record[j] = csvText(record[j])
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
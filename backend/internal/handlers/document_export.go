@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ── Export ───────────────────────────────────────────────────────
+
+// documentStatusSQL is compliance.ComputeStatus in SQL, for filtering and
+// exporting many documents at once; it needs the cr and gr rule joins.
+const documentStatusSQL = `CASE
+			WHEN d.expiry_date IS NULL THEN 'incomplete'
+			WHEN d.expiry_date <= CURRENT_DATE
+			 AND (COALESCE(cr.grace_period_days, gr.grace_period_days, 0) = 0
+			  OR CURRENT_DATE - d.expiry_date > COALESCE(cr.grace_period_days, gr.grace_period_days, 0)) THEN 'penalty_active'
+			WHEN d.expiry_date <= CURRENT_DATE THEN 'in_grace'
+			WHEN d.expiry_date <= CURRENT_DATE + 30 THEN 'expiring_soon'
+			WHEN COALESCE(d.document_number, '') = '' THEN 'incomplete'
+			ELSE 'valid'
+		END`
+
+// documentExportColumns are the columns Export can include. As in the
+// employee export, expiry.<doc_type> and number.<doc_type> add the
+// employee's latest document of a type, e.g. the passport number next to
+// each visa.
+var documentExportColumns = []exportColumn{
+	{key: "employee", header: "Employee", expr: "e.name"},
+	{key: "passportNumber", header: "Passport", expr: "e.passport_number"},
+	{key: "trade", header: "Trade", expr: "e.trade"},
+	{key: "company", header: "Company", expr: "c.name"},
+	{key: "documentType", header: "Document Type", expr: "COALESCE(dt.display_name, d.document_type)"},
+	{key: "documentNumber", header: "Document Number", expr: "d.document_number"},
+	{key: "issueDate", header: "Issue Date", expr: "d.issue_date", kind: exportDate},
+	{key: "expiryDate", header: "Expiry Date", expr: "d.expiry_date", kind: exportDate},
+	{key: "status", header: "Status", expr: documentStatusSQL},
+	{key: "daysRemaining", header: "Days Remaining", expr: "d.expiry_date - CURRENT_DATE", kind: exportInt},
+	{key: "gracePeriodDays", header: "Grace Period (days)", expr: "COALESCE(cr.grace_period_days, gr.grace_period_days, 0)", kind: exportInt},
+	{key: "mandatory", header: "Mandatory", expr: "CASE WHEN COALESCE(cr.is_mandatory, dt.is_mandatory) THEN 'Yes' ELSE 'No' END"},
+	{key: "files", header: "Files", expr: "(SELECT COUNT(*) FROM document_files f WHERE f.document_id = d.id)", kind: exportInt},
+	{key: "lastUpdated", header: "Last Updated", expr: "d.last_updated", kind: exportDate},
+}
+
+// documentExportDefaults are the columns exported when none are chosen.
+var documentExportDefaults = []string{
+	"employee", "passportNumber", "company", "documentType", "documentNumber",
+	"issueDate", "expiryDate", "status",
+}
+
+// Export handles GET /api/documents/export — documents across employees as
+// CSV, or with format=xlsx as a workbook with date and number cells.
+// Filters: company_id, employee_id and document_type (comma-separated),
+// status (computed compliance status, comma-separated), search (employee
+// name) and emp_status as in the employee list (exited employees are left
+// out unless "all"). columns picks the columns (documentExportColumns).
+func (h *DocumentHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		JSONError(w, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	q := r.URL.Query()
+	cols, err := pickExportColumns(q.Get("columns"), documentExportColumns, documentExportDefaults,
+		docTypeColumns(ctx, pool, "d.employee_id"))
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	where, args, argIdx := documentExportFilter(ctx, q)
+	selectList, args := exportSelect(cols, args, argIdx)
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM documents d
+		JOIN employees e ON d.employee_id = e.id
+		JOIN companies c ON e.company_id = c.id
+		LEFT JOIN compliance_rules cr ON cr.doc_type = d.document_type AND cr.company_id = e.company_id
+		LEFT JOIN compliance_rules gr ON gr.doc_type = d.document_type AND gr.company_id IS NULL
+		LEFT JOIN document_types dt ON dt.doc_type = d.document_type AND dt.is_active = TRUE
+		%s
+		ORDER BY c.name, e.name, e.id, COALESCE(dt.sort_order, 100), d.document_type, d.created_at
+	`, selectList, where), args...)
+	if err != nil {
+		log.Printf("Error exporting documents: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to export")
+		return
+	}
+	defer rows.Close()
+
+	writeExport(w, rows, cols, format, "documents")
+}
+
+// documentExportFilter builds the WHERE clause of Export, within the
+// caller's company scope.
+func documentExportFilter(ctx context.Context, q url.Values) (string, []interface{}, int) {
+	where := "WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+	where, args, argIdx = appendCompanyScope(ctx, where, args, argIdx, "e.company_id")
+
+	list := func(s string) []string {
+		var out []string
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	if v := q.Get("company_id"); v != "" {
+		where += fmt.Sprintf(" AND e.company_id = $%d", argIdx)
+		args = append(args, v)
+		argIdx++
+	}
+	if v := list(q.Get("employee_id")); len(v) > 0 {
+		where += fmt.Sprintf(" AND d.employee_id::text = ANY($%d)", argIdx)
+		args = append(args, v)
+		argIdx++
+	}
+	if v := list(q.Get("document_type")); len(v) > 0 {
+		where += fmt.Sprintf(" AND d.document_type = ANY($%d)", argIdx)
+		args = append(args, v)
+		argIdx++
+	}
+	if v := list(q.Get("status")); len(v) > 0 {
+		where += fmt.Sprintf(" AND (%s) = ANY($%d)", documentStatusSQL, argIdx)
+		args = append(args, v)
+		argIdx++
+	}
+	if v := q.Get("search"); v != "" {
+		where += fmt.Sprintf(" AND e.name ILIKE $%d", argIdx)
+		args = append(args, "%"+v+"%")
+		argIdx++
+	}
+	switch v := q.Get("emp_status"); v {
+	case "all":
+	case "":
+		where += " AND e.exit_type IS NULL"
+	default:
+		where += fmt.Sprintf(" AND e.exit_type IS NULL AND e.status = $%d", argIdx)
+		args = append(args, v)
+		argIdx++
+	}
+	return where, args, argIdx
+}
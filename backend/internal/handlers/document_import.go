@@ -0,0 +1,801 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"manpower-backend/internal/compliance"
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/models"
+)
+
+// ── Import ───────────────────────────────────────────────────────
+
+// maxImportArchiveSize caps a document import request, scans ZIP included.
+const maxImportArchiveSize = 512 << 20
+
+// documentImportFields are the columns a document sheet can have. Rows name
+// the employee by ID or passport number.
+var documentImportFields = []importField{
+	{"employeeId", []string{"employeeid"}},
+	{"passportNumber", []string{"passport", "passportno", "passportnumber"}},
+	{"documentType", []string{"documenttype", "doctype", "type", "document"}},
+	{"documentNumber", []string{"documentnumber", "docnumber", "docno", "number"}},
+	{"issueDate", []string{"issuedate", "dateofissue", "issued"}},
+	{"expiryDate", []string{"expirydate", "dateofexpiry", "expiry", "expires", "validuntil"}},
+	{"files", []string{"file", "files", "filename", "scan", "scans"}},
+}
+
+// docTypeAliases are short names for built-in document types that sheets
+// and scan file names commonly use (compared after normHeader).
+var docTypeAliases = map[string]string{
+	"eid":        "emirates_id",
+	"labourcard": "work_permit",
+	"laborcard":  "work_permit",
+	"medical":    "medical_fitness",
+	"iloe":       "iloe_insurance",
+	"insurance":  "health_insurance",
+}
+
+// importEmployee is an employee that document rows can refer to.
+type importEmployee struct {
+	id, name, passport string // passport as passportKey
+}
+
+// importDoc is a resolved row of a document import.
+type importDoc struct {
+	employee              *importEmployee
+	docType               string
+	number, issue, expiry *string
+	existing              *importExisting
+	scans                 []*importScan
+	files                 []models.AddDocumentFileRequest // the scans once stored
+}
+
+// importExisting is the employee's current document of the row's type.
+type importExisting struct {
+	id, number, issue, expiry string
+	files                     int
+}
+
+// importScan is a file in the scans ZIP.
+type importScan struct {
+	file  *zip.File
+	name  string // base name
+	label string // from the name, e.g. "Front"
+	used  bool
+}
+
+// Import handles POST /api/documents/import — multipart "file" (CSV or
+// XLSX) with one document per row: the employee (employeeId or
+// passportNumber), documentType (slug or display name), documentNumber,
+// issueDate and expiryDate; see parseImportUpload for mapping, dryRun and
+// skipInvalid. An optional "scans" ZIP holds the files, named
+// <passport number or employee ID>_<document type>[_<label>].<ext>, e.g.
+// N1234567_visa.pdf or N1234567_eid_front.jpg; a "files" column can name
+// them instead.
+//
+// Each row updates the employee's current document of its type — the
+// mandatory slot made when the employee was created — or creates one.
+// Empty cells keep the value on record; scans replace the attachments.
+// The report reconciles the sheet with the records: what changed, scans
+// no row took, and mandatory documents still incomplete afterwards.
+func (h *DocumentHandler) Import(w http.ResponseWriter, r *http.Request) {
+	u := parseImportUpload(w, r, documentImportFields, maxImportArchiveSize)
+	if u == nil {
+		return
+	}
+	if !u.has("employeeId") && !u.has("passportNumber") {
+		JSONError(w, http.StatusUnprocessableEntity, "No column is mapped to employeeId or passportNumber")
+		return
+	}
+	if !u.has("documentType") {
+		JSONError(w, http.StatusUnprocessableEntity, "No column is mapped to documentType")
+		return
+	}
+
+	var archive *zip.Reader
+	if f, fh, err := r.FormFile("scans"); err == nil {
+		defer f.Close()
+		if archive, err = zip.NewReader(f, fh.Size); err != nil {
+			JSONError(w, http.StatusUnprocessableEntity, "scans must be a ZIP file")
+			return
+		}
+	}
+
+	// Scans are virus-checked and stored one by one
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	types, err := importDocTypes(ctx, pool)
+	if err != nil {
+		log.Printf("Error loading document types for import: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import documents")
+		return
+	}
+	byID, byPassport, err := importEmployees(ctx, pool, u)
+	if err != nil {
+		log.Printf("Error loading employees for document import: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import documents")
+		return
+	}
+	allScans, scansByKey, scansByName := indexScans(archive, types)
+
+	report := models.ImportReport{
+		DryRun:  u.dryRun,
+		Columns: u.columns,
+		Fields:  fieldNames(documentImportFields),
+		Rows:    []models.ImportRow{},
+	}
+	var docs []importDoc // parallel to report.Rows
+	seen := map[string]int{}
+	for i, row := range u.rows {
+		if blankRow(row) {
+			continue
+		}
+		line := i + 2
+		d, errs := documentFromRow(u, row, byID, byPassport, types)
+
+		label := u.get(row, "passportNumber")
+		if d.employee != nil {
+			label = d.employee.name
+			key := d.employee.id + "|" + d.docType
+			if first, dup := seen[key]; dup && d.docType != "" {
+				errs["documentType"] = fmt.Sprintf("Same employee and document type as row %d", first)
+			} else {
+				seen[key] = line
+			}
+		}
+		if d.docType != "" {
+			label += " · " + compliance.DisplayName(d.docType)
+		}
+
+		// Scans: named in the row, or found by the file name convention
+		if names := u.get(row, "files"); names != "" {
+			for _, name := range strings.FieldsFunc(names, func(r rune) bool { return r == ';' || r == ',' }) {
+				name = strings.TrimSpace(name)
+				s := scansByName[strings.ToLower(path.Base(name))]
+				if s == nil {
+					errs["files"] = fmt.Sprintf("%s is not in the scans ZIP", name)
+					continue
+				}
+				d.scans = append(d.scans, s)
+			}
+		} else if d.employee != nil && d.docType != "" {
+			d.scans = append(scansByKey[scanKey(d.employee.passport, d.docType)],
+				scansByKey[scanKey(d.employee.id, d.docType)]...)
+		}
+		if len(d.scans) > models.MaxDocumentFiles {
+			errs["files"] = fmt.Sprintf("At most %d files per document", models.MaxDocumentFiles)
+		}
+		limit := h.uploads.sizeLimit("documents")
+		for _, s := range d.scans {
+			s.used = true
+			if err := checkScan(s, limit); err != nil {
+				errs["files"] = err.Error()
+			}
+		}
+
+		report.Rows = append(report.Rows, models.ImportRow{Row: line, Label: label, Status: "valid", Errors: errs})
+		docs = append(docs, d)
+	}
+	report.Total = len(report.Rows)
+	if report.Total == 0 {
+		JSONError(w, http.StatusUnprocessableEntity, "The sheet has no data rows")
+		return
+	}
+	for _, s := range allScans {
+		if !s.used {
+			report.UnmatchedFiles = append(report.UnmatchedFiles, s.file.Name)
+		}
+	}
+
+	if err := loadExistingDocs(ctx, pool, docs); err != nil {
+		log.Printf("Error loading documents for import: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import documents")
+		return
+	}
+	for i := range docs {
+		reconcileDoc(&docs[i], &report.Rows[i])
+	}
+	countImportRows(&report)
+
+	if u.dryRun {
+		if report.Outstanding, err = importOutstanding(ctx, pool, docs, report.Rows, true); err != nil {
+			log.Printf("Error listing outstanding documents: %v", err)
+		}
+		JSON(w, http.StatusOK, map[string]interface{}{"data": report})
+		return
+	}
+	if report.Failed > 0 && !u.skipInvalid {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": fmt.Sprintf("%d row(s) have errors; nothing was imported", report.Failed),
+			"data":  report,
+		})
+		return
+	}
+	if report.Valid == 0 && report.Unchanged == 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": "No valid rows to import",
+			"data":  report,
+		})
+		return
+	}
+
+	// Store the scans first; what a failed import leaves behind is
+	// unreferenced and goes to the orphan cleanup.
+	for i := range docs {
+		row := &report.Rows[i]
+		if row.Status != "valid" {
+			continue
+		}
+		for _, s := range docs[i].scans {
+			f, err := h.storeScan(ctx, s)
+			if err != nil {
+				row.Errors = map[string]string{"files": err.Error()}
+				break
+			}
+			docs[i].files = append(docs[i].files, *f)
+		}
+	}
+	countImportRows(&report)
+	if report.Failed > 0 && !u.skipInvalid {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": fmt.Sprintf("%d row(s) have errors; nothing was imported", report.Failed),
+			"data":  report,
+		})
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import documents")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Status != "valid" {
+			continue
+		}
+		// A savepoint per row, so a failed write only loses its own row
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			log.Printf("Error starting savepoint: %v", err)
+			JSONError(w, http.StatusInternalServerError, "Failed to import documents")
+			return
+		}
+		id, err := h.applyImportDoc(ctx, sp, &docs[i])
+		if err == nil {
+			err = sp.Commit(ctx)
+		}
+		if err != nil {
+			sp.Rollback(ctx)
+			log.Printf("Error importing document on row %d: %v", row.Row, err)
+			row.Errors = map[string]string{"row": "Could not be saved; check the lengths and formats of its values"}
+			continue
+		}
+		row.ID = &id
+		row.Status = "updated"
+		if docs[i].existing == nil {
+			row.Status = "created"
+		}
+	}
+	countImportRows(&report)
+
+	if report.Failed > 0 && !u.skipInvalid {
+		for i := range report.Rows {
+			if s := report.Rows[i].Status; s == "created" || s == "updated" {
+				report.Rows[i].Status, report.Rows[i].ID = "valid", nil
+			}
+		}
+		countImportRows(&report)
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": fmt.Sprintf("%d row(s) could not be saved; nothing was imported", report.Failed),
+			"data":  report,
+		})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing document import: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import documents")
+		return
+	}
+
+	// Audit trail
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	for i, row := range report.Rows {
+		if row.ID != nil {
+			logActivity(r.Context(), pool, userID, "imported", "document", *row.ID, map[string]interface{}{
+				"type": docs[i].docType, "employeeId": docs[i].employee.id,
+				"changes": row.Changes, "files": row.Files, "file": u.fileName, "row": row.Row,
+			})
+		}
+	}
+
+	if report.Outstanding, err = importOutstanding(ctx, pool, docs, report.Rows, false); err != nil {
+		log.Printf("Error listing outstanding documents: %v", err)
+	}
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data":    report,
+		"message": fmt.Sprintf("%d document(s) created, %d updated", report.Created, report.Updated),
+	})
+}
+
+// importDocTypes maps the normalised slugs, display names and aliases of
+// the active document types to their slugs.
+func importDocTypes(ctx context.Context, q dbtx) (map[string]string, error) {
+	types := map[string]string{}
+	rows, err := q.Query(ctx, `SELECT doc_type, display_name FROM document_types WHERE is_active = TRUE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var docType, displayName string
+		if err := rows.Scan(&docType, &displayName); err != nil {
+			return nil, err
+		}
+		types[normHeader(docType)] = docType
+		types[normHeader(displayName)] = docType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Fallback: if the table is empty, the hardcoded mandatory types
+	if len(types) == 0 {
+		for _, md := range compliance.MandatoryDocs {
+			types[normHeader(md.DocType)] = md.DocType
+			types[normHeader(md.DisplayName)] = md.DocType
+		}
+	}
+	for alias, docType := range docTypeAliases {
+		if _, ok := types[normHeader(docType)]; ok {
+			if _, taken := types[alias]; !taken {
+				types[alias] = docType
+			}
+		}
+	}
+	return types, nil
+}
+
+// importEmployees loads the employees the sheet refers to, within the
+// caller's scope, by ID and by passport number.
+func importEmployees(ctx context.Context, q dbtx, u *importUpload) (map[string]*importEmployee, map[string][]*importEmployee, error) {
+	var ids, passports []string
+	for _, row := range u.rows {
+		if id := u.get(row, "employeeId"); id != "" {
+			ids = append(ids, strings.ToLower(id))
+		}
+		if p := u.get(row, "passportNumber"); p != "" {
+			passports = append(passports, passportKey(p))
+		}
+	}
+
+	where, args, _ := appendCompanyScope(ctx, `
+		WHERE (e.id::text = ANY($1)
+		   OR UPPER(REGEXP_REPLACE(COALESCE(e.passport_number, ''), '[\s-]', '', 'g')) = ANY($2))`,
+		[]interface{}{ids, passports}, 3, "e.company_id")
+	rows, err := q.Query(ctx, `
+		SELECT e.id::text, e.name, UPPER(REGEXP_REPLACE(COALESCE(e.passport_number, ''), '[\s-]', '', 'g'))
+		FROM employees e `+where, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	byID := map[string]*importEmployee{}
+	byPassport := map[string][]*importEmployee{}
+	for rows.Next() {
+		e := &importEmployee{}
+		if err := rows.Scan(&e.id, &e.name, &e.passport); err != nil {
+			return nil, nil, err
+		}
+		byID[e.id] = e
+		if e.passport != "" {
+			byPassport[e.passport] = append(byPassport[e.passport], e)
+		}
+	}
+	return byID, byPassport, rows.Err()
+}
+
+// documentFromRow resolves a sheet row to its employee and document type
+// and parses its values.
+func documentFromRow(u *importUpload, row []string, byID map[string]*importEmployee,
+	byPassport map[string][]*importEmployee, types map[string]string) (importDoc, map[string]string) {
+	errs := map[string]string{}
+	var d importDoc
+
+	id, passport := strings.ToLower(u.get(row, "employeeId")), u.get(row, "passportNumber")
+	switch {
+	case id != "":
+		if d.employee = byID[id]; d.employee == nil {
+			errs["employeeId"] = fmt.Sprintf("No employee with ID %q", id)
+		} else if passport != "" && passportKey(passport) != d.employee.passport {
+			errs["passportNumber"] = "Passport number does not match the employee"
+		}
+	case passport != "":
+		switch matches := byPassport[passportKey(passport)]; len(matches) {
+		case 0:
+			errs["passportNumber"] = fmt.Sprintf("No employee with passport number %q", passport)
+		case 1:
+			d.employee = matches[0]
+		default:
+			errs["passportNumber"] = fmt.Sprintf("%d employees have this passport number; use employeeId", len(matches))
+		}
+	default:
+		errs["employeeId"] = "Give an employee ID or passport number"
+	}
+
+	if v := u.get(row, "documentType"); v == "" {
+		errs["documentType"] = "Document type is required"
+	} else if d.docType = types[normHeader(v)]; d.docType == "" {
+		errs["documentType"] = fmt.Sprintf("No document type %q", v)
+	}
+
+	if v := u.get(row, "documentNumber"); v != "" {
+		if len(v) > 100 {
+			errs["documentNumber"] = "Document number must be at most 100 characters"
+		}
+		d.number = &v
+	}
+	for _, f := range []struct {
+		field string
+		dst   **string
+	}{{"issueDate", &d.issue}, {"expiryDate", &d.expiry}} {
+		v := u.get(row, f.field)
+		if v == "" {
+			continue
+		}
+		date, ok := importDate(v)
+		if !ok {
+			errs[f.field] = fmt.Sprintf("%q is not a date; use YYYY-MM-DD or DD/MM/YYYY", v)
+			continue
+		}
+		*f.dst = &date
+	}
+	if d.issue != nil && d.expiry != nil && *d.expiry < *d.issue {
+		errs["expiryDate"] = "Expiry date is before the issue date"
+	}
+	return d, errs
+}
+
+// loadExistingDocs finds each row's current document: the latest of its
+// type for the employee.
+func loadExistingDocs(ctx context.Context, q dbtx, docs []importDoc) error {
+	var ids []string
+	for _, d := range docs {
+		if d.employee != nil {
+			ids = append(ids, d.employee.id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT DISTINCT ON (d.employee_id, d.document_type)
+			d.id::text, d.employee_id::text, d.document_type, COALESCE(d.document_number, ''),
+			COALESCE(d.issue_date::text, ''), COALESCE(d.expiry_date::text, ''),
+			(SELECT COUNT(*) FROM document_files f WHERE f.document_id = d.id)
+		FROM documents d
+		WHERE d.employee_id = ANY($1::uuid[])
+		ORDER BY d.employee_id, d.document_type, d.created_at DESC
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	current := map[string]*importExisting{}
+	for rows.Next() {
+		var employeeID, docType string
+		e := &importExisting{}
+		if err := rows.Scan(&e.id, &employeeID, &docType, &e.number, &e.issue, &e.expiry, &e.files); err != nil {
+			return err
+		}
+		current[employeeID+"|"+docType] = e
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range docs {
+		if d := &docs[i]; d.employee != nil {
+			d.existing = current[d.employee.id+"|"+d.docType]
+		}
+	}
+	return nil
+}
+
+// reconcileDoc fills in what a valid row would change on the record, and
+// warns about values that look stale.
+func reconcileDoc(d *importDoc, row *models.ImportRow) {
+	for _, s := range d.scans {
+		row.Files = append(row.Files, s.name)
+	}
+	if len(row.Errors) > 0 {
+		return
+	}
+	if d.number == nil && d.issue == nil && d.expiry == nil && len(d.scans) == 0 {
+		row.Errors["row"] = "Nothing to import: give a document number, a date or a scan"
+		return
+	}
+	if d.expiry != nil && *d.expiry < time.Now().Format("2006-01-02") {
+		row.Warnings = append(row.Warnings, "Expired on "+*d.expiry)
+	}
+
+	e := d.existing
+	if e == nil {
+		return
+	}
+	row.Changes = map[string]models.ImportChange{}
+	for _, f := range []struct {
+		field    string
+		old      string
+		incoming *string
+	}{{"documentNumber", e.number, d.number}, {"issueDate", e.issue, d.issue}, {"expiryDate", e.expiry, d.expiry}} {
+		if f.incoming != nil && *f.incoming != f.old {
+			row.Changes[f.field] = models.ImportChange{From: f.old, To: *f.incoming}
+		}
+	}
+	if d.expiry != nil && e.expiry != "" && *d.expiry < e.expiry {
+		row.Warnings = append(row.Warnings, "The expiry date on record is later ("+e.expiry+")")
+	}
+	if len(d.scans) > 0 && e.files > 0 {
+		row.Warnings = append(row.Warnings, fmt.Sprintf("Replaces the %d file(s) attached now", e.files))
+	}
+	if len(row.Changes) == 0 && len(d.scans) == 0 {
+		row.Status, row.ID = "unchanged", &e.id
+	}
+}
+
+// applyImportDoc writes a row: the values it has onto the current
+// document, or a new one, and its scans as the attachments.
+func (h *DocumentHandler) applyImportDoc(ctx context.Context, q dbtx, d *importDoc) (string, error) {
+	var id string
+	if d.existing != nil {
+		id = d.existing.id
+		if _, err := q.Exec(ctx, `
+			UPDATE documents SET
+				document_number = COALESCE($2, document_number),
+				issue_date = COALESCE($3::date, issue_date),
+				expiry_date = COALESCE($4::date, expiry_date),
+				last_updated = NOW()
+			WHERE id = $1
+		`, id, d.number, d.issue, d.expiry); err != nil {
+			return "", err
+		}
+	} else if err := q.QueryRow(ctx, `
+		INSERT INTO documents (
+			employee_id, document_type, document_number, issue_date, expiry_date,
+			file_url, file_name, file_size, file_type
+		)
+		VALUES ($1, $2, $3, $4, $5, '', '', 0, '')
+		RETURNING id::text
+	`, d.employee.id, d.docType, d.number, d.issue, d.expiry).Scan(&id); err != nil {
+		return "", err
+	}
+
+	if len(d.files) > 0 {
+		if _, err := q.Exec(ctx, `DELETE FROM document_files WHERE document_id = $1`, id); err != nil {
+			return "", err
+		}
+		if err := h.files.insertFiles(ctx, q, id, 0, d.files); err != nil {
+			return "", err
+		}
+		if err := syncPrimaryFile(ctx, q, id); err != nil {
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// importOutstanding lists the mandatory documents of the sheet's employees
+// that lack a number or expiry date. With pending set (a dry run), the
+// values of the valid rows are counted as if saved.
+func importOutstanding(ctx context.Context, q dbtx, docs []importDoc, rows []models.ImportRow, pending bool) ([]models.ImportOutstanding, error) {
+	var ids []string
+	seen := map[string]bool{}
+	incoming := map[string]*importDoc{}
+	for i := range docs {
+		d := &docs[i]
+		if d.employee == nil {
+			continue
+		}
+		if !seen[d.employee.id] {
+			seen[d.employee.id] = true
+			ids = append(ids, d.employee.id)
+		}
+		if pending && rows[i].Status == "valid" {
+			incoming[d.employee.id+"|"+d.docType] = d
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	res, err := q.Query(ctx, `
+		SELECT DISTINCT ON (e.id, d.document_type)
+			e.id::text, e.name, d.document_type,
+			COALESCE(d.document_number, '') <> '', d.expiry_date IS NOT NULL
+		FROM employees e
+		JOIN documents d ON d.employee_id = e.id
+		LEFT JOIN document_types dt ON dt.doc_type = d.document_type AND dt.is_active = TRUE
+		LEFT JOIN compliance_rules cr ON cr.doc_type = d.document_type AND cr.company_id = e.company_id
+		WHERE e.id = ANY($1::uuid[]) AND COALESCE(cr.is_mandatory, dt.is_mandatory) = TRUE
+		ORDER BY e.id, d.document_type, d.created_at DESC
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	byEmployee := map[string]*models.ImportOutstanding{}
+	for res.Next() {
+		var id, name, docType string
+		var hasNumber, hasExpiry bool
+		if err := res.Scan(&id, &name, &docType, &hasNumber, &hasExpiry); err != nil {
+			return nil, err
+		}
+		if d := incoming[id+"|"+docType]; d != nil {
+			hasNumber = hasNumber || d.number != nil
+			hasExpiry = hasExpiry || d.expiry != nil
+		}
+		if hasNumber && hasExpiry {
+			continue
+		}
+		o := byEmployee[id]
+		if o == nil {
+			o = &models.ImportOutstanding{EmployeeID: id, Name: name}
+			byEmployee[id] = o
+		}
+		o.DocumentTypes = append(o.DocumentTypes, docType)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+
+	// In sheet order
+	var out []models.ImportOutstanding
+	for _, id := range ids {
+		if o := byEmployee[id]; o != nil {
+			out = append(out, *o)
+		}
+	}
+	return out, nil
+}
+
+// ── Scans ────────────────────────────────────────────────────────
+
+// scanKey is how scans are matched to rows: the normalised identifier from
+// the file name (passport number or employee ID) and the document type.
+func scanKey(ident, docType string) string {
+	return strings.ToLower(passportKey(ident)) + "|" + docType
+}
+
+// indexScans lists the files in the scans ZIP, by scanKey (for those named
+// by the convention, ordered front to back) and by lowercased base name.
+func indexScans(archive *zip.Reader, types map[string]string) ([]*importScan, map[string][]*importScan, map[string]*importScan) {
+	byKey := map[string][]*importScan{}
+	byName := map[string]*importScan{}
+	if archive == nil {
+		return nil, byKey, byName
+	}
+
+	var all []*importScan
+	for _, f := range archive.File {
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.Contains(f.Name, "__MACOSX/") {
+			continue
+		}
+		s := &importScan{file: f, name: base}
+		all = append(all, s)
+		byName[strings.ToLower(base)] = s
+
+		// <ident>_<type, possibly with underscores>[_<label>].<ext>; the
+		// longest run of parts that names a type wins
+		parts := strings.Split(strings.TrimSuffix(base, path.Ext(base)), "_")
+		for n := len(parts) - 1; n >= 1; n-- {
+			docType, ok := types[normHeader(strings.Join(parts[1:1+n], ""))]
+			if !ok || parts[0] == "" {
+				continue
+			}
+			if label := strings.Join(parts[1+n:], " "); label != "" {
+				s.label = strings.ToUpper(label[:1]) + label[1:]
+			}
+			key := scanKey(parts[0], docType)
+			byKey[key] = append(byKey[key], s)
+			break
+		}
+	}
+
+	// Unlabelled and front first, back last
+	rank := func(s *importScan) int {
+		switch strings.ToLower(s.label) {
+		case "":
+			return 0
+		case "front":
+			return 1
+		case "back":
+			return 3
+		}
+		return 2
+	}
+	for _, scans := range byKey {
+		sort.SliceStable(scans, func(i, j int) bool {
+			if rank(scans[i]) != rank(scans[j]) {
+				return rank(scans[i]) < rank(scans[j])
+			}
+			return scans[i].name < scans[j].name
+		})
+	}
+	return all, byKey, byName
+}
+
+// checkScan rejects scans that are too large or not a PDF or image.
+func checkScan(s *importScan, limit int64) error {
+	if s.file.UncompressedSize64 > uint64(limit) {
+		return fmt.Errorf("%s is larger than %dMB", s.name, limit>>20)
+	}
+	rc, err := s.file.Open()
+	if err != nil {
+		return fmt.Errorf("%s cannot be read from the ZIP", s.name)
+	}
+	defer rc.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(rc, head)
+	if !allowedTypes[http.DetectContentType(head[:n])] {
+		return fmt.Errorf("%s is not a PDF, JPG or PNG", s.name)
+	}
+	return nil
+}
+
+// storeScan stores a scan from the ZIP the way Upload stores an upload:
+// checked, scanned for malware and content-addressed.
+func (h *DocumentHandler) storeScan(ctx context.Context, s *importScan) (*models.AddDocumentFileRequest, error) {
+	limit := h.uploads.sizeLimit("documents")
+	rc, err := s.file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s cannot be read from the ZIP", s.name)
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s cannot be read from the ZIP", s.name)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %dMB", s.name, limit>>20)
+	}
+
+	info, uerr := h.uploads.ingest(ctx, bytes.NewReader(data), s.name, "documents")
+	if uerr != nil {
+		return nil, fmt.Errorf("%s: %s", s.name, uerr.message)
+	}
+	f := &models.AddDocumentFileRequest{
+		FileURL:  info.Key,
+		FileName: info.FileName,
+		FileSize: info.FileSize,
+		FileType: info.FileType,
+	}
+	if s.label != "" {
+		label := s.label
+		if len(label) > 50 {
+			label = label[:50]
+		}
+		f.Label = &label
+	}
+	return f, nil
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -17,16 +18,20 @@ import (
 	"manpower-backend/internal/ctxkeys"
 	"manpower-backend/internal/database"
 	"manpower-backend/internal/models"
+	"manpower-backend/internal/storage"
 )
 
 // EmployeeHandler handles employee-related HTTP requests.
+// photo_url holds a storage key; responses carry a short-lived signed URL.
 type EmployeeHandler struct {
-	db database.Service
+	db    database.Service
+	files fileLinks
 }
 
-// NewEmployeeHandler creates a new EmployeeHandler.
-func NewEmployeeHandler(db database.Service) *EmployeeHandler {
-	return &EmployeeHandler{db: db}
+// NewEmployeeHandler creates a new EmployeeHandler. Signed photo URLs are
+// valid for urlTTL.
+func NewEmployeeHandler(db database.Service, store storage.Store, urlTTL time.Duration) *EmployeeHandler {
+	return &EmployeeHandler{db: db, files: fileLinks{store: store, ttl: urlTTL}}
 }
 
 // ── Columns ────────────────────────────────────────────────────
@@ -114,6 +119,11 @@ func (h *EmployeeHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	pool := h.db.GetPool()
 
+	if err := h.files.checkAttachable(ctx, pool, req.PhotoURL); err != nil {
+		writeAttachError(w, err)
+		return
+	}
+
 	// Use a transaction: insert employee + mandatory doc slots
 	tx, err := pool.Begin(ctx)
 	if err != nil {
@@ -124,78 +134,15 @@ func (h *EmployeeHandler) Create(w http.ResponseWriter, r *http.Request) {
 	defer tx.Rollback(ctx)
 
 	// 1. Insert the employee
-	var employee models.Employee
-	err = tx.QueryRow(ctx, `
-		INSERT INTO employees (
-			company_id, name, trade, mobile, joining_date, photo_url,
-			gender, date_of_birth, nationality, passport_number,
-			native_location, current_location, salary, status
-		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
-		RETURNING `+employeeRetCols,
-		req.CompanyID, req.Name, req.Trade, req.Mobile, req.JoiningDate,
-		nilIfEmpty(req.PhotoURL),
-		req.Gender, req.DateOfBirth, req.Nationality, req.PassportNumber,
-		req.NativeLocation, req.CurrentLocation, req.Salary, req.Status,
-	).Scan(
-		&employee.ID, &employee.CompanyID, &employee.Name,
-		&employee.Trade, &employee.Mobile, &employee.JoiningDate,
-		&employee.PhotoURL,
-		&employee.Gender, &employee.DateOfBirth, &employee.Nationality, &employee.PassportNumber,
-		&employee.NativeLocation, &employee.CurrentLocation, &employee.Salary, &employee.Status,
-		&employee.ExitType, &employee.ExitDate, &employee.ExitNotes,
-		&employee.CreatedAt, &employee.UpdatedAt,
-	)
+	employee, err := insertEmployee(ctx, tx, &req, nilIfEmpty(h.files.key(req.PhotoURL)))
 	if err != nil {
 		log.Printf("Error creating employee: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Failed to create employee")
 		return
 	}
 
-	// 2. Auto-create mandatory document slots from DB (falls back to hardcoded if DB empty)
-	mandatoryRows, mandErr := tx.Query(ctx, `
-		SELECT dt.doc_type
-		FROM document_types dt
-		LEFT JOIN compliance_rules cr ON cr.doc_type = dt.doc_type AND cr.company_id = $1
-		WHERE dt.is_active = TRUE
-		  AND COALESCE(cr.is_mandatory, dt.is_mandatory) = TRUE
-		ORDER BY dt.sort_order
-	`, req.CompanyID)
-
-	var mandatoryDocTypes []string
-
-	if mandErr == nil {
-		defer mandatoryRows.Close()
-		for mandatoryRows.Next() {
-			var docType string
-			if err := mandatoryRows.Scan(&docType); err != nil {
-				log.Printf("Error scanning mandatory doc type: %v", err)
-				continue
-			}
-			mandatoryDocTypes = append(mandatoryDocTypes, docType)
-		}
-	}
-
-	// Fallback: if DB tables are empty or query failed, use hardcoded defaults
-	if len(mandatoryDocTypes) == 0 {
-		for _, md := range compliance.MandatoryDocs {
-			mandatoryDocTypes = append(mandatoryDocTypes, md.DocType)
-		}
-	}
-
-	for _, docType := range mandatoryDocTypes {
-		_, err := tx.Exec(ctx, `
-			INSERT INTO documents (
-				employee_id, document_type,
-				file_url, file_name, file_size, file_type
-			)
-			VALUES ($1, $2, '', '', 0, '')
-		`, employee.ID, docType)
-		if err != nil {
-			log.Printf("Error creating mandatory doc slot %s for employee %s: %v",
-				docType, employee.ID, err)
-		}
-	}
+	// 2. Auto-create mandatory document slots
+	createDocSlots(ctx, tx, employee.ID, mandatoryDocTypes(ctx, tx, req.CompanyID))
 
 	if err := tx.Commit(ctx); err != nil {
 		log.Printf("Error committing employee creation: %v", err)
@@ -205,10 +152,11 @@ func (h *EmployeeHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "created", "employee", employee.ID, map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "created", "employee", employee.ID, map[string]interface{}{
 		"name": employee.Name, "trade": employee.Trade,
 	})
 
+	h.files.signPhotos(ctx, pool, employee)
 	JSON(w, http.StatusCreated, map[string]interface{}{
 		"data":    employee,
 		"message": "Employee created successfully",
@@ -231,99 +179,14 @@ func (h *EmployeeHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 	offset := (page - 1) * limit
 
-	companyID := q.Get("company_id")
-	trade := q.Get("trade")
-	search := q.Get("search")
-	docStatus := q.Get("status")     // document status filter
-	empStatus := q.Get("emp_status") // employee active/inactive filter
-	nationality := q.Get("nationality")
-	sortBy := q.Get("sort_by")
-	sortOrder := q.Get("sort_order")
-
-	// Whitelist allowed sort columns
-	allowedSorts := map[string]string{
-		"name":         "e.name",
-		"joining_date": "e.joining_date",
-		"created_at":   "e.created_at",
-		"salary":       "e.salary",
-	}
-	sortCol, ok := allowedSorts[sortBy]
-	if !ok {
-		sortCol = "e.name"
-	}
-	if sortOrder != "desc" {
-		sortOrder = "asc"
-	}
+	orderBy := employeeListOrder(q)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
 	pool := h.db.GetPool()
 
-	// Build dynamic WHERE clause
-	where := "WHERE 1=1"
-	args := []interface{}{}
-	argIdx := 1
-
-	// Company scope (role-based)
-	where, args, argIdx = appendCompanyScope(ctx, where, args, argIdx, "e.company_id")
-
-	if companyID != "" {
-		where += fmt.Sprintf(" AND e.company_id = $%d", argIdx)
-		args = append(args, companyID)
-		argIdx++
-	}
-	if trade != "" {
-		where += fmt.Sprintf(" AND e.trade = $%d", argIdx)
-		args = append(args, trade)
-		argIdx++
-	}
-	if search != "" {
-		where += fmt.Sprintf(" AND e.name ILIKE $%d", argIdx)
-		args = append(args, "%"+search+"%")
-		argIdx++
-	}
-	// Exclude exited employees by default; "all" means include exited
-	if empStatus != "all" {
-		where += " AND e.exit_type IS NULL"
-	}
-	if empStatus != "" && empStatus != "all" {
-		where += fmt.Sprintf(" AND e.status = $%d", argIdx)
-		args = append(args, empStatus)
-		argIdx++
-	}
-	if nationality != "" {
-		where += fmt.Sprintf(" AND e.nationality ILIKE $%d", argIdx)
-		args = append(args, "%"+nationality+"%")
-		argIdx++
-	}
-
-	// Doc status filter: either by aggregate compliance_status (LATERAL) or by "has at least one" doc (EXISTS).
-	// "valid"/"active" uses EXISTS so we show employees who have at least one valid mandatory document (same
-	// definition as dashboard Active Documents), not only those with all docs valid — so the app is usable
-	// before every doc is complete.
-	const existsValidDoc = ` AND EXISTS (
-		SELECT 1 FROM documents d2
-		LEFT JOIN document_types dt2 ON dt2.doc_type = d2.document_type AND dt2.is_active = TRUE
-		LEFT JOIN compliance_rules cr2 ON cr2.doc_type = d2.document_type AND cr2.company_id = e.company_id
-		WHERE d2.employee_id = e.id
-		  AND COALESCE(cr2.is_mandatory, dt2.is_mandatory) = TRUE
-		  AND d2.expiry_date IS NOT NULL
-		  AND d2.expiry_date > CURRENT_DATE + INTERVAL '30 days'
-	)`
-	var statusFilter string
-	switch docStatus {
-	case "expiring", "expiring_soon":
-		statusFilter = " AND ds.compliance_status = 'expiring_soon'"
-	case "expired", "penalty_active":
-		statusFilter = " AND ds.compliance_status = 'penalty_active'"
-	case "in_grace":
-		statusFilter = " AND ds.compliance_status = 'in_grace'"
-	case "valid", "active":
-		statusFilter = existsValidDoc
-	case "incomplete":
-		statusFilter = " AND ds.compliance_status = 'incomplete'"
-	}
+	where, args, argIdx := employeeListFilter(ctx, q)
 
 	// Count total for pagination
 	// Per-document status hierarchy (severity-first):
@@ -351,8 +214,8 @@ func (h *EmployeeHandler) List(w http.ResponseWriter, r *http.Request) {
 			LEFT JOIN compliance_rules gr2 ON gr2.doc_type = d2.document_type AND gr2.company_id IS NULL
 			WHERE d2.employee_id = e.id AND COALESCE(cr2.is_mandatory, dt2.is_mandatory) = TRUE
 		) ds ON TRUE
-		%s %s
-	`, where, statusFilter)
+		%s
+	`, where)
 	var total int
 	if err := pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
 		log.Printf("Error counting employees: %v", err)
@@ -375,7 +238,51 @@ func (h *EmployeeHandler) List(w http.ResponseWriter, r *http.Request) {
 			ds.expiring_count
 		FROM employees e
 		JOIN companies c ON e.company_id = c.id
-		LEFT JOIN LATERAL (
+		`+employeeComplianceJoin+`
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, employeeCols, where, orderBy, argIdx, argIdx+1)
+
+	args = append(args, limit, offset)
+
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		log.Printf("Error querying employees: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch employees")
+		return
+	}
+	defer rows.Close()
+
+	employees := []models.EmployeeWithCompany{}
+	for rows.Next() {
+		var emp models.EmployeeWithCompany
+		if err := scanEmployeeWithCompany(rows, &emp); err != nil {
+			log.Printf("Error scanning employee: %v", err)
+			continue
+		}
+		employees = append(employees, emp)
+	}
+	photos := make([]*models.Employee, len(employees))
+	for i := range employees {
+		photos[i] = &employees[i].Employee
+	}
+	h.files.signPhotos(ctx, pool, photos...)
+
+	JSON(w, http.StatusOK, PaginatedResponse{
+		Data: employees,
+		Pagination: PaginationMeta{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
+
+// employeeComplianceJoin aggregates the compliance of each employee's
+// mandatory documents into ds (see List for the status hierarchy).
+const employeeComplianceJoin = `LEFT JOIN LATERAL (
 			SELECT
 				CASE
 					WHEN COUNT(*) = 0 THEN 'none'
@@ -402,41 +309,109 @@ func (h *EmployeeHandler) List(w http.ResponseWriter, r *http.Request) {
 			LEFT JOIN compliance_rules cr2 ON cr2.doc_type = d2.document_type AND cr2.company_id = e.company_id
 			LEFT JOIN compliance_rules gr2 ON gr2.doc_type = d2.document_type AND gr2.company_id IS NULL
 			WHERE d2.employee_id = e.id AND COALESCE(cr2.is_mandatory, dt2.is_mandatory) = TRUE
-		) ds ON TRUE
-		%s %s
-		ORDER BY %s %s
-		LIMIT $%d OFFSET $%d
-	`, employeeCols, where, statusFilter, sortCol, sortOrder, argIdx, argIdx+1)
+		) ds ON TRUE`
+
+// employeeListFilter builds the WHERE clause of List and Export from the
+// query: company_id, trade, search, status (document compliance),
+// emp_status and nationality, within the caller's company scope. The
+// status filter refers to ds, so the query must join employeeComplianceJoin
+// (or, for a count, its compliance_status).
+func employeeListFilter(ctx context.Context, q url.Values) (string, []interface{}, int) {
+	companyID := q.Get("company_id")
+	trade := q.Get("trade")
+	search := q.Get("search")
+	docStatus := q.Get("status")     // document status filter
+	empStatus := q.Get("emp_status") // employee active/inactive filter
+	nationality := q.Get("nationality")
 
-	args = append(args, limit, offset)
+	// Build dynamic WHERE clause
+	where := "WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
 
-	rows, err := pool.Query(ctx, query, args...)
-	if err != nil {
-		log.Printf("Error querying employees: %v", err)
-		JSONError(w, http.StatusInternalServerError, "Failed to fetch employees")
-		return
+	// Company scope (role-based)
+	where, args, argIdx = appendCompanyScope(ctx, where, args, argIdx, "e.company_id")
+
+	if companyID != "" {
+		where += fmt.Sprintf(" AND e.company_id = $%d", argIdx)
+		args = append(args, companyID)
+		argIdx++
+	}
+	if trade != "" {
+		where += fmt.Sprintf(" AND e.trade = $%d", argIdx)
+		args = append(args, trade)
+		argIdx++
+	}
+	if search != "" {
+		where += fmt.Sprintf(" AND e.name ILIKE $%d", argIdx)
+		args = append(args, "%"+search+"%")
+		argIdx++
+	}
+	// Exclude exited employees by default; "all" means include exited
+	if empStatus != "all" {
+		where += " AND e.exit_type IS NULL"
+	}
+	if empStatus != "" && empStatus != "all" {
+		where += fmt.Sprintf(" AND e.status = $%d", argIdx)
+		args = append(args, empStatus)
+		argIdx++
+	}
+	if nationality != "" {
+		where += fmt.Sprintf(" AND e.nationality ILIKE $%d", argIdx)
+		args = append(args, "%"+nationality+"%")
+		argIdx++
 	}
-	defer rows.Close()
 
-	employees := []models.EmployeeWithCompany{}
-	for rows.Next() {
-		var emp models.EmployeeWithCompany
-		if err := scanEmployeeWithCompany(rows, &emp); err != nil {
-			log.Printf("Error scanning employee: %v", err)
-			continue
-		}
-		employees = append(employees, emp)
+	// Doc status filter: either by aggregate compliance_status (LATERAL) or by "has at least one" doc (EXISTS).
+	// "valid"/"active" uses EXISTS so we show employees who have at least one valid mandatory document (same
+	// definition as dashboard Active Documents), not only those with all docs valid — so the app is usable
+	// before every doc is complete.
+	const existsValidDoc = ` AND EXISTS (
+		SELECT 1 FROM documents d2
+		LEFT JOIN document_types dt2 ON dt2.doc_type = d2.document_type AND dt2.is_active = TRUE
+		LEFT JOIN compliance_rules cr2 ON cr2.doc_type = d2.document_type AND cr2.company_id = e.company_id
+		WHERE d2.employee_id = e.id
+		  AND COALESCE(cr2.is_mandatory, dt2.is_mandatory) = TRUE
+		  AND d2.expiry_date IS NOT NULL
+		  AND d2.expiry_date > CURRENT_DATE + INTERVAL '30 days'
+	)`
+	var statusFilter string
+	switch docStatus {
+	case "expiring", "expiring_soon":
+		statusFilter = " AND ds.compliance_status = 'expiring_soon'"
+	case "expired", "penalty_active":
+		statusFilter = " AND ds.compliance_status = 'penalty_active'"
+	case "in_grace":
+		statusFilter = " AND ds.compliance_status = 'in_grace'"
+	case "valid", "active":
+		statusFilter = existsValidDoc
+	case "incomplete":
+		statusFilter = " AND ds.compliance_status = 'incomplete'"
 	}
 
-	JSON(w, http.StatusOK, PaginatedResponse{
-		Data: employees,
-		Pagination: PaginationMeta{
-			Page:       page,
-			Limit:      limit,
-			Total:      total,
-			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
-		},
-	})
+	return where + statusFilter, args, argIdx
+}
+
+// employeeListOrder reads sort_by and sort_order into an ORDER BY list.
+func employeeListOrder(q url.Values) string {
+	sortBy := q.Get("sort_by")
+	sortOrder := q.Get("sort_order")
+
+	// Whitelist allowed sort columns
+	allowedSorts := map[string]string{
+		"name":         "e.name",
+		"joining_date": "e.joining_date",
+		"created_at":   "e.created_at",
+		"salary":       "e.salary",
+	}
+	sortCol, ok := allowedSorts[sortBy]
+	if !ok {
+		sortCol = "e.name"
+	}
+	if sortOrder != "desc" {
+		sortOrder = "asc"
+	}
+	return sortCol + " " + sortOrder
 }
 
 // ── GetByID ────────────────────────────────────────────────────
@@ -522,6 +497,7 @@ func (h *EmployeeHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		JSONError(w, http.StatusForbidden, "Access denied to this employee")
 		return
 	}
+	h.files.signPhotos(ctx, pool, &emp.Employee)
 
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"data": emp,
@@ -598,10 +574,11 @@ func (h *EmployeeHandler) Exit(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "exited", "employee", employee.ID, map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "exited", "employee", employee.ID, map[string]interface{}{
 		"name": employee.Name, "exitType": req.ExitType,
 	})
 
+	h.files.signPhotos(ctx, pool, &employee)
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"data":    employee,
 		"message": "Employee exit recorded successfully",
@@ -634,6 +611,32 @@ func (h *EmployeeHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	pool := h.db.GetPool()
 
+	// Moving to another company is a transfer, recorded in the employment
+	// history; the current company may be sent back unchanged.
+	if req.CompanyID != nil {
+		var current string
+		if err := pool.QueryRow(ctx, `SELECT company_id::text FROM employees WHERE id = $1`, id).Scan(&current); err != nil {
+			JSONError(w, http.StatusNotFound, "Employee not found")
+			return
+		}
+		if *req.CompanyID != current {
+			JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"error": "Validation failed",
+				"details": map[string]string{
+					"companyId": "Use POST /api/employees/{id}/transfer to move an employee to another company",
+				},
+			})
+			return
+		}
+	}
+
+	if req.PhotoURL != nil {
+		if err := h.files.checkAttachable(ctx, pool, *req.PhotoURL); err != nil {
+			writeAttachError(w, err)
+			return
+		}
+	}
+
 	// Build dynamic SET clause — only update provided fields
 	setClauses := []string{}
 	args := []interface{}{}
@@ -651,9 +654,6 @@ func (h *EmployeeHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Trade != nil {
 		addField("trade", *req.Trade)
 	}
-	if req.CompanyID != nil {
-		addField("company_id", *req.CompanyID)
-	}
 	if req.Mobile != nil {
 		addField("mobile", *req.Mobile)
 	}
@@ -661,7 +661,7 @@ func (h *EmployeeHandler) Update(w http.ResponseWriter, r *http.Request) {
 		addField("joining_date", *req.JoiningDate)
 	}
 	if req.PhotoURL != nil {
-		addField("photo_url", *req.PhotoURL)
+		addField("photo_url", h.files.key(*req.PhotoURL))
 	}
 	if req.Gender != nil {
 		addField("gender", *req.Gender)
@@ -703,19 +703,60 @@ func (h *EmployeeHandler) Update(w http.ResponseWriter, r *http.Request) {
 	`, strings.Join(setClauses, ", "), argIdx, employeeRetCols)
 	args = append(args, id)
 
-	var employee models.Employee
-	if err := scanEmployee(pool.QueryRow(ctx, query, args...), &employee); err != nil {
+	// Read the row before and after in one transaction, so the change set
+	// records exactly what this update changed
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to update employee")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var before, employee models.Employee
+	if err := scanEmployee(tx.QueryRow(ctx, `SELECT `+employeeRetCols+` FROM employees WHERE id = $1 FOR UPDATE`, id), &before); err != nil {
+		JSONError(w, http.StatusNotFound, "Employee not found")
+		return
+	}
+	if err := scanEmployee(tx.QueryRow(ctx, query, args...), &employee); err != nil {
 		log.Printf("Error updating employee %s: %v", id, err)
 		JSONError(w, http.StatusNotFound, "Employee not found")
 		return
 	}
 
-	// Audit trail
+	// The first employment period starts on the joining date
+	if employee.JoiningDate != before.JoiningDate {
+		if _, err := tx.Exec(ctx, `
+			UPDATE employment_history SET start_date = $2
+			WHERE id = (SELECT id FROM employment_history WHERE employee_id = $1 ORDER BY start_date LIMIT 1)
+			  AND (end_date IS NULL OR end_date > $2)
+		`, id, employee.JoiningDate); err != nil {
+			log.Printf("Error moving start of employment of %s: %v", id, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to update employee")
+			return
+		}
+	}
+
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "updated", "employee", employee.ID, map[string]interface{}{
-		"name": employee.Name,
+	changes := diffFields(employeeFields(&before), employeeFields(&employee))
+	if err := recordChanges(ctx, tx, userID, "updated", "employee", id, changes); err != nil {
+		log.Printf("Error recording changes of employee %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to update employee")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing employee update: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to update employee")
+		return
+	}
+
+	// Audit trail
+	logActivity(r.Context(), pool, userID, "updated", "employee", employee.ID, map[string]interface{}{
+		"name": employee.Name, "fields": changedFields(changes),
 	})
 
+	h.files.signPhotos(ctx, pool, &employee)
 	JSON(w, http.StatusOK, map[string]interface{}{
 		"data":    employee,
 		"message": "Employee updated successfully",
@@ -756,7 +797,7 @@ func (h *EmployeeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "deleted", "employee", id, nil)
+	logActivity(r.Context(), pool, userID, "deleted", "employee", id, nil)
 
 	JSON(w, http.StatusOK, map[string]string{
 		"message": "Employee deleted successfully",
@@ -802,7 +843,7 @@ func (h *EmployeeHandler) BatchDelete(w http.ResponseWriter, r *http.Request) {
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
 	for _, id := range req.IDs {
-		logActivity(pool, userID, "deleted", "employee", id, nil)
+		logActivity(r.Context(), pool, userID, "deleted", "employee", id, nil)
 	}
 
 	JSON(w, http.StatusOK, map[string]interface{}{
@@ -813,30 +854,76 @@ func (h *EmployeeHandler) BatchDelete(w http.ResponseWriter, r *http.Request) {
 
 // ── Export ──────────────────────────────────────────────────────
 
-// Export handles GET /api/employees/export — returns CSV
+// employeeExportColumns are the columns Export can include, in the order
+// the export dialog lists them. expiry.<doc_type> and number.<doc_type>
+// add the employee's latest document of that type (docTypeColumns).
+var employeeExportColumns = []exportColumn{
+	{key: "name", header: "Name", expr: "e.name"},
+	{key: "trade", header: "Trade", expr: "e.trade"},
+	{key: "mobile", header: "Mobile", expr: "e.mobile"},
+	{key: "joiningDate", header: "Joining Date", expr: "e.joining_date", kind: exportDate},
+	{key: "gender", header: "Gender", expr: "e.gender"},
+	{key: "dateOfBirth", header: "Date of Birth", expr: "e.date_of_birth", kind: exportDate},
+	{key: "nationality", header: "Nationality", expr: "e.nationality"},
+	{key: "passportNumber", header: "Passport", expr: "e.passport_number"},
+	{key: "nativeLocation", header: "Native Location", expr: "e.native_location"},
+	{key: "currentLocation", header: "Current Location", expr: "e.current_location"},
+	{key: "salary", header: "Salary", expr: "e.salary", kind: exportMoney},
+	{key: "currency", header: "Currency", expr: "COALESCE(c.currency, 'AED')"},
+	{key: "status", header: "Status", expr: "e.status"},
+	{key: "company", header: "Company", expr: "c.name"},
+	{key: "exitType", header: "Exit Type", expr: "e.exit_type"},
+	{key: "exitDate", header: "Exit Date", expr: "e.exit_date", kind: exportDate},
+	// Compliance, as in List
+	{key: "complianceStatus", header: "Compliance Status", expr: "ds.compliance_status"},
+	{key: "docsComplete", header: "Documents Complete", expr: "ds.docs_complete", kind: exportInt},
+	{key: "docsTotal", header: "Mandatory Documents", expr: "ds.docs_total", kind: exportInt},
+	{key: "nearestExpiryDays", header: "Days to Nearest Expiry", expr: "ds.nearest_expiry_days", kind: exportInt},
+	{key: "urgentDocType", header: "Most Urgent Document", expr: "ds.urgent_doc_type"},
+	{key: "expiredCount", header: "Expired Documents", expr: "ds.expired_count", kind: exportInt},
+	{key: "expiringCount", header: "Expiring Documents", expr: "ds.expiring_count", kind: exportInt},
+}
+
+// employeeExportDefaults are the columns exported when none are chosen.
+var employeeExportDefaults = []string{
+	"name", "trade", "mobile", "joiningDate", "gender", "nationality", "passportNumber",
+	"nativeLocation", "currentLocation", "salary", "status", "company",
+}
+
+// Export handles GET /api/employees/export — every employee List would
+// return (same filters and sort, no paging) as CSV, or with format=xlsx as
+// a workbook with date and number cells. columns picks the columns, e.g.
+// columns=name,passportNumber,complianceStatus,expiry.visa.
 func (h *EmployeeHandler) Export(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	format, ok := exportFormat(r)
+	if !ok {
+		JSONError(w, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 	defer cancel()
 
 	pool := h.db.GetPool()
 
-	where := "WHERE 1=1"
-	exportArgs := []interface{}{}
-	exportArgIdx := 1
-	where, exportArgs, exportArgIdx = appendCompanyScope(ctx, where, exportArgs, exportArgIdx, "e.company_id")
-	_ = exportArgIdx
+	q := r.URL.Query()
+	cols, err := pickExportColumns(q.Get("columns"), employeeExportColumns, employeeExportDefaults,
+		docTypeColumns(ctx, pool, "e.id"))
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	where, args, argIdx := employeeListFilter(ctx, q)
+	selectList, args := exportSelect(cols, args, argIdx)
 	rows, err := pool.Query(ctx, fmt.Sprintf(`
-		SELECT e.name, e.trade, e.mobile, e.joining_date::text,
-			COALESCE(e.gender,''), COALESCE(e.nationality,''),
-			COALESCE(e.passport_number,''), COALESCE(e.native_location,''),
-			COALESCE(e.current_location,''), COALESCE(e.salary::text,''),
-			e.status, c.name
+		SELECT %s
 		FROM employees e
 		JOIN companies c ON e.company_id = c.id
 		%s
-		ORDER BY e.name ASC
-	`, where), exportArgs...)
+		%s
+		ORDER BY %s, e.id
+	`, selectList, employeeComplianceJoin, where, employeeListOrder(q)), args...)
 	if err != nil {
 		log.Printf("Error exporting employees: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Failed to export")
@@ -844,25 +931,89 @@ func (h *EmployeeHandler) Export(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", "attachment; filename=employees.csv")
+	writeExport(w, rows, cols, format, "employees")
+}
 
-	// Write CSV header
-	fmt.Fprintln(w, "Name,Trade,Mobile,Joining Date,Gender,Nationality,Passport,Native Location,Current Location,Salary,Status,Company")
+// ── Helpers ────────────────────────────────────────────────────
 
-	for rows.Next() {
-		var name, trade, mobile, joiningDate, gender, nationality, passport, nativeLoc, currentLoc, salary, status, company string
-		if err := rows.Scan(&name, &trade, &mobile, &joiningDate, &gender, &nationality, &passport, &nativeLoc, &currentLoc, &salary, &status, &company); err != nil {
-			continue
+// insertEmployee inserts the employee described by req, with their first
+// employment period starting on the joining date; Status must be set.
+func insertEmployee(ctx context.Context, q dbtx, req *models.CreateEmployeeRequest, photoKey *string) (*models.Employee, error) {
+	var employee models.Employee
+	err := scanEmployee(q.QueryRow(ctx, `
+		INSERT INTO employees (
+			company_id, name, trade, mobile, joining_date, photo_url,
+			gender, date_of_birth, nationality, passport_number,
+			native_location, current_location, salary, status
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		RETURNING `+employeeRetCols,
+		req.CompanyID, req.Name, req.Trade, req.Mobile, req.JoiningDate, photoKey,
+		req.Gender, req.DateOfBirth, req.Nationality, req.PassportNumber,
+		req.NativeLocation, req.CurrentLocation, req.Salary, req.Status,
+	), &employee)
+	if err != nil {
+		return nil, err
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO employment_history (employee_id, company_id, start_date)
+		VALUES ($1, $2, $3)
+	`, employee.ID, employee.CompanyID, employee.JoiningDate)
+	return &employee, err
+}
+
+// mandatoryDocTypes returns the document types that are mandatory for
+// employees of companyID, from the DB (falls back to hardcoded if DB empty).
+func mandatoryDocTypes(ctx context.Context, q dbtx, companyID string) []string {
+	mandatoryRows, mandErr := q.Query(ctx, `
+		SELECT dt.doc_type
+		FROM document_types dt
+		LEFT JOIN compliance_rules cr ON cr.doc_type = dt.doc_type AND cr.company_id = $1
+		WHERE dt.is_active = TRUE
+		  AND COALESCE(cr.is_mandatory, dt.is_mandatory) = TRUE
+		ORDER BY dt.sort_order
+	`, companyID)
+
+	var mandatoryDocTypes []string
+
+	if mandErr == nil {
+		defer mandatoryRows.Close()
+		for mandatoryRows.Next() {
+			var docType string
+			if err := mandatoryRows.Scan(&docType); err != nil {
+				log.Printf("Error scanning mandatory doc type: %v", err)
+				continue
+			}
+			mandatoryDocTypes = append(mandatoryDocTypes, docType)
+		}
+	}
+
+	// Fallback: if DB tables are empty or query failed, use hardcoded defaults
+	if len(mandatoryDocTypes) == 0 {
+		for _, md := range compliance.MandatoryDocs {
+			mandatoryDocTypes = append(mandatoryDocTypes, md.DocType)
 		}
-		fmt.Fprintf(w, "%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s\n",
-			csvEscape(name), csvEscape(trade), csvEscape(mobile), joiningDate,
-			gender, nationality, passport,
-			csvEscape(nativeLoc), csvEscape(currentLoc), salary, status, csvEscape(company))
 	}
+	return mandatoryDocTypes
 }
 
-// ── Helpers ────────────────────────────────────────────────────
+// createDocSlots inserts an empty document of each type for a new
+// employee, so that compliance posture is immediately visible.
+func createDocSlots(ctx context.Context, q dbtx, employeeID string, docTypes []string) {
+	for _, docType := range docTypes {
+		_, err := q.Exec(ctx, `
+			INSERT INTO documents (
+				employee_id, document_type,
+				file_url, file_name, file_size, file_type
+			)
+			VALUES ($1, $2, '', '', 0, '')
+		`, employeeID, docType)
+		if err != nil {
+			log.Printf("Error creating mandatory doc slot %s for employee %s: %v",
+				docType, employeeID, err)
+		}
+	}
+}
 
 // nilIfEmpty returns nil if the string is empty, otherwise returns a pointer to it.
 func nilIfEmpty(s string) *string {
@@ -871,11 +1022,3 @@ func nilIfEmpty(s string) *string {
 	}
 	return &s
 }
-
-// csvEscape wraps a value in quotes if it contains commas.
-func csvEscape(s string) string {
-	if strings.Contains(s, ",") || strings.Contains(s, "\"") {
-		return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
-	}
-	return s
-}
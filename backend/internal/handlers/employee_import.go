@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/models"
+)
+
+// ── Import ─────────────────────────────────────────────────────
+
+// employeeImportFields are the columns an employee sheet can have. The
+// headers of Export are recognised, so an export can be edited and
+// imported back.
+var employeeImportFields = []importField{
+	{"name", []string{"name", "fullname", "employeename", "workername"}},
+	{"trade", []string{"trade", "designation", "jobtitle", "profession"}},
+	{"mobile", []string{"mobile", "mobileno", "mobilenumber", "phone", "phonenumber", "contactnumber"}},
+	{"joiningDate", []string{"joiningdate", "dateofjoining", "doj", "joindate", "startdate"}},
+	{"company", []string{"company", "companyname", "companyid", "employer"}},
+	{"gender", []string{"gender", "sex"}},
+	{"dateOfBirth", []string{"dateofbirth", "dob", "birthdate"}},
+	{"nationality", []string{"nationality", "country"}},
+	{"passportNumber", []string{"passport", "passportno", "passportnumber"}},
+	{"nativeLocation", []string{"nativelocation", "hometown"}},
+	{"currentLocation", []string{"currentlocation", "location", "camp", "accommodation"}},
+	{"salary", []string{"salary", "basicsalary", "monthlysalary"}},
+	{"status", []string{"status"}},
+}
+
+// Import handles POST /api/employees/import — multipart "file" (CSV or
+// XLSX) with one employee per row; see parseImportUpload for the mapping,
+// dryRun and skipInvalid fields. "companyId" is used for rows without a
+// Company column (matched by name or ID within the caller's scope).
+//
+// Rows are validated like Create, and checked for passport numbers and
+// mobile numbers that repeat in the file or belong to an existing
+// employee. A dry run only reports. Otherwise the import is one
+// transaction: nothing is saved if any row fails, unless skipInvalid is
+// set, in which case only the valid rows are. Each new employee gets its
+// mandatory document slots and an "imported" audit entry.
+func (h *EmployeeHandler) Import(w http.ResponseWriter, r *http.Request) {
+	u := parseImportUpload(w, r, employeeImportFields, maxUploadSize)
+	if u == nil {
+		return
+	}
+	defaultCompany := r.FormValue("companyId")
+	if defaultCompany != "" && !checkCompanyAccess(r.Context(), defaultCompany) {
+		JSONError(w, http.StatusForbidden, "Access denied to this company")
+		return
+	}
+	var missing []string
+	for _, f := range []string{"name", "trade", "joiningDate"} {
+		if !u.has(f) {
+			missing = append(missing, f)
+		}
+	}
+	if !u.has("company") && defaultCompany == "" {
+		missing = append(missing, "company (or choose a company for every row)")
+	}
+	if len(missing) > 0 {
+		JSONError(w, http.StatusUnprocessableEntity, "No column is mapped to "+strings.Join(missing, ", "))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	companies, err := importCompanies(ctx, pool)
+	if err != nil {
+		log.Printf("Error loading companies for import: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import employees")
+		return
+	}
+
+	report := models.ImportReport{
+		DryRun:  u.dryRun,
+		Columns: u.columns,
+		Fields:  fieldNames(employeeImportFields),
+		Rows:    []models.ImportRow{},
+	}
+	var reqs []models.CreateEmployeeRequest // parallel to report.Rows
+	passports := map[string]int{}           // key → first line
+	mobiles := map[string]int{}
+	for i, row := range u.rows {
+		if blankRow(row) {
+			continue
+		}
+		line := i + 2
+		req, errs := employeeFromRow(u, row, companies, defaultCompany)
+
+		if req.PassportNumber != nil {
+			key := passportKey(*req.PassportNumber)
+			if first, dup := passports[key]; dup {
+				errs["passportNumber"] = fmt.Sprintf("Same passport number as row %d", first)
+			} else {
+				passports[key] = line
+			}
+		}
+		if key := mobileKey(req.Mobile); key != "" {
+			if first, dup := mobiles[key]; dup {
+				errs["mobile"] = fmt.Sprintf("Same mobile number as row %d", first)
+			} else {
+				mobiles[key] = line
+			}
+		}
+
+		report.Rows = append(report.Rows, models.ImportRow{Row: line, Label: req.Name, Status: "valid", Errors: errs})
+		reqs = append(reqs, req)
+	}
+	report.Total = len(report.Rows)
+	if report.Total == 0 {
+		JSONError(w, http.StatusUnprocessableEntity, "The sheet has no data rows")
+		return
+	}
+
+	if err := markExistingEmployees(ctx, pool, reqs, report.Rows); err != nil {
+		log.Printf("Error checking import duplicates: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import employees")
+		return
+	}
+	countImportRows(&report)
+
+	if u.dryRun {
+		JSON(w, http.StatusOK, map[string]interface{}{"data": report})
+		return
+	}
+	if report.Failed > 0 && !u.skipInvalid {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": fmt.Sprintf("%d row(s) have errors; nothing was imported", report.Failed),
+			"data":  report,
+		})
+		return
+	}
+	if report.Valid == 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": "No valid rows to import",
+			"data":  report,
+		})
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import employees")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	slots := map[string][]string{} // company → mandatory doc types
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if len(row.Errors) > 0 {
+			continue
+		}
+		req := &reqs[i]
+		// A savepoint per row, so a failed insert only loses its own row
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			log.Printf("Error starting savepoint: %v", err)
+			JSONError(w, http.StatusInternalServerError, "Failed to import employees")
+			return
+		}
+		employee, err := insertEmployee(ctx, sp, req, nil)
+		if err == nil {
+			if _, ok := slots[req.CompanyID]; !ok {
+				slots[req.CompanyID] = mandatoryDocTypes(ctx, sp, req.CompanyID)
+			}
+			createDocSlots(ctx, sp, employee.ID, slots[req.CompanyID])
+			err = sp.Commit(ctx)
+		}
+		if err != nil {
+			sp.Rollback(ctx)
+			log.Printf("Error importing employee on row %d: %v", row.Row, err)
+			row.Errors = map[string]string{"row": "Could not be saved; check the lengths and formats of its values"}
+			continue
+		}
+		row.Status, row.ID = "created", &employee.ID
+	}
+	countImportRows(&report)
+
+	if report.Failed > 0 && !u.skipInvalid {
+		for i := range report.Rows {
+			if report.Rows[i].Status == "created" {
+				report.Rows[i].Status, report.Rows[i].ID = "valid", nil
+			}
+		}
+		countImportRows(&report)
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": fmt.Sprintf("%d row(s) could not be saved; nothing was imported", report.Failed),
+			"data":  report,
+		})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing employee import: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to import employees")
+		return
+	}
+
+	// Audit trail
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	for i, row := range report.Rows {
+		if row.ID != nil {
+			logActivity(r.Context(), pool, userID, "imported", "employee", *row.ID, map[string]interface{}{
+				"name": reqs[i].Name, "trade": reqs[i].Trade, "file": u.fileName, "row": row.Row,
+			})
+		}
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data":    report,
+		"message": fmt.Sprintf("%d employee(s) imported", report.Created),
+	})
+}
+
+// importCompanies maps the IDs and lowercased names of the companies in
+// the caller's scope to their IDs.
+func importCompanies(ctx context.Context, q dbtx) (map[string]string, error) {
+	where, args, _ := appendCompanyScope(ctx, "WHERE 1=1", nil, 1, "id")
+	rows, err := q.Query(ctx, `SELECT id::text, name FROM companies `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	companies := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		companies[id] = id
+		companies[strings.ToLower(strings.TrimSpace(name))] = id
+	}
+	return companies, rows.Err()
+}
+
+// employeeFromRow builds the create request for a sheet row and validates
+// it. Values are normalised the way the forms would enter them.
+func employeeFromRow(u *importUpload, row []string, companies map[string]string, defaultCompany string) (models.CreateEmployeeRequest, map[string]string) {
+	errs := map[string]string{}
+	optional := func(field string) *string {
+		if v := u.get(row, field); v != "" {
+			return &v
+		}
+		return nil
+	}
+	date := func(field string) string {
+		v := u.get(row, field)
+		if v == "" {
+			return ""
+		}
+		d, ok := importDate(v)
+		if !ok {
+			errs[field] = fmt.Sprintf("%q is not a date; use YYYY-MM-DD or DD/MM/YYYY", v)
+		}
+		return d
+	}
+
+	req := models.CreateEmployeeRequest{
+		CompanyID:       defaultCompany,
+		Name:            u.get(row, "name"),
+		Trade:           u.get(row, "trade"),
+		Mobile:          u.get(row, "mobile"),
+		JoiningDate:     date("joiningDate"),
+		Nationality:     optional("nationality"),
+		NativeLocation:  optional("nativeLocation"),
+		CurrentLocation: optional("currentLocation"),
+	}
+	if v := u.get(row, "company"); v != "" {
+		id, ok := companies[strings.ToLower(v)]
+		if !ok {
+			errs["company"] = fmt.Sprintf("No company %q", v)
+		}
+		req.CompanyID = id
+	}
+	if dob := date("dateOfBirth"); dob != "" {
+		req.DateOfBirth = &dob
+	}
+	if v := u.get(row, "gender"); v != "" {
+		g := map[string]string{"m": "male", "male": "male", "f": "female", "female": "female"}[strings.ToLower(v)]
+		if g == "" {
+			errs["gender"] = "Gender must be male or female"
+		}
+		req.Gender = &g
+	}
+	if v := u.get(row, "passportNumber"); v != "" {
+		p := strings.ToUpper(strings.Join(strings.Fields(v), ""))
+		if len(p) > 30 {
+			errs["passportNumber"] = "Passport number must be at most 30 characters"
+		}
+		req.PassportNumber = &p
+	}
+	if len(req.Mobile) > 20 {
+		errs["mobile"] = "Mobile must be at most 20 characters"
+	}
+	if v := u.get(row, "salary"); v != "" {
+		salary, ok := importNumber(v)
+		if !ok || salary < 0 {
+			errs["salary"] = fmt.Sprintf("%q is not a salary", v)
+		}
+		req.Salary = &salary
+	}
+	req.Status = strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(u.get(row, "status")))
+	switch req.Status {
+	case "":
+		req.Status = "active"
+	case "active", "inactive", "on_leave":
+	default:
+		errs["status"] = "Status must be active, inactive or on_leave; record exits separately"
+	}
+
+	for field, msg := range req.Validate() {
+		if _, ok := errs[field]; !ok {
+			errs[field] = msg
+		}
+	}
+	if msg, ok := errs["companyId"]; ok {
+		delete(errs, "companyId")
+		if _, ok := errs["company"]; !ok {
+			errs["company"] = msg
+		}
+	}
+	return req, errs
+}
+
+// passportKey and mobileKey are the forms compared for duplicates: a
+// passport number without spaces or dashes, and a mobile number's digits
+// without an international "00" prefix (too short to tell apart: none).
+func passportKey(s string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(s))
+}
+
+func mobileKey(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+	digits = strings.TrimPrefix(digits, "00")
+	if len(digits) < 6 {
+		return ""
+	}
+	return digits
+}
+
+// markExistingEmployees flags rows whose passport or mobile number already
+// belongs to an employee, in any company.
+func markExistingEmployees(ctx context.Context, q dbtx, reqs []models.CreateEmployeeRequest, rows []models.ImportRow) error {
+	var passports, mobiles []string
+	for _, req := range reqs {
+		if req.PassportNumber != nil {
+			passports = append(passports, passportKey(*req.PassportNumber))
+		}
+		if key := mobileKey(req.Mobile); key != "" {
+			mobiles = append(mobiles, key)
+		}
+	}
+	if len(passports) == 0 && len(mobiles) == 0 {
+		return nil
+	}
+
+	res, err := q.Query(ctx, `
+		SELECT UPPER(REGEXP_REPLACE(COALESCE(passport_number, ''), '[\s-]', '', 'g')),
+			REGEXP_REPLACE(REGEXP_REPLACE(mobile, '\D', '', 'g'), '^00', '')
+		FROM employees
+		WHERE UPPER(REGEXP_REPLACE(COALESCE(passport_number, ''), '[\s-]', '', 'g')) = ANY($1)
+		   OR REGEXP_REPLACE(REGEXP_REPLACE(mobile, '\D', '', 'g'), '^00', '') = ANY($2)
+	`, passports, mobiles)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	takenPassports, takenMobiles := map[string]bool{}, map[string]bool{}
+	for res.Next() {
+		var passport, mobile string
+		if err := res.Scan(&passport, &mobile); err != nil {
+			return err
+		}
+		takenPassports[passport], takenMobiles[mobile] = true, true
+	}
+	if err := res.Err(); err != nil {
+		return err
+	}
+
+	for i, req := range reqs {
+		if req.PassportNumber != nil && takenPassports[passportKey(*req.PassportNumber)] {
+			rows[i].Errors["passportNumber"] = "An employee with this passport number already exists"
+		}
+		if key := mobileKey(req.Mobile); key != "" && takenMobiles[key] {
+			rows[i].Errors["mobile"] = "An employee with this mobile number already exists"
+		}
+	}
+	return nil
+}
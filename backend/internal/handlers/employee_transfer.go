@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/models"
+)
+
+// ── Transfer ───────────────────────────────────────────────────
+
+// Transfer handles POST /api/employees/{id}/transfer
+// Moves an employee to another company from an effective date on: the
+// current employment period ends on that date and a new one starts, salary
+// records for months ending on or after it are counted for the new company,
+// and the new company's mandatory document slots are added. The response
+// lists the compliance rules that now judge the employee's documents
+// differently.
+func (h *EmployeeHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		JSONError(w, http.StatusBadRequest, "Employee ID is required")
+		return
+	}
+
+	if !checkEmployeeAccess(r.Context(), h.db.GetPool(), id) {
+		JSONError(w, http.StatusForbidden, "Access denied to this employee")
+		return
+	}
+
+	var req models.TransferEmployeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	if !checkCompanyAccess(r.Context(), req.CompanyID) {
+		JSONError(w, http.StatusForbidden, "Access denied to this company")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	invalid := func(field, msg string) {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": map[string]string{field: msg},
+		})
+	}
+
+	var toName string
+	if err := pool.QueryRow(ctx, `SELECT name FROM companies WHERE id::text = $1`, req.CompanyID).Scan(&toName); err != nil {
+		invalid("companyId", "Company not found")
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the employee, so transfers of the same employee run one at a time
+	var fromID, fromName, joiningDate string
+	var exited bool
+	err = tx.QueryRow(ctx, `
+		SELECT e.company_id::text, c.name, e.joining_date::text, e.exit_type IS NOT NULL
+		FROM employees e
+		JOIN companies c ON c.id = e.company_id
+		WHERE e.id = $1
+		FOR UPDATE OF e
+	`, id).Scan(&fromID, &fromName, &joiningDate, &exited)
+	if err != nil {
+		log.Printf("Error fetching employee %s for transfer: %v", id, err)
+		JSONError(w, http.StatusNotFound, "Employee not found")
+		return
+	}
+	if exited {
+		JSONError(w, http.StatusUnprocessableEntity, "Exited employees cannot be transferred")
+		return
+	}
+	if fromID == req.CompanyID {
+		invalid("companyId", "Employee already belongs to this company")
+		return
+	}
+
+	// 2. End the current period on the effective date. Employees without one
+	// get a closed period for the old company, from their last period's end
+	// or joining date.
+	var periodID, periodStart string
+	err = tx.QueryRow(ctx, `
+		SELECT id::text, start_date::text FROM employment_history
+		WHERE employee_id = $1 AND end_date IS NULL
+	`, id).Scan(&periodID, &periodStart)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(MAX(end_date), $2::date)::text FROM employment_history WHERE employee_id = $1
+		`, id, joiningDate).Scan(&periodStart)
+	}
+	if err != nil {
+		log.Printf("Error fetching employment of %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+	if req.EffectiveDate <= periodStart {
+		invalid("effectiveDate", "Effective date must be after "+periodStart+", when the current employment began")
+		return
+	}
+
+	if periodID != "" {
+		_, err = tx.Exec(ctx, `UPDATE employment_history SET end_date = $2 WHERE id = $1`, periodID, req.EffectiveDate)
+	} else {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO employment_history (employee_id, company_id, start_date, end_date)
+			VALUES ($1, $2, $3, $4)
+		`, id, fromID, periodStart, req.EffectiveDate)
+	}
+	if err != nil {
+		log.Printf("Error ending employment of %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+
+	// 3. Start the new period and move the employee
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO employment_history (employee_id, company_id, start_date, reason, transferred_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`, id, req.CompanyID, req.EffectiveDate, req.Reason, nilIfEmpty(userID)); err != nil {
+		log.Printf("Error starting employment of %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+
+	var result models.TransferResult
+	if err := scanEmployee(tx.QueryRow(ctx, `
+		UPDATE employees SET company_id = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+employeeRetCols, id, req.CompanyID), &result.Employee); err != nil {
+		log.Printf("Error transferring employee %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+
+	// 4. Salary from the effective date on is the new company's cost; a
+	// month belongs to the company the employee is with at its end.
+	tag, err := tx.Exec(ctx, `
+		UPDATE salary_records SET company_id = $2, updated_at = NOW()
+		WHERE employee_id = $1
+		  AND (make_date(year, month, 1) + INTERVAL '1 month - 1 day')::date >= $3::date
+	`, id, req.CompanyID, req.EffectiveDate)
+	if err != nil {
+		log.Printf("Error moving salary records of %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+	result.SalaryRecordsMoved = tag.RowsAffected()
+
+	// 5. Documents: the new company's rules, and its mandatory slots
+	result.RuleChanges = transferRuleChanges(ctx, tx, id, fromID, req.CompanyID)
+	result.AddedDocuments = missingDocTypes(ctx, tx, id, mandatoryDocTypes(ctx, tx, req.CompanyID))
+	createDocSlots(ctx, tx, id, result.AddedDocuments)
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing employee transfer: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to transfer employee")
+		return
+	}
+
+	// Audit trail
+	logActivity(r.Context(), pool, userID, "transferred", "employee", id, map[string]interface{}{
+		"name": result.Employee.Name, "from": fromName, "to": toName,
+		"effectiveDate": req.EffectiveDate, "reason": req.Reason,
+	})
+
+	result.History = employmentHistory(ctx, pool, id)
+	h.files.signPhotos(ctx, pool, &result.Employee)
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data":    result,
+		"message": "Employee transferred successfully",
+	})
+}
+
+// ── Employment ─────────────────────────────────────────────────
+
+// Employment handles GET /api/employees/{id}/employment
+// Returns the companies the employee has belonged to, oldest first.
+func (h *EmployeeHandler) Employment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		JSONError(w, http.StatusBadRequest, "Employee ID is required")
+		return
+	}
+
+	if !checkEmployeeAccess(r.Context(), h.db.GetPool(), id) {
+		JSONError(w, http.StatusForbidden, "Access denied to this employee")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data": employmentHistory(ctx, h.db.GetPool(), id),
+	})
+}
+
+// ── Helpers ────────────────────────────────────────────────────
+
+// employmentHistory returns the employee's employment periods, oldest first.
+func employmentHistory(ctx context.Context, q dbtx, employeeID string) []models.EmploymentPeriod {
+	periods := []models.EmploymentPeriod{}
+	rows, err := q.Query(ctx, `
+		SELECT h.id, h.employee_id, h.company_id, c.name,
+			h.start_date::text, h.end_date::text, h.reason,
+			h.transferred_by::text, u.name, h.created_at
+		FROM employment_history h
+		JOIN companies c ON c.id = h.company_id
+		LEFT JOIN users u ON u.id = h.transferred_by
+		WHERE h.employee_id = $1
+		ORDER BY h.start_date ASC
+	`, employeeID)
+	if err != nil {
+		log.Printf("Error fetching employment history of %s: %v", employeeID, err)
+		return periods
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.EmploymentPeriod
+		if err := rows.Scan(&p.ID, &p.EmployeeID, &p.CompanyID, &p.CompanyName,
+			&p.StartDate, &p.EndDate, &p.Reason,
+			&p.TransferredBy, &p.TransferredByName, &p.CreatedAt); err != nil {
+			log.Printf("Error scanning employment period: %v", err)
+			continue
+		}
+		periods = append(periods, p)
+	}
+	return periods
+}
+
+// transferRuleChanges compares the compliance rules that apply to the
+// employee's document types at company from and at company to (each
+// falling back to the global rule and the type's default), and returns the
+// differences.
+func transferRuleChanges(ctx context.Context, q dbtx, employeeID, from, to string) []models.RuleChange {
+	changes := []models.RuleChange{}
+	rows, err := q.Query(ctx, `
+		SELECT t.doc_type,
+			COALESCE(o.grace_period_days, g.grace_period_days, 0)::text,
+			COALESCE(n.grace_period_days, g.grace_period_days, 0)::text,
+			COALESCE(o.fine_per_day, g.fine_per_day, 0)::text,
+			COALESCE(n.fine_per_day, g.fine_per_day, 0)::text,
+			COALESCE(o.fine_type, g.fine_type, 'daily'),
+			COALESCE(n.fine_type, g.fine_type, 'daily'),
+			COALESCE(o.fine_cap, g.fine_cap, 0)::text,
+			COALESCE(n.fine_cap, g.fine_cap, 0)::text,
+			COALESCE(o.is_mandatory, dt.is_mandatory, FALSE)::text,
+			COALESCE(n.is_mandatory, dt.is_mandatory, FALSE)::text
+		FROM (SELECT DISTINCT document_type AS doc_type FROM documents WHERE employee_id = $1) t
+		LEFT JOIN document_types dt ON dt.doc_type = t.doc_type AND dt.is_active = TRUE
+		LEFT JOIN compliance_rules o ON o.doc_type = t.doc_type AND o.company_id = $2
+		LEFT JOIN compliance_rules n ON n.doc_type = t.doc_type AND n.company_id = $3
+		LEFT JOIN compliance_rules g ON g.doc_type = t.doc_type AND g.company_id IS NULL
+		ORDER BY COALESCE(dt.sort_order, 100), t.doc_type
+	`, employeeID, from, to)
+	if err != nil {
+		log.Printf("Error comparing compliance rules for %s: %v", employeeID, err)
+		return changes
+	}
+	defer rows.Close()
+
+	fields := []string{"gracePeriodDays", "finePerDay", "fineType", "fineCap", "isMandatory"}
+	for rows.Next() {
+		var docType string
+		values := make([]string, 2*len(fields))
+		dest := []interface{}{&docType}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			log.Printf("Error scanning compliance rule: %v", err)
+			continue
+		}
+		for i, field := range fields {
+			if old, cur := values[2*i], values[2*i+1]; old != cur {
+				changes = append(changes, models.RuleChange{DocumentType: docType, Field: field, From: old, To: cur})
+			}
+		}
+	}
+	return changes
+}
+
+// missingDocTypes returns the docTypes the employee has no document of.
+func missingDocTypes(ctx context.Context, q dbtx, employeeID string, docTypes []string) []string {
+	have := map[string]bool{}
+	rows, err := q.Query(ctx, `SELECT DISTINCT document_type FROM documents WHERE employee_id = $1`, employeeID)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var docType string
+			if rows.Scan(&docType) == nil {
+				have[docType] = true
+			}
+		}
+	}
+
+	missing := []string{}
+	for _, docType := range docTypes {
+		if !have[docType] {
+			missing = append(missing, docType)
+		}
+	}
+	return missing
+}
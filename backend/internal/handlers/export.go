@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"manpower-backend/internal/compliance"
+	"manpower-backend/internal/xlsx"
+)
+
+// ── Exports ──────────────────────────────────────────────────────
+// Shared by the employee, salary and document exports: choosing columns
+// (?columns=name,passportNumber,expiry.visa), and writing the rows as CSV
+// or as an XLSX workbook with typed cells (?format=xlsx).
+
+// exportKind is the type of an export column's values.
+type exportKind uint8
+
+const (
+	exportText exportKind = iota
+	exportInt
+	exportMoney // two decimals
+	exportDate
+)
+
+// exportColumn is a column an export can include: its key in ?columns, its
+// header and the SQL for its value. A "$?" in expr is bound to arg.
+type exportColumn struct {
+	key    string
+	header string
+	expr   string
+	kind   exportKind
+	arg    interface{}
+}
+
+// exportFormat reads ?format: "csv" (the default) or "xlsx".
+func exportFormat(r *http.Request) (string, bool) {
+	switch f := strings.ToLower(r.URL.Query().Get("format")); f {
+	case "", "csv":
+		return "csv", true
+	case "xlsx":
+		return f, true
+	}
+	return "", false
+}
+
+// pickExportColumns resolves ?columns (comma-separated keys, in output
+// order) against catalogue, or returns the defaults when it is empty. dynamic,
+// if set, resolves the keys the catalogue does not list.
+func pickExportColumns(param string, catalogue []exportColumn, defaults []string, dynamic func(key string) (exportColumn, bool)) ([]exportColumn, error) {
+	byKey := map[string]exportColumn{}
+	for _, c := range catalogue {
+		byKey[c.key] = c
+	}
+	keys := defaults
+	if param != "" {
+		keys = strings.Split(param, ",")
+	}
+
+	var cols []exportColumn
+	seen := map[string]bool{}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		c, ok := byKey[key]
+		if !ok && dynamic != nil {
+			c, ok = dynamic(key)
+		}
+		if !ok {
+			return nil, fmt.Errorf("Unknown column %q", key)
+		}
+		cols = append(cols, c)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("Choose at least one column")
+	}
+	if len(cols) > xlsx.MaxColumns {
+		return nil, fmt.Errorf("At most %d columns", xlsx.MaxColumns)
+	}
+	return cols, nil
+}
+
+// exportSelect builds the SELECT list of cols, cast to their kind, binding
+// their args from $argIdx on.
+func exportSelect(cols []exportColumn, args []interface{}, argIdx int) (string, []interface{}) {
+	exprs := make([]string, len(cols))
+	for i, c := range cols {
+		expr := c.expr
+		if c.arg != nil {
+			expr = strings.ReplaceAll(expr, "$?", fmt.Sprintf("$%d", argIdx))
+			args = append(args, c.arg)
+			argIdx++
+		}
+		switch c.kind {
+		case exportInt:
+			expr = "(" + expr + ")::bigint"
+		case exportMoney:
+			expr = "(" + expr + ")::float8"
+		case exportDate:
+			expr = "(" + expr + ")::date"
+		default:
+			expr = "(" + expr + ")::text"
+		}
+		exprs[i] = expr
+	}
+	return strings.Join(exprs, ",\n\t\t\t"), args
+}
+
+// docTypeColumns resolves the per-document-type columns of the employee
+// and document exports, e.g. "expiry.visa": the latest document of that
+// type, where employeeID is the SQL of the employee ID to match.
+func docTypeColumns(ctx context.Context, q dbtx, employeeID string) func(key string) (exportColumn, bool) {
+	var names map[string]string
+	return func(key string) (exportColumn, bool) {
+		field, docType, ok := strings.Cut(key, ".")
+		if !ok {
+			return exportColumn{}, false
+		}
+		if names == nil {
+			names = exportDocTypes(ctx, q)
+		}
+		name, known := names[docType]
+		if !known {
+			return exportColumn{}, false
+		}
+		latest := `(SELECT %s FROM documents xd
+			WHERE xd.employee_id = ` + employeeID + ` AND xd.document_type = $?
+			ORDER BY xd.created_at DESC LIMIT 1)`
+		switch field {
+		case "expiry":
+			return exportColumn{key, name + " Expiry", fmt.Sprintf(latest, "xd.expiry_date"), exportDate, docType}, true
+		case "number":
+			return exportColumn{key, name + " Number", fmt.Sprintf(latest, "xd.document_number"), exportText, docType}, true
+		}
+		return exportColumn{}, false
+	}
+}
+
+// exportDocTypes maps the active document types to their display names,
+// falling back to the hardcoded mandatory types.
+func exportDocTypes(ctx context.Context, q dbtx) map[string]string {
+	names := map[string]string{}
+	rows, err := q.Query(ctx, `SELECT doc_type, display_name FROM document_types WHERE is_active = TRUE`)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var docType, name string
+			if rows.Scan(&docType, &name) == nil {
+				names[docType] = name
+			}
+		}
+	}
+	if len(names) == 0 {
+		for _, md := range compliance.MandatoryDocs {
+			names[md.DocType] = md.DisplayName
+		}
+	}
+	return names
+}
+
+// writeExport streams rows, selected with exportSelect(cols), as an
+// attachment named name.csv or name.xlsx. Errors after the first byte can
+// only be logged.
+func writeExport(w http.ResponseWriter, rows pgx.Rows, cols []exportColumn, format, name string) {
+	dest := make([]interface{}, len(cols))
+	for i, c := range cols {
+		switch c.kind {
+		case exportInt:
+			dest[i] = new(*int64)
+		case exportMoney:
+			dest[i] = new(*float64)
+		case exportDate:
+			dest[i] = new(*time.Time)
+		default:
+			dest[i] = new(*string)
+		}
+	}
+
+	var table interface {
+		write(values []interface{}) error
+		close() error
+	}
+	if format == "xlsx" {
+		columns := make([]xlsx.Column, len(cols))
+		for i, c := range cols {
+			columns[i] = xlsx.Column{Header: c.header}
+			switch c.kind {
+			case exportMoney:
+				columns[i].Format = xlsx.Decimal
+			case exportDate:
+				columns[i].Format, columns[i].Width = xlsx.Date, 12
+			}
+		}
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename="+name+".xlsx")
+		xw, err := xlsx.NewWriter(w, name, columns)
+		if err != nil {
+			log.Printf("Error starting %s export: %v", name, err)
+			return
+		}
+		table = xlsxTable{xw}
+	} else {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename="+name+".csv")
+		cw := csv.NewWriter(w)
+		headers := make([]string, len(cols))
+		for i, c := range cols {
+			headers[i] = csvText(c.header)
+		}
+		cw.Write(headers)
+		table = csvTable{cw}
+	}
+
+	values := make([]interface{}, len(cols))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			log.Printf("Error scanning %s export row: %v", name, err)
+			continue
+		}
+		for i, d := range dest {
+			values[i] = nil
+			switch d := d.(type) {
+			case **int64:
+				if *d != nil {
+					values[i] = **d
+				}
+			case **float64:
+				if *d != nil {
+					values[i] = **d
+				}
+			case **time.Time:
+				if *d != nil {
+					values[i] = **d
+				}
+			case **string:
+				if *d != nil {
+					values[i] = **d
+				}
+			}
+		}
+		// Stop at the first failed row (the xlsx row limit, say) but still
+		// close the table, so the client gets a well-formed file.
+		if err := table.write(values); err != nil {
+			log.Printf("Error writing %s export: %v", name, err)
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading %s export rows: %v", name, err)
+	}
+	if err := table.close(); err != nil {
+		log.Printf("Error finishing %s export: %v", name, err)
+	}
+}
+
+type xlsxTable struct{ w *xlsx.Writer }
+
+func (t xlsxTable) write(values []interface{}) error { return t.w.WriteRow(values...) }
+func (t xlsxTable) close() error                     { return t.w.Close() }
+
+// csvTable writes values as text: money with two decimals, dates as
+// YYYY-MM-DD. encoding/csv quotes commas, quotes and line breaks. Text that
+// a spreadsheet would run as a formula gets a leading apostrophe.
+type csvTable struct{ w *csv.Writer }
+
+func (t csvTable) write(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', 2, 64)
+		case time.Time:
+			record[i] = v.Format("2006-01-02")
+		case string:
+			record[i] = csvText(v)
+		}
+	}
+	return t.w.Write(record)
+}
+
+// csvText neutralises formula injection: cells starting with =, +, -, @,
+// a tab or a carriage return are prefixed with ' so Excel and Sheets show
+// them as text.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (t csvTable) close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
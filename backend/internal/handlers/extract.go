@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"manpower-backend/internal/mrz"
+	"manpower-backend/internal/ocr"
+)
+
+// ExtractHandler suggests document and employee fields from a scan, so
+// they need not be re-keyed by hand.
+type ExtractHandler struct {
+	reader *ocr.Reader
+}
+
+// NewExtractHandler creates an ExtractHandler reading scans with reader.
+func NewExtractHandler(reader *ocr.Reader) *ExtractHandler {
+	return &ExtractHandler{reader: reader}
+}
+
+// SuggestedField is an extracted value with a confidence between 0 and 1.
+type SuggestedField struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Extract handles POST /api/documents/extract — multipart "file" (a
+// passport or ID card image, or a PDF). It reads the MRZ and returns
+// suggested document fields (documentNumber, expiryDate, issueDate),
+// employee fields (name, nationality, dateOfBirth, gender,
+// passportNumber) and document metadata, each with a confidence. Nothing is
+// saved; 422 when the file has no readable MRZ.
+func (h *ExtractHandler) Extract(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		JSONError(w, http.StatusBadRequest, "File too large. Maximum size is 10MB.")
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, "Missing 'file' field in form data.")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, "Could not read file.")
+		return
+	}
+
+	// OCR of a full-page scan takes a few seconds
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	lines, source, err := h.reader.Read(ctx, data)
+	switch {
+	case errors.Is(err, ocr.ErrUnsupported):
+		JSONError(w, http.StatusUnprocessableEntity, "Fields can be extracted from JPG or PNG images and from PDFs.")
+		return
+	case errors.Is(err, ocr.ErrUnavailable):
+		JSONError(w, http.StatusUnprocessableEntity, "Text recognition is not configured; only PDFs with a text layer can be read.")
+		return
+	case err != nil:
+		log.Printf("Error extracting document text: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to read document")
+		return
+	}
+
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.Text
+	}
+	m, err := mrz.Parse(texts)
+	if err != nil {
+		JSONError(w, http.StatusUnprocessableEntity, "No machine readable zone found. Scan the whole data page, including the two or three lines of '<' characters at the bottom.")
+		return
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data": suggestFields(m, lines, source),
+	})
+}
+
+// ── Suggestions ────────────────────────────────────────────────
+
+// suggestFields turns a parsed MRZ into field suggestions. A field's
+// confidence starts from the OCR confidence of the MRZ lines and is scaled
+// by what the check digits say about it: fields with a passing check digit
+// of their own are near certain; fields without one (names, nationality,
+// sex) rest on the composite check; fields whose check failed are shown
+// for reference only.
+func suggestFields(m *mrz.Result, lines []ocr.Line, source string) map[string]interface{} {
+	var ocrConf float64
+	for i := m.Line; i < m.Line+len(m.Lines); i++ {
+		ocrConf += lines[i].Confidence
+	}
+	ocrConf /= float64(len(m.Lines))
+
+	checked := func(ok bool) float64 {
+		if ok {
+			return 0.5 + 0.5*ocrConf
+		}
+		return 0.2 * ocrConf
+	}
+	unchecked := 0.6 * ocrConf
+	if m.Checks.Composite {
+		unchecked = 0.8 * ocrConf
+	}
+	field := func(value string, conf float64) *SuggestedField {
+		if value == "" {
+			return nil
+		}
+		return &SuggestedField{Value: value, Confidence: math.Round(conf*100) / 100}
+	}
+
+	docType := ""
+	fields := map[string]*SuggestedField{
+		"expiryDate": field(m.ExpiryDate, checked(m.Checks.ExpiryDate)),
+	}
+	employee := map[string]*SuggestedField{
+		"name":        field(m.Name(), unchecked),
+		"nationality": field(mrz.CountryName(m.Nationality), unchecked),
+		"dateOfBirth": field(m.BirthDate, checked(m.Checks.BirthDate)),
+		"gender":      field(map[string]string{"M": "male", "F": "female"}[m.Sex], unchecked),
+	}
+	metadata := map[string]*SuggestedField{}
+
+	switch {
+	case strings.HasPrefix(m.DocumentCode, "P"):
+		docType = "passport"
+		number := field(m.DocumentNumber, checked(m.Checks.DocumentNumber))
+		fields["documentNumber"] = number
+		employee["passportNumber"] = number
+		metadata["nationality"] = employee["nationality"]
+		metadata["issuing_country"] = field(mrz.CountryName(m.IssuingState), unchecked)
+	default:
+		if eid, ok := m.EmiratesID(); ok {
+			// The Luhn digit checks the Emirates ID number; the card
+			// number's own check digit is not the one that matters
+			docType = "emirates_id"
+			fields["documentNumber"] = field(eid, checked(true))
+		} else {
+			fields["documentNumber"] = field(m.DocumentNumber, checked(m.Checks.DocumentNumber))
+		}
+	}
+
+	// The MRZ carries no issue date; look for a labelled one in the text
+	if issue, conf := issueDate(lines, m.BirthDate, m.ExpiryDate); issue != "" {
+		fields["issueDate"] = field(issue, 0.5*conf)
+	}
+
+	return map[string]interface{}{
+		"documentType": nilIfEmptyStr(docType),
+		"fields":       compactFields(fields),
+		"employee":     compactFields(employee),
+		"metadata":     compactFields(metadata),
+		"source":       source,
+		"mrz":          m,
+		"valid":        m.Valid(),
+	}
+}
+
+// compactFields drops fields with no value.
+func compactFields(in map[string]*SuggestedField) map[string]*SuggestedField {
+	out := make(map[string]*SuggestedField, len(in))
+	for k, v := range in {
+		if v != nil {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+var (
+	issueLabel   = regexp.MustCompile(`(?i)\b(date\s+of\s+issue|issue\s+date|issued|date\s+d'[ée]mission)\b`)
+	labelledDate = regexp.MustCompile(`(?i)\b(\d{1,2})[\s./-]+([a-z]{3}|\d{1,2})[a-z]*[\s./-]+(\d{4})\b`)
+)
+
+// issueDate finds a date on, or on the line after, an "issue date" label.
+// It must fall between the birth and expiry dates. Returns the date and
+// the OCR confidence of its line.
+func issueDate(lines []ocr.Line, birth, expiry string) (string, float64) {
+	for i, l := range lines {
+		if !issueLabel.MatchString(l.Text) {
+			continue
+		}
+		for j := i; j < len(lines) && j <= i+1; j++ {
+			for _, sm := range labelledDate.FindAllStringSubmatch(lines[j].Text, -1) {
+				d := parseLooseDate(sm[1], sm[2], sm[3])
+				if d == "" || (birth != "" && d <= birth) || (expiry != "" && d >= expiry) {
+					continue
+				}
+				return d, lines[j].Confidence
+			}
+		}
+	}
+	return "", 0
+}
+
+// parseLooseDate reads day, month (number or English abbreviation)
+// and year as printed on passports.
+func parseLooseDate(day, month, year string) string {
+	layout := "2 1 2006"
+	if len(month) == 3 && (month[0] < '0' || month[0] > '9') {
+		layout = "2 Jan 2006"
+		month = strings.ToUpper(month[:1]) + strings.ToLower(month[1:])
+	}
+	t, err := time.Parse(layout, day+" "+month+" "+year)
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/filegc"
+)
+
+// FileGCHandler exposes orphaned file cleanup to admins.
+type FileGCHandler struct {
+	db        database.Service
+	collector *filegc.Collector
+}
+
+// NewFileGCHandler creates a new FileGCHandler.
+func NewFileGCHandler(db database.Service, collector *filegc.Collector) *FileGCHandler {
+	return &FileGCHandler{db: db, collector: collector}
+}
+
+// Run handles POST /api/admin/storage/gc[?dryRun=true] — runs a cleanup now
+// and returns its report. Conflicts with a run already in progress.
+func (h *FileGCHandler) Run(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
+	defer cancel()
+
+	report, err := h.collector.Run(ctx, userID, dryRun)
+	if errors.Is(err, filegc.ErrRunning) {
+		JSONError(w, http.StatusConflict, "A file cleanup run is already in progress")
+		return
+	}
+	if err != nil && report == nil {
+		log.Printf("Error running file cleanup: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to run file cleanup")
+		return
+	}
+
+	go logActivity(r.Context(), h.db.GetPool(), userID, "file_gc", "file_gc_run", report.ID, map[string]interface{}{
+		"dryRun":         dryRun,
+		"quarantined":    report.Quarantined,
+		"deleted":        report.Deleted,
+		"bytesReclaimed": report.BytesReclaimed,
+	})
+
+	opts := h.collector.Options()
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data": report,
+		"settings": map[string]string{
+			"graceAge":      opts.GraceAge.String(),
+			"quarantineAge": opts.QuarantineAge.String(),
+		},
+	})
+}
+
+// fileGCRun is a row of file_gc_runs.
+type fileGCRun struct {
+	ID               string   `json:"id"`
+	TriggeredBy      *string  `json:"triggeredBy"` // null for the scheduled job
+	TriggeredByName  *string  `json:"triggeredByName"`
+	DryRun           bool     `json:"dryRun"`
+	StartedAt        string   `json:"startedAt"`
+	FinishedAt       *string  `json:"finishedAt"`
+	ObjectsScanned   int      `json:"objectsScanned"`
+	OrphansFound     int      `json:"orphansFound"`
+	Quarantined      int      `json:"quarantined"`
+	Restored         int      `json:"restored"`
+	Deleted          int      `json:"deleted"`
+	BytesQuarantined int64    `json:"bytesQuarantined"`
+	BytesReclaimed   int64    `json:"bytesReclaimed"`
+	Errors           []string `json:"errors"`
+}
+
+// ListRuns handles GET /api/admin/storage/gc — the 50 most recent runs.
+func (h *FileGCHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := h.db.GetPool().Query(ctx, `
+		SELECT g.id, g.triggered_by::text, u.name, g.dry_run, g.started_at::text, g.finished_at::text,
+			g.objects_scanned, g.orphans_found, g.quarantined, g.restored, g.deleted,
+			g.bytes_quarantined, g.bytes_reclaimed,
+			ARRAY(SELECT jsonb_array_elements_text(g.errors))
+		FROM file_gc_runs g
+		LEFT JOIN users u ON u.id = g.triggered_by
+		ORDER BY g.started_at DESC
+		LIMIT 50
+	`)
+	if err != nil {
+		log.Printf("Error fetching file cleanup runs: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch file cleanup runs")
+		return
+	}
+	defer rows.Close()
+
+	runs := []fileGCRun{}
+	for rows.Next() {
+		var run fileGCRun
+		if err := rows.Scan(
+			&run.ID, &run.TriggeredBy, &run.TriggeredByName, &run.DryRun, &run.StartedAt, &run.FinishedAt,
+			&run.ObjectsScanned, &run.OrphansFound, &run.Quarantined, &run.Restored, &run.Deleted,
+			&run.BytesQuarantined, &run.BytesReclaimed, &run.Errors,
+		); err != nil {
+			log.Printf("Error scanning file cleanup run: %v", err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{"data": runs})
+}
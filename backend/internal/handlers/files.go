@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/filegc"
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/permissions"
+	"manpower-backend/internal/resumable"
+	"manpower-backend/internal/storage"
+	"manpower-backend/internal/thumbnail"
+)
+
+// fileLinks mints short-lived signed URLs for stored files. The database only
+// holds storage keys; a URL is handed out after the caller's access to the
+// owning employee or document has been checked.
+type fileLinks struct {
+	store storage.Store
+	ttl   time.Duration
+}
+
+// sign returns a signed URL for key, or "" when there is no file or signing fails.
+func (f fileLinks) sign(ctx context.Context, key string) string {
+	if key == "" {
+		return ""
+	}
+	u, err := f.store.SignedURL(ctx, f.key(key), f.ttl)
+	if err != nil {
+		log.Printf("Error signing file URL for %s: %v", key, err)
+		return ""
+	}
+	return u
+}
+
+// key normalises a client-supplied file reference (a key or a URL we issued)
+// to the storage key that gets persisted.
+func (f fileLinks) key(raw string) string {
+	return storage.KeyFor(f.store, raw)
+}
+
+// signPtr replaces a nullable key in place with its signed URL.
+func (f fileLinks) signPtr(ctx context.Context, key *string) {
+	if key == nil || *key == "" {
+		return
+	}
+	signed := f.sign(ctx, *key)
+	*key = signed
+}
+
+// checksum returns the SHA-256 recorded in a content-addressed key, or nil
+// for legacy keys. Clients never supply checksums; they are derived here.
+func (f fileLinks) checksum(key string) *string {
+	sum, ok := storage.SHA256FromKey(key)
+	if !ok {
+		return nil
+	}
+	return &sum
+}
+
+// errFileNotAttachable means a client-supplied file reference is not one the
+// caller may attach.
+var errFileNotAttachable = errors.New("file not uploaded by the caller")
+
+// checkAttachable verifies that the caller may attach each file reference
+// in raws to a document or employee: it must be a file they uploaded (see
+// filegc.Collector.Claim), or one a document or employee within their
+// company scope already uses, so that resubmitting an unchanged file works.
+// Any other key, guessed or seen elsewhere, would hand them another
+// company's file. Resumable upload chunks and quarantined files are never
+// attachable.
+func (f fileLinks) checkAttachable(ctx context.Context, q dbtx, raws ...string) error {
+	userID, _ := ctx.Value(ctxkeys.UserID).(string)
+	scopeClause, scopeArg := companyScopeClause(ctx, 4, "e.company_id")
+
+	for _, raw := range raws {
+		if raw == "" {
+			continue
+		}
+		key := f.key(raw)
+		if strings.HasPrefix(key, resumable.StagingPrefix) || strings.HasPrefix(key, filegc.QuarantinePrefix) {
+			return errFileNotAttachable
+		}
+
+		// Rows may hold a legacy URL rather than its key, so match either
+		args := []interface{}{key, nilIfEmpty(userID), []string{key, raw}}
+		if scopeArg != nil {
+			args = append(args, scopeArg)
+		}
+		var ok bool
+		if err := q.QueryRow(ctx, fmt.Sprintf(`
+			SELECT EXISTS (SELECT 1 FROM file_uploads WHERE key = $1 AND user_id = $2)
+			    OR EXISTS (SELECT 1 FROM documents d JOIN employees e ON e.id = d.employee_id
+			               WHERE d.file_url = ANY($3)%[1]s)
+			    OR EXISTS (SELECT 1 FROM document_files df JOIN documents d ON d.id = df.document_id
+			               JOIN employees e ON e.id = d.employee_id
+			               WHERE df.file_url = ANY($3)%[1]s)
+			    OR EXISTS (SELECT 1 FROM employees e WHERE e.photo_url = ANY($3)%[1]s)
+		`, scopeClause), args...).Scan(&ok); err != nil {
+			return fmt.Errorf("check file %s: %w", key, err)
+		}
+		if !ok {
+			return errFileNotAttachable
+		}
+	}
+	return nil
+}
+
+// writeAttachError responds to a failed checkAttachable.
+func writeAttachError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errFileNotAttachable) {
+		JSONError(w, http.StatusUnprocessableEntity, "Files must be uploaded before they are attached")
+		return
+	}
+	log.Printf("Error checking attached files: %v", err)
+	JSONError(w, http.StatusInternalServerError, "Failed to check attached files")
+}
+
+// previewLinks are the signed derivative URLs of one stored file.
+type previewLinks struct {
+	thumbnail, preview *string
+}
+
+// previews signs the thumbnail and preview of each original key that has
+// them (see package thumbnail), with one query for all keys.
+func (f fileLinks) previews(ctx context.Context, pool *pgxpool.Pool, keys []string) map[string]previewLinks {
+	sets, err := thumbnail.Lookup(ctx, pool, keys)
+	if err != nil {
+		log.Printf("Error looking up thumbnails: %v", err)
+		return nil
+	}
+	links := make(map[string]previewLinks, len(sets))
+	for key, set := range sets {
+		var l previewLinks
+		if u := f.sign(ctx, set[thumbnail.Thumb]); u != "" {
+			l.thumbnail = &u
+		}
+		if u := f.sign(ctx, set[thumbnail.Preview]); u != "" {
+			l.preview = &u
+		}
+		links[key] = l
+	}
+	return links
+}
+
+// signPhotos replaces each employee's photo key with a signed URL and adds
+// the photo's thumbnail and preview URLs.
+func (f fileLinks) signPhotos(ctx context.Context, pool *pgxpool.Pool, emps ...*models.Employee) {
+	var keys []string
+	for _, e := range emps {
+		if e.PhotoURL != nil && *e.PhotoURL != "" {
+			keys = append(keys, f.key(*e.PhotoURL))
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	links := f.previews(ctx, pool, keys)
+	for _, e := range emps {
+		if e.PhotoURL == nil || *e.PhotoURL == "" {
+			continue
+		}
+		l := links[f.key(*e.PhotoURL)]
+		e.ThumbnailURL, e.PreviewURL = l.thumbnail, l.preview
+		f.signPtr(ctx, e.PhotoURL)
+	}
+}
+
+// signPreviews adds thumbnail and preview URLs to documents. The file itself
+// stays a key, and since previews show its content they are only handed to
+// callers who may download it.
+func (f fileLinks) signPreviews(ctx context.Context, pool *pgxpool.Pool, docs ...*models.Document) {
+	if !ctxkeys.HasPermission(ctx, permissions.DocumentsDownload) {
+		return
+	}
+	var keys []string
+	for _, d := range docs {
+		if d.FileURL != "" {
+			keys = append(keys, f.key(d.FileURL))
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	links := f.previews(ctx, pool, keys)
+	for _, d := range docs {
+		if d.FileURL == "" {
+			continue
+		}
+		l := links[f.key(d.FileURL)]
+		d.ThumbnailURL, d.PreviewURL = l.thumbnail, l.preview
+	}
+}
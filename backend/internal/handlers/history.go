@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/models"
+)
+
+// ── Change History ───────────────────────────────────────────────
+// Edits of employees and documents are stored field by field in
+// change_history: the handler reads the row before and after the UPDATE,
+// in one transaction, and records what differs.
+
+// fieldValue is a field of an entity and its value as text; nil for empty.
+type fieldValue struct {
+	field string
+	value *string
+}
+
+// diffFields returns the fields whose values differ between two snapshots
+// of the same entity, in snapshot order.
+func diffFields(before, after []fieldValue) []models.FieldChange {
+	changes := []models.FieldChange{}
+	for i, b := range before {
+		a := after[i]
+		if b.value == nil && a.value == nil || b.value != nil && a.value != nil && *b.value == *a.value {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: b.field, From: b.value, To: a.value})
+	}
+	return changes
+}
+
+// changedFields lists the fields of changes, for the activity log.
+func changedFields(changes []models.FieldChange) []string {
+	fields := make([]string, len(changes))
+	for i, c := range changes {
+		fields[i] = c.Field
+	}
+	return fields
+}
+
+// employeeFields snapshots the fields Update can change.
+func employeeFields(e *models.Employee) []fieldValue {
+	var salary *string
+	if e.Salary != nil {
+		s := strconv.FormatFloat(*e.Salary, 'f', -1, 64)
+		salary = &s
+	}
+	return []fieldValue{
+		{"name", nilIfEmpty(e.Name)},
+		{"trade", nilIfEmpty(e.Trade)},
+		{"mobile", nilIfEmpty(e.Mobile)},
+		{"joining_date", nilIfEmpty(e.JoiningDate)},
+		{"photo_url", e.PhotoURL},
+		{"gender", e.Gender},
+		{"date_of_birth", e.DateOfBirth},
+		{"nationality", e.Nationality},
+		{"passport_number", e.PassportNumber},
+		{"native_location", e.NativeLocation},
+		{"current_location", e.CurrentLocation},
+		{"salary", salary},
+		{"status", nilIfEmpty(e.Status)},
+	}
+}
+
+// documentFields snapshots the fields Update can change.
+func documentFields(d *models.Document) []fieldValue {
+	derefEmpty := func(s *string) *string {
+		if s == nil {
+			return nil
+		}
+		return nilIfEmpty(*s)
+	}
+	size := strconv.FormatInt(d.FileSize, 10)
+	return []fieldValue{
+		{"document_type", nilIfEmpty(d.DocumentType)},
+		{"document_number", derefEmpty(d.DocumentNumber)},
+		{"issue_date", derefEmpty(d.IssueDate)},
+		{"expiry_date", derefEmpty(d.ExpiryDate)},
+		{"metadata", nilIfEmpty(string(d.Metadata))},
+		{"file_url", nilIfEmpty(d.FileURL)},
+		{"file_name", nilIfEmpty(d.FileName)},
+		{"file_size", &size},
+		{"file_type", nilIfEmpty(d.FileType)},
+		{"file_sha256", d.FileSHA256},
+	}
+}
+
+// recordChanges stores a change set for the entity, attributed like
+// logActivity: to the user, and to the impersonator if there is one.
+// Nothing is stored when changes is empty.
+func recordChanges(ctx context.Context, q dbtx, userID, action, entityType, entityID string, changes []models.FieldChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	impersonatorID, _ := ctx.Value(ctxkeys.ImpersonatorID).(string)
+	_, err = q.Exec(ctx, `
+		INSERT INTO change_history (entity_type, entity_id, action, changes, user_id, impersonator_id)
+		VALUES ($1, $2::uuid, $3, $4::jsonb, $5::uuid, $6::uuid)
+	`, entityType, entityID, action, string(changesJSON), nilIfEmptyStr(userID), nilIfEmptyStr(impersonatorID))
+	return err
+}
+
+// changeHistory returns the entity's change sets, newest first.
+func changeHistory(ctx context.Context, q dbtx, entityType, entityID string) ([]models.ChangeSet, error) {
+	rows, err := q.Query(ctx, `
+		SELECT ch.id, ch.entity_type, ch.entity_id, ch.action, ch.changes,
+			ch.user_id::text, u.name, ch.impersonator_id::text, imp.name, ch.created_at
+		FROM change_history ch
+		LEFT JOIN users u ON u.id = ch.user_id
+		LEFT JOIN users imp ON imp.id = ch.impersonator_id
+		WHERE ch.entity_type = $1 AND ch.entity_id = $2
+		ORDER BY ch.created_at DESC
+	`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []models.ChangeSet{}
+	for rows.Next() {
+		var cs models.ChangeSet
+		if err := rows.Scan(&cs.ID, &cs.EntityType, &cs.EntityID, &cs.Action, &cs.Changes,
+			&cs.UserID, &cs.UserName, &cs.ImpersonatorID, &cs.ImpersonatorName, &cs.CreatedAt); err != nil {
+			log.Printf("Error scanning change set: %v", err)
+			continue
+		}
+		sets = append(sets, cs)
+	}
+	return sets, rows.Err()
+}
+
+// History handles GET /api/employees/{id}/history
+// Returns the employee's field-level edits, newest first.
+func (h *EmployeeHandler) History(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		JSONError(w, http.StatusBadRequest, "Employee ID is required")
+		return
+	}
+
+	if !checkEmployeeAccess(r.Context(), h.db.GetPool(), id) {
+		JSONError(w, http.StatusForbidden, "Access denied to this employee")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	sets, err := changeHistory(ctx, h.db.GetPool(), "employee", id)
+	if err != nil {
+		log.Printf("Error fetching history of employee %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch history")
+		return
+	}
+	JSON(w, http.StatusOK, map[string]interface{}{"data": sets})
+}
+
+// History handles GET /api/documents/{id}/history
+// Returns the document's field-level edits, newest first.
+func (h *DocumentHandler) History(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		JSONError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	if !checkDocumentAccess(r.Context(), h.db.GetPool(), id) {
+		JSONError(w, http.StatusForbidden, "Access denied to this document")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	sets, err := changeHistory(ctx, h.db.GetPool(), "document", id)
+	if err != nil {
+		log.Printf("Error fetching history of document %s: %v", id, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch history")
+		return
+	}
+	JSON(w, http.StatusOK, map[string]interface{}{"data": sets})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/middleware"
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/permissions"
+)
+
+// ImpersonationHandler lets super admins see the app exactly as another user
+// does, for support. The issued token carries the target's identity (so role,
+// permissions and company scope resolve as for the target) plus an
+// "impersonator" claim; activity is logged with both identities and account
+// management is blocked (see middleware.RequireDirectLogin).
+type ImpersonationHandler struct {
+	db   database.Service
+	auth *AuthHandler
+}
+
+// NewImpersonationHandler creates an ImpersonationHandler. Tokens are signed
+// through auth's keyring.
+func NewImpersonationHandler(db database.Service, auth *AuthHandler) *ImpersonationHandler {
+	return &ImpersonationHandler{db: db, auth: auth}
+}
+
+// Start handles POST /api/admin/impersonate/{userId}.
+// Super admins cannot be impersonated, and the caller must be signed in directly.
+// Unless the caller is super_admin, the target's permissions must be a subset
+// of the caller's.
+func (h *ImpersonationHandler) Start(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "userId")
+	currentUserID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	currentRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
+
+	if targetID == currentUserID {
+		JSONError(w, http.StatusBadRequest, "Cannot impersonate yourself")
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+	if req.Minutes == 0 {
+		req.Minutes = models.DefaultImpersonationMinutes
+	}
+	ttl := time.Duration(req.Minutes) * time.Minute
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	var target models.User
+	err := pool.QueryRow(ctx, `
+		SELECT id, email, name, role, created_at::text, updated_at::text
+		FROM users WHERE id = $1
+	`, targetID).Scan(&target.ID, &target.Email, &target.Name, &target.Role, &target.CreatedAt, &target.UpdatedAt)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if target.Role == permissions.SuperAdminRole {
+		JSONError(w, http.StatusForbidden, "Super admins cannot be impersonated")
+		return
+	}
+	// Like role assignment: impersonating must not grant the caller more
+	// than they hold, so a support role can't act as an admin.
+	if currentRole != permissions.SuperAdminRole {
+		granted, err := rolePermissions(ctx, pool, target.Role)
+		if err != nil {
+			log.Printf("Failed to fetch permissions of role %s: %v", target.Role, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to start impersonation")
+			return
+		}
+		if len(missingPermissions(r.Context(), granted)) > 0 {
+			JSONError(w, http.StatusForbidden, "Cannot impersonate a user with permissions you do not hold")
+			return
+		}
+	}
+
+	var sessionID string
+	var expiresAt time.Time
+	err = pool.QueryRow(ctx, `
+		INSERT INTO user_sessions (user_id, impersonator_id, ip_address, user_agent, device, expires_at)
+		VALUES ($1, $2, $3, $4, 'Impersonation', NOW() + $5 * INTERVAL '1 second')
+		RETURNING id, expires_at
+	`, target.ID, currentUserID, middleware.ClientIP(r), r.UserAgent(), int(ttl.Seconds()),
+	).Scan(&sessionID, &expiresAt)
+	if err != nil {
+		log.Printf("Failed to create impersonation session: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to start impersonation")
+		return
+	}
+
+	token, err := h.auth.keys.Sign(jwt.MapClaims{
+		"userId":       target.ID,
+		"role":         target.Role,
+		"sid":          sessionID,
+		"impersonator": currentUserID,
+		"exp":          expiresAt.Unix(),
+		"iat":          time.Now().Unix(),
+	})
+	if err != nil {
+		log.Printf("Failed to sign impersonation token: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to start impersonation")
+		return
+	}
+
+	// Logged as the super admin, since the impersonation hasn't begun yet
+	go logActivity(r.Context(), pool, currentUserID, "impersonation_started", "user", target.ID, map[string]interface{}{
+		"email":     target.Email,
+		"reason":    req.Reason,
+		"minutes":   req.Minutes,
+		"sessionId": sessionID,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data": models.ImpersonationResponse{
+			Token:     token,
+			ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+			User:      target,
+		},
+		"message": "Impersonation started",
+	})
+}
+
+// Stop handles DELETE /api/admin/impersonate — ends the current impersonation
+// session early. Called with the impersonation token itself.
+func (h *ImpersonationHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	if !ctxkeys.IsImpersonating(r.Context()) {
+		JSONError(w, http.StatusBadRequest, "Not impersonating")
+		return
+	}
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	sessionID, _ := r.Context().Value(ctxkeys.SessionID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	if _, err := pool.Exec(ctx,
+		`UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, sessionID,
+	); err != nil {
+		log.Printf("Failed to end impersonation session %s: %v", sessionID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to end impersonation")
+		return
+	}
+
+	go logActivity(r.Context(), pool, userID, "impersonation_ended", "user", userID, map[string]interface{}{
+		"sessionId": sessionID,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{"message": "Impersonation ended"})
+}
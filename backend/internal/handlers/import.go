@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/xlsx"
+)
+
+// ── Spreadsheet imports ──────────────────────────────────────────
+// Shared by the employee and document imports: reading the uploaded sheet,
+// mapping its columns to fields and parsing the values people type into
+// spreadsheets.
+
+// importField is a field a sheet column can map to, with the headers it is
+// recognised by (compared after normHeader).
+type importField struct {
+	name    string
+	headers []string
+}
+
+// importUpload is a parsed multipart import request.
+type importUpload struct {
+	fileName    string
+	header      []string
+	rows        [][]string // data rows; rows[i] is line i+2 of the sheet
+	columns     []models.ImportColumn
+	index       map[string]int // field → column
+	dryRun      bool
+	skipInvalid bool
+}
+
+// parseImportUpload reads an import request: multipart "file" (CSV or XLSX,
+// first row headers), optional "mapping" (JSON object of header → field,
+// "" to ignore a column; unmapped headers are matched automatically),
+// "dryRun" and "skipInvalid". The whole request may be maxBody bytes (the
+// sheet itself at most maxUploadSize); parts beyond 32MB spill to disk. It
+// writes the error response and returns nil when the request is unusable.
+func parseImportUpload(w http.ResponseWriter, r *http.Request, fields []importField, maxBody int64) *importUpload {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		JSONError(w, http.StatusBadRequest, fmt.Sprintf("Upload too large. Maximum size is %dMB.", maxBody>>20))
+		return nil
+	}
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, "Missing 'file' field in form data.")
+		return nil
+	}
+	defer file.Close()
+	if fh.Size > maxUploadSize {
+		JSONError(w, http.StatusRequestEntityTooLarge, "The sheet is too large. Maximum size is 10MB.")
+		return nil
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, "Could not read file.")
+		return nil
+	}
+
+	sheet, err := readImportSheet(fh.Filename, data)
+	if err != nil {
+		JSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return nil
+	}
+	if len(sheet) < 2 {
+		JSONError(w, http.StatusUnprocessableEntity, "The sheet needs a header row and at least one data row")
+		return nil
+	}
+	if len(sheet)-1 > models.MaxImportRows {
+		JSONError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("At most %d rows can be imported at once", models.MaxImportRows))
+		return nil
+	}
+
+	mapping := map[string]string{}
+	if m := r.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			JSONError(w, http.StatusBadRequest, "mapping must be a JSON object of header → field")
+			return nil
+		}
+	}
+	columns, index, err := mapColumns(sheet[0], fields, mapping)
+	if err != nil {
+		JSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return nil
+	}
+
+	return &importUpload{
+		fileName:    fh.Filename,
+		header:      sheet[0],
+		rows:        sheet[1:],
+		columns:     columns,
+		index:       index,
+		dryRun:      formBool(r.FormValue("dryRun")),
+		skipInvalid: formBool(r.FormValue("skipInvalid")),
+	}
+}
+
+func formBool(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
+// readImportSheet parses a CSV file, or the first worksheet of an XLSX
+// workbook, into rows of trimmed cells.
+func readImportSheet(name string, data []byte) ([][]string, error) {
+	var rows [][]string
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) || strings.EqualFold(path.Ext(name), ".xlsx") {
+		var err error
+		if rows, err = xlsx.ReadRows(data); err != nil {
+			if errors.Is(err, xlsx.ErrNotSpreadsheet) {
+				return nil, errors.New("Upload a CSV file or an Excel workbook (.xlsx)")
+			}
+			return nil, fmt.Errorf("Could not read the workbook: %v", err)
+		}
+	} else {
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+		cr := csv.NewReader(bytes.NewReader(data))
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+		// Excel writes semicolon-separated CSV in locales with a decimal comma
+		firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+		if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+			cr.Comma = ';'
+		}
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Could not read the CSV file: %v", err)
+			}
+			// The reader skips blank lines; keep rows numbered as in Excel
+			line, _ := cr.FieldPos(0)
+			for len(rows) < line-1 {
+				rows = append(rows, nil)
+			}
+			rows = append(rows, rec)
+			if len(rows) > models.MaxImportRows+1 {
+				break
+			}
+		}
+	}
+
+	out := rows[:0]
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+		if len(out) > 0 && blankRow(row) {
+			out = append(out, nil) // keep line numbers; skipped by the importers
+			continue
+		}
+		out = append(out, row)
+	}
+	for len(out) > 0 && blankRow(out[len(out)-1]) {
+		out = out[:len(out)-1]
+	}
+	return out, nil
+}
+
+func blankRow(row []string) bool {
+	for _, v := range row {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// normHeader lowercases a header and drops everything but letters and
+// digits, so "Passport No." and "passport_no" match.
+func normHeader(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// mapColumns assigns a field to each header: the mapping given for it, or
+// else the field it is recognised as, unless another column has it. A
+// field is taken by one column only.
+func mapColumns(header []string, fields []importField, mapping map[string]string) ([]models.ImportColumn, map[string]int, error) {
+	known := map[string]bool{}
+	byHeader := map[string]string{}
+	for _, f := range fields {
+		known[f.name] = true
+		byHeader[normHeader(f.name)] = f.name
+		for _, h := range f.headers {
+			byHeader[h] = f.name
+		}
+	}
+	mapped := map[string]bool{}
+	for h, f := range mapping {
+		if f != "" && !known[f] {
+			return nil, nil, fmt.Errorf("Unknown field %q for column %q", f, h)
+		}
+		mapped[f] = true
+	}
+
+	columns := make([]models.ImportColumn, len(header))
+	index := map[string]int{}
+	for i, h := range header {
+		field, ok := mapping[h]
+		if !ok {
+			field = byHeader[normHeader(h)]
+			if _, taken := index[field]; taken || mapped[field] {
+				field = "" // a second "Mobile" column is ignored
+			}
+		}
+		if prev, taken := index[field]; taken && field != "" {
+			return nil, nil, fmt.Errorf("Columns %q and %q are both mapped to %s", header[prev], h, field)
+		}
+		if field != "" {
+			index[field] = i
+		}
+		columns[i] = models.ImportColumn{Header: h, Field: field}
+	}
+	return columns, index, nil
+}
+
+// countImportRows recomputes the totals of report from its rows.
+func countImportRows(report *models.ImportReport) {
+	report.Valid, report.Created, report.Updated, report.Unchanged, report.Failed = 0, 0, 0, 0, 0
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		switch {
+		case len(row.Errors) > 0:
+			row.Status = "error"
+			report.Failed++
+		case row.Status == "created":
+			report.Created++
+		case row.Status == "updated":
+			report.Updated++
+		case row.Status == "unchanged":
+			report.Unchanged++
+		default:
+			report.Valid++
+		}
+	}
+}
+
+// fieldNames lists the names of fields, for the report.
+func fieldNames(fields []importField) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// get returns the value of field in row, or "" if no column maps to it.
+func (u *importUpload) get(row []string, field string) string {
+	i, ok := u.index[field]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+// has reports whether a column maps to field.
+func (u *importUpload) has(field string) bool {
+	_, ok := u.index[field]
+	return ok
+}
+
+// importDateLayouts are the date formats accepted in sheets. Dates are
+// read day first, as they are written in the UAE.
+var importDateLayouts = []string{
+	"2006-01-02", "2006/01/02", "2006-01-02 15:04:05",
+	"02/01/2006", "2/1/2006", "02-01-2006", "2-1-2006", "02.01.2006", "2.1.2006",
+	"02-Jan-2006", "2-Jan-2006", "02 Jan 2006", "2 Jan 2006", "02-Jan-06", "2 January 2006",
+}
+
+// importDate parses a date cell into YYYY-MM-DD.
+func importDate(s string) (string, bool) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// importNumber parses a number cell, ignoring thousands separators and a
+// leading currency code such as "AED 1,500".
+func importNumber(s string) (float64, bool) {
+	s = strings.TrimLeftFunc(s, unicode.IsLetter)
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
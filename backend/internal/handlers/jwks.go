@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"manpower-backend/internal/keyring"
+)
+
+// JWKSHandler publishes the public keys that verify our JWTs, so other
+// services can validate tokens without sharing a secret.
+type JWKSHandler struct {
+	keys *keyring.Keyring
+}
+
+// NewJWKSHandler creates a new JWKSHandler.
+func NewJWKSHandler(keys *keyring.Keyring) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// Serve handles GET /.well-known/jwks.json. Keys that are waiting to be
+// activated are already listed, so verifiers can cache them ahead of use.
+func (h *JWKSHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	JSON(w, http.StatusOK, map[string]interface{}{"keys": h.keys.JWKS()})
+}
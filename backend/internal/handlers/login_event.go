@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/middleware"
+	"manpower-backend/internal/models"
+)
+
+// Login event reasons stored in login_events.reason.
+const (
+	loginReasonSuccess         = "success"
+	loginReasonInvalidPassword = "invalid_password"
+	loginReasonUnknownEmail    = "unknown_email"
+	loginReasonAccountLocked   = "account_locked"
+	loginReasonPasswordOff     = "password_disabled"
+	loginReasonSSO             = "sso"
+	loginReasonSSONoAccount    = "sso_no_account"
+)
+
+// lockoutDuration returns how long an account is locked after the given number
+// of consecutive failed logins. Lockout is progressive: every further block of
+// five failures locks the account for longer, up to a day. Admins can unlock early.
+func lockoutDuration(failures int) time.Duration {
+	switch {
+	case failures >= 15:
+		return 24 * time.Hour
+	case failures >= 10:
+		return 30 * time.Minute
+	case failures >= 5:
+		return 5 * time.Minute
+	default:
+		return 0
+	}
+}
+
+// recordLoginEvent writes a sign-in attempt to login_events.
+// Like logActivity it is best-effort and never fails the request.
+func recordLoginEvent(pool *pgxpool.Pool, r *http.Request, userID, email string, success bool, reason string) {
+	ip := middleware.ClientIP(r)
+	userAgent := r.UserAgent()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := pool.Exec(ctx, `
+			INSERT INTO login_events (user_id, email, ip_address, user_agent, success, reason)
+			VALUES ($1::uuid, $2, $3, $4, $5, $6)
+		`, nilIfEmptyStr(userID), email, ip, userAgent, success, reason)
+		if err != nil {
+			log.Printf("audit: failed to write login event: %v", err)
+		}
+	}()
+}
+
+// recentSignIns returns the latest login events for a user, newest first.
+func recentSignIns(ctx context.Context, pool *pgxpool.Pool, userID string, limit int) []models.LoginEvent {
+	events := []models.LoginEvent{}
+
+	rows, err := pool.Query(ctx, `
+		SELECT id, user_id::text, email, ip_address, user_agent, success, reason, created_at::text
+		FROM login_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		log.Printf("Error fetching recent sign-ins for %s: %v", userID, err)
+		return events
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.LoginEvent
+		if err := rows.Scan(
+			&e.ID, &e.UserID, &e.Email, &e.IPAddress, &e.UserAgent, &e.Success, &e.Reason, &e.CreatedAt,
+		); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// LoginEventHandler exposes the login audit trail to admins.
+type LoginEventHandler struct {
+	db database.Service
+}
+
+// NewLoginEventHandler creates a new LoginEventHandler.
+func NewLoginEventHandler(db database.Service) *LoginEventHandler {
+	return &LoginEventHandler{db: db}
+}
+
+// List handles GET /api/admin/login-events?user_id=&email=&success=&page=&limit=
+func (h *LoginEventHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	where := "WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if userID := q.Get("user_id"); userID != "" {
+		where += fmt.Sprintf(" AND le.user_id = $%d", argIdx)
+		args = append(args, userID)
+		argIdx++
+	}
+	if email := q.Get("email"); email != "" {
+		where += fmt.Sprintf(" AND le.email ILIKE $%d", argIdx)
+		args = append(args, "%"+email+"%")
+		argIdx++
+	}
+	switch q.Get("success") {
+	case "true":
+		where += " AND le.success = TRUE"
+	case "false":
+		where += " AND le.success = FALSE"
+	}
+
+	var total int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM login_events le "+where, args...).Scan(&total); err != nil {
+		log.Printf("Error counting login events: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch login events")
+		return
+	}
+
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT le.id, le.user_id::text, u.name, le.email, le.ip_address, le.user_agent,
+			le.success, le.reason, le.created_at::text
+		FROM login_events le
+		LEFT JOIN users u ON u.id = le.user_id
+		%s
+		ORDER BY le.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, argIdx, argIdx+1), append(args, limit, offset)...)
+	if err != nil {
+		log.Printf("Error fetching login events: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch login events")
+		return
+	}
+	defer rows.Close()
+
+	events := []models.LoginEvent{}
+	for rows.Next() {
+		var e models.LoginEvent
+		if err := rows.Scan(
+			&e.ID, &e.UserID, &e.UserName, &e.Email, &e.IPAddress, &e.UserAgent,
+			&e.Success, &e.Reason, &e.CreatedAt,
+		); err != nil {
+			log.Printf("Error scanning login event: %v", err)
+			continue
+		}
+		events = append(events, e)
+	}
+
+	JSON(w, http.StatusOK, PaginatedResponse{
+		Data: events,
+		Pagination: PaginationMeta{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/permissions"
+)
+
+// RoleHandler provides the permission catalogue and CRUD for roles.
+type RoleHandler struct {
+	db database.Service
+}
+
+func NewRoleHandler(db database.Service) *RoleHandler {
+	return &RoleHandler{db: db}
+}
+
+// rolePermissions returns the permissions granted to role.
+func rolePermissions(ctx context.Context, q dbtx, role string) ([]string, error) {
+	var perms []string
+	err := q.QueryRow(ctx,
+		"SELECT COALESCE(ARRAY(SELECT permission FROM role_permissions WHERE role = $1), '{}')", role,
+	).Scan(&perms)
+	return perms, err
+}
+
+// missingPermissions returns those of perms the caller does not hold.
+func missingPermissions(ctx context.Context, perms []string) []string {
+	missing := []string{}
+	for _, p := range perms {
+		if !ctxkeys.HasPermission(ctx, p) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// ListPermissions handles GET /api/permissions — the catalogue of permission keys.
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, map[string]interface{}{"data": permissions.All})
+}
+
+// List handles GET /api/roles — every role with its permissions and user count.
+func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	rows, err := pool.Query(ctx, `
+		SELECT ro.id, ro.name, ro.display_name, ro.description, ro.is_system,
+			COALESCE(ARRAY(SELECT rp.permission FROM role_permissions rp WHERE rp.role = ro.name ORDER BY rp.permission), '{}'),
+			(SELECT COUNT(*) FROM users u WHERE u.role = ro.name)::int,
+			ro.created_at::text, ro.updated_at::text
+		FROM roles ro
+		ORDER BY ro.is_system DESC, ro.display_name ASC
+	`)
+	if err != nil {
+		log.Printf("Failed to list roles: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch roles")
+		return
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var ro models.Role
+		if err := rows.Scan(
+			&ro.ID, &ro.Name, &ro.DisplayName, &ro.Description, &ro.IsSystem,
+			&ro.Permissions, &ro.UserCount,
+			&ro.CreatedAt, &ro.UpdatedAt,
+		); err != nil {
+			log.Printf("Failed to scan role: %v", err)
+			continue
+		}
+		if ro.Name == permissions.SuperAdminRole {
+			ro.Permissions = permissions.Keys()
+		}
+		roles = append(roles, ro)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{"data": roles})
+}
+
+// Create handles POST /api/roles — defines a custom role. Unless the caller is
+// super_admin, its permissions must be a subset of the caller's.
+func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	// Only super_admin can grant permissions they don't hold themselves
+	currentRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
+	if currentRole != permissions.SuperAdminRole && len(missingPermissions(r.Context(), req.Permissions)) > 0 {
+		JSONError(w, http.StatusForbidden, "Cannot grant permissions you do not hold")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var ro models.Role
+	err = tx.QueryRow(ctx, `
+		INSERT INTO roles (name, display_name, description, is_system)
+		VALUES ($1, $2, $3, FALSE)
+		RETURNING id, name, display_name, description, is_system, created_at::text, updated_at::text
+	`, req.Name, req.DisplayName, req.Description).Scan(
+		&ro.ID, &ro.Name, &ro.DisplayName, &ro.Description, &ro.IsSystem, &ro.CreatedAt, &ro.UpdatedAt,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			JSONError(w, http.StatusConflict, "A role with this name already exists")
+			return
+		}
+		log.Printf("Failed to create role: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role, permission)
+		SELECT $1, UNNEST($2::text[])
+		ON CONFLICT DO NOTHING
+	`, ro.Name, req.Permissions); err != nil {
+		log.Printf("Failed to grant permissions to role %s: %v", ro.Name, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+
+	ro.Permissions = req.Permissions
+	if ro.Permissions == nil {
+		ro.Permissions = []string{}
+	}
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	go logActivity(r.Context(), pool, userID, "created", "role", ro.ID, map[string]interface{}{
+		"role":        ro.Name,
+		"permissions": ro.Permissions,
+	})
+
+	JSON(w, http.StatusCreated, map[string]interface{}{
+		"data":    ro,
+		"message": "Role created successfully",
+	})
+}
+
+// Update handles PUT /api/roles/{name} — edits labels and/or replaces the permission set.
+// super_admin always holds every permission, so its grants cannot be edited.
+// Other callers can't edit their own role, and the role's current and new
+// permissions must be a subset of theirs.
+func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req models.UpdateRoleDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	if name == permissions.SuperAdminRole && req.Permissions != nil {
+		JSONError(w, http.StatusBadRequest, "super_admin always has every permission")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	// Except for super_admin, nobody edits their own role, a role granting
+	// more than they hold, or grants permissions they don't hold.
+	currentRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
+	if currentRole != permissions.SuperAdminRole {
+		if name == currentRole {
+			JSONError(w, http.StatusForbidden, "Cannot edit your own role")
+			return
+		}
+		granted, err := rolePermissions(ctx, pool, name)
+		if err != nil {
+			log.Printf("Failed to fetch permissions of role %s: %v", name, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to update role")
+			return
+		}
+		if req.Permissions != nil {
+			granted = append(granted, *req.Permissions...)
+		}
+		if len(missingPermissions(r.Context(), granted)) > 0 {
+			JSONError(w, http.StatusForbidden, "Cannot edit a role with permissions you do not hold")
+			return
+		}
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var ro models.Role
+	err = tx.QueryRow(ctx, `
+		UPDATE roles SET
+			display_name = COALESCE($1, display_name),
+			description  = COALESCE($2, description),
+			updated_at   = NOW()
+		WHERE name = $3
+		RETURNING id, name, display_name, description, is_system, created_at::text, updated_at::text
+	`, req.DisplayName, req.Description, name).Scan(
+		&ro.ID, &ro.Name, &ro.DisplayName, &ro.Description, &ro.IsSystem, &ro.CreatedAt, &ro.UpdatedAt,
+	)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "Role not found")
+		return
+	}
+
+	if req.Permissions != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role = $1`, name); err != nil {
+			log.Printf("Failed to clear permissions of role %s: %v", name, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to update role")
+			return
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO role_permissions (role, permission)
+			SELECT $1, UNNEST($2::text[])
+			ON CONFLICT DO NOTHING
+		`, name, *req.Permissions); err != nil {
+			log.Printf("Failed to grant permissions to role %s: %v", name, err)
+			JSONError(w, http.StatusInternalServerError, "Failed to update role")
+			return
+		}
+	}
+
+	if err := tx.QueryRow(ctx,
+		`SELECT COALESCE(ARRAY(SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission), '{}')`,
+		name,
+	).Scan(&ro.Permissions); err != nil {
+		log.Printf("Failed to read permissions of role %s: %v", name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		JSONError(w, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+
+	if ro.Name == permissions.SuperAdminRole {
+		ro.Permissions = permissions.Keys()
+	}
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	go logActivity(r.Context(), pool, userID, "updated", "role", ro.ID, map[string]interface{}{
+		"role":        ro.Name,
+		"permissions": ro.Permissions,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data":    ro,
+		"message": "Role updated successfully",
+	})
+}
+
+// Delete handles DELETE /api/roles/{name}. System roles and roles still
+// assigned to users cannot be deleted.
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	var roleID string
+	var isSystem bool
+	var userCount int
+	err := pool.QueryRow(ctx, `
+		SELECT id, is_system, (SELECT COUNT(*) FROM users WHERE role = $1)::int
+		FROM roles WHERE name = $1
+	`, name).Scan(&roleID, &isSystem, &userCount)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "Role not found")
+		return
+	}
+	if isSystem {
+		JSONError(w, http.StatusForbidden, "System roles cannot be deleted")
+		return
+	}
+	if userCount > 0 {
+		JSONError(w, http.StatusConflict, "Role is still assigned to users; reassign them first")
+		return
+	}
+
+	if _, err := pool.Exec(ctx, `DELETE FROM roles WHERE name = $1`, name); err != nil {
+		log.Printf("Failed to delete role %s: %v", name, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to delete role")
+		return
+	}
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	go logActivity(r.Context(), pool, userID, "deleted", "role", roleID, map[string]interface{}{
+		"role": name,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{"message": "Role deleted successfully"})
+}
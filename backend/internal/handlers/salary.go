@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -46,19 +47,30 @@ func (h *SalaryHandler) Generate(w http.ResponseWriter, r *http.Request) {
 
 	pool := h.db.GetPool()
 
-	// Insert salary records for all active employees that have a salary set.
-	// ON CONFLICT DO NOTHING — skip if record already exists for that month.
-	genScopeFilter, genScopeArg := companyScopeClause(ctx, 3, "company_id")
+	// Insert salary records for all active employees that have a salary set,
+	// each counted for the company the employee belonged to at the end of
+	// the month (employment history; the current company if it has no
+	// period then). ON CONFLICT DO NOTHING — skip if record already exists
+	// for that month.
+	genScopeFilter, genScopeArg := companyScopeClause(ctx, 3, "COALESCE(h.company_id, e.company_id)")
 	genArgs := []interface{}{req.Month, req.Year}
 	if genScopeArg != nil {
 		genArgs = append(genArgs, genScopeArg)
 	}
 
 	tag, err := pool.Exec(ctx, fmt.Sprintf(`
-		INSERT INTO salary_records (employee_id, month, year, amount, status)
-		SELECT id, $1, $2, salary, 'pending'
-		FROM employees
-		WHERE status = 'active' AND salary IS NOT NULL AND salary > 0%s
+		INSERT INTO salary_records (employee_id, company_id, month, year, amount, status)
+		SELECT e.id, COALESCE(h.company_id, e.company_id), $1, $2, e.salary, 'pending'
+		FROM employees e
+		LEFT JOIN LATERAL (
+			SELECT h.company_id FROM employment_history h
+			WHERE h.employee_id = e.id
+			  AND h.start_date <= `+salaryMonthEnd+`
+			  AND (h.end_date IS NULL OR h.end_date > `+salaryMonthEnd+`)
+			ORDER BY h.start_date DESC
+			LIMIT 1
+		) h ON TRUE
+		WHERE e.status = 'active' AND e.salary IS NOT NULL AND e.salary > 0%s
 		ON CONFLICT (employee_id, month, year) DO NOTHING
 	`, genScopeFilter), genArgs...)
 	if err != nil {
@@ -69,7 +81,7 @@ func (h *SalaryHandler) Generate(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "generated_salary", "salary", "bulk", map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "generated_salary", "salary", "bulk", map[string]interface{}{
 		"month": req.Month, "year": req.Year, "count": tag.RowsAffected(),
 	})
 
@@ -79,44 +91,18 @@ func (h *SalaryHandler) Generate(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// salaryMonthEnd is the last day of the month of a salary record, with
+// $1 the month and $2 the year.
+const salaryMonthEnd = `(make_date($2::int, $1::int, 1) + INTERVAL '1 month - 1 day')::date`
+
 // List handles GET /api/salary?month=X&year=Y
 func (h *SalaryHandler) List(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	month, _ := strconv.Atoi(q.Get("month"))
-	year, _ := strconv.Atoi(q.Get("year"))
-	statusFilter := q.Get("status")
-	companyFilter := q.Get("company_id")
-
-	if month < 1 || month > 12 {
-		now := time.Now()
-		month = int(now.Month())
-		year = now.Year()
-	}
-	if year < 2020 {
-		year = time.Now().Year()
-	}
-
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
 	pool := h.db.GetPool()
 
-	where := "WHERE s.month = $1 AND s.year = $2"
-	args := []interface{}{month, year}
-	argIdx := 3
-
-	where, args, argIdx = appendCompanyScope(ctx, where, args, argIdx, "e.company_id")
-
-	if statusFilter != "" && statusFilter != "all" {
-		where += fmt.Sprintf(" AND s.status = $%d", argIdx)
-		args = append(args, statusFilter)
-		argIdx++
-	}
-	if companyFilter != "" {
-		where += fmt.Sprintf(" AND e.company_id = $%d", argIdx)
-		args = append(args, companyFilter)
-		argIdx++
-	}
+	where, args, _, _, _ := salaryListFilter(ctx, r.URL.Query())
 
 	rows, err := pool.Query(ctx, fmt.Sprintf(`
 		SELECT s.id, s.employee_id, s.month, s.year, s.amount, s.status,
@@ -125,7 +111,7 @@ func (h *SalaryHandler) List(w http.ResponseWriter, r *http.Request) {
 			e.name, c.name, COALESCE(c.currency, 'AED')
 		FROM salary_records s
 		JOIN employees e ON s.employee_id = e.id
-		JOIN companies c ON e.company_id = c.id
+		JOIN companies c ON s.company_id = c.id
 		%s
 		ORDER BY e.name ASC
 	`, where), args...)
@@ -156,6 +142,45 @@ func (h *SalaryHandler) List(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// salaryListFilter builds the WHERE clause of List and Export from the
+// query: month and year (default: the current month), status and
+// company_id, within the caller's company scope. Records are filtered and
+// scoped by the company they are counted for, not the employee's current
+// one.
+func salaryListFilter(ctx context.Context, q url.Values) (where string, args []interface{}, argIdx, month, year int) {
+	month, _ = strconv.Atoi(q.Get("month"))
+	year, _ = strconv.Atoi(q.Get("year"))
+	statusFilter := q.Get("status")
+	companyFilter := q.Get("company_id")
+
+	if month < 1 || month > 12 {
+		now := time.Now()
+		month = int(now.Month())
+		year = now.Year()
+	}
+	if year < 2020 {
+		year = time.Now().Year()
+	}
+
+	where = "WHERE s.month = $1 AND s.year = $2"
+	args = []interface{}{month, year}
+	argIdx = 3
+
+	where, args, argIdx = appendCompanyScope(ctx, where, args, argIdx, "s.company_id")
+
+	if statusFilter != "" && statusFilter != "all" {
+		where += fmt.Sprintf(" AND s.status = $%d", argIdx)
+		args = append(args, statusFilter)
+		argIdx++
+	}
+	if companyFilter != "" {
+		where += fmt.Sprintf(" AND s.company_id = $%d", argIdx)
+		args = append(args, companyFilter)
+		argIdx++
+	}
+	return where, args, argIdx, month, year
+}
+
 // UpdateStatus handles PATCH /api/salary/{id}/status — quick toggle
 func (h *SalaryHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -211,7 +236,7 @@ func (h *SalaryHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "updated_status", "salary", id, map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "updated_status", "salary", id, map[string]interface{}{
 		"status": req.Status,
 	})
 
@@ -261,7 +286,7 @@ func (h *SalaryHandler) BulkUpdateStatus(w http.ResponseWriter, r *http.Request)
 	scope := ctxkeys.GetCompanyScope(r.Context())
 	scopeJoin := ""
 	if scope != nil {
-		scopeJoin = fmt.Sprintf(` AND employee_id IN (SELECT id FROM employees WHERE company_id = ANY($%d))`, len(args)+1)
+		scopeJoin = fmt.Sprintf(` AND company_id = ANY($%d)`, len(args)+1)
 		args = append(args, scope)
 	}
 
@@ -280,7 +305,7 @@ func (h *SalaryHandler) BulkUpdateStatus(w http.ResponseWriter, r *http.Request)
 
 	// Audit trail
 	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	logActivity(pool, userID, "bulk_updated_status", "salary", "bulk", map[string]interface{}{
+	logActivity(r.Context(), pool, userID, "bulk_updated_status", "salary", "bulk", map[string]interface{}{
 		"status": req.Status, "count": tag.RowsAffected(),
 	})
 
@@ -313,7 +338,7 @@ func (h *SalaryHandler) Summary(w http.ResponseWriter, r *http.Request) {
 	// Calculate summary
 	// Note: Summing amounts of different currencies is conceptually wrong,
 	// but for this MVP we just sum raw values. Ideally frontend should warn if "Mixed".
-	sumScopeFilter, sumScopeArg := companyScopeClause(ctx, 3, "e.company_id")
+	sumScopeFilter, sumScopeArg := companyScopeClause(ctx, 3, "s.company_id")
 	sumArgs := []interface{}{month, year}
 	if sumScopeArg != nil {
 		sumArgs = append(sumArgs, sumScopeArg)
@@ -333,7 +358,7 @@ func (h *SalaryHandler) Summary(w http.ResponseWriter, r *http.Request) {
 				MAX(c.currency) as single_currency
 			FROM salary_records s
 			JOIN employees e ON s.employee_id = e.id
-			JOIN companies c ON e.company_id = c.id
+			JOIN companies c ON s.company_id = c.id
 			WHERE s.month = $1 AND s.year = $2%s
 		)
 		SELECT total_amount, paid_amount, pending_count, paid_count, partial_count, total_count,
@@ -355,41 +380,55 @@ func (h *SalaryHandler) Summary(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Export handles GET /api/salary/export?month=X&year=Y — returns CSV
-func (h *SalaryHandler) Export(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	month, _ := strconv.Atoi(q.Get("month"))
-	year, _ := strconv.Atoi(q.Get("year"))
+// salaryExportColumns are the columns Export can include.
+var salaryExportColumns = []exportColumn{
+	{key: "employee", header: "Employee", expr: "e.name"},
+	{key: "passportNumber", header: "Passport", expr: "e.passport_number"},
+	{key: "trade", header: "Trade", expr: "e.trade"},
+	{key: "company", header: "Company", expr: "c.name"},
+	{key: "month", header: "Month", expr: "s.month", kind: exportInt},
+	{key: "year", header: "Year", expr: "s.year", kind: exportInt},
+	{key: "currency", header: "Currency", expr: "COALESCE(c.currency, 'AED')"},
+	{key: "amount", header: "Amount", expr: "s.amount", kind: exportMoney},
+	{key: "status", header: "Status", expr: "s.status"},
+	{key: "paidDate", header: "Paid Date", expr: "s.paid_date", kind: exportDate},
+	{key: "notes", header: "Notes", expr: "s.notes"},
+}
 
-	if month < 1 || month > 12 {
-		now := time.Now()
-		month = int(now.Month())
-		year = now.Year()
+// salaryExportDefaults are the columns exported when none are chosen.
+var salaryExportDefaults = []string{"employee", "company", "currency", "amount", "status", "paidDate", "notes"}
+
+// Export handles GET /api/salary/export — the records List would return
+// for the month (same filters) as CSV, or with format=xlsx as a workbook
+// with date and number cells. columns picks the columns (salaryExportColumns).
+func (h *SalaryHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		JSONError(w, http.StatusBadRequest, "format must be csv or xlsx")
+		return
 	}
-	if year < 2020 {
-		year = time.Now().Year()
+	q := r.URL.Query()
+	cols, err := pickExportColumns(q.Get("columns"), salaryExportColumns, salaryExportDefaults, nil)
+	if err != nil {
+		JSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
 	pool := h.db.GetPool()
 
-	expScopeFilter, expScopeArg := companyScopeClause(ctx, 3, "e.company_id")
-	expArgs := []interface{}{month, year}
-	if expScopeArg != nil {
-		expArgs = append(expArgs, expScopeArg)
-	}
-
+	where, args, argIdx, month, year := salaryListFilter(ctx, q)
+	selectList, args := exportSelect(cols, args, argIdx)
 	rows, err := pool.Query(ctx, fmt.Sprintf(`
-		SELECT e.name, c.name, COALESCE(c.currency, 'AED'), s.amount, s.status,
-			COALESCE(s.paid_date::text, ''), COALESCE(s.notes, '')
+		SELECT %s
 		FROM salary_records s
 		JOIN employees e ON s.employee_id = e.id
-		JOIN companies c ON e.company_id = c.id
-		WHERE s.month = $1 AND s.year = $2%s
-		ORDER BY e.name ASC
-	`, expScopeFilter), expArgs...)
+		JOIN companies c ON s.company_id = c.id
+		%s
+		ORDER BY e.name ASC, s.id
+	`, selectList, where), args...)
 	if err != nil {
 		log.Printf("Error exporting salary: %v", err)
 		JSONError(w, http.StatusInternalServerError, "Failed to export")
@@ -397,21 +436,7 @@ func (h *SalaryHandler) Export(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	filename := fmt.Sprintf("salary_%d_%02d.csv", year, month)
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
-
-	fmt.Fprintln(w, "Employee,Company,Currency,Amount,Status,Paid Date,Notes")
-
-	for rows.Next() {
-		var name, company, currency, status, paidDate, notes string
-		var amount float64
-		if err := rows.Scan(&name, &company, &currency, &amount, &status, &paidDate, &notes); err != nil {
-			continue
-		}
-		fmt.Fprintf(w, "%s,%s,%s,%.2f,%s,%s,%s\n",
-			csvEscape(name), csvEscape(company), csvEscape(currency), amount, status, paidDate, csvEscape(notes))
-	}
+	writeExport(w, rows, cols, format, fmt.Sprintf("salary_%d_%02d", year, month))
 }
 
 // ListByEmployee handles GET /api/employees/{id}/salary
@@ -440,7 +465,7 @@ func (h *SalaryHandler) ListByEmployee(w http.ResponseWriter, r *http.Request) {
 			c.name, COALESCE(c.currency, 'AED')
 		FROM salary_records s
 		JOIN employees e ON s.employee_id = e.id
-		JOIN companies c ON e.company_id = c.id
+		JOIN companies c ON s.company_id = c.id
 		WHERE s.employee_id = $1
 		ORDER BY s.year DESC, s.month DESC
 	`, employeeID)
@@ -64,14 +64,14 @@ func checkDocumentAccess(ctx context.Context, pool *pgxpool.Pool, documentID str
 	return checkCompanyAccess(ctx, companyID)
 }
 
-// checkSalaryAccess looks up the salary record's employee → company and checks scope.
+// checkSalaryAccess looks up the company the salary record is counted for and checks scope.
 func checkSalaryAccess(ctx context.Context, pool *pgxpool.Pool, salaryID string) bool {
 	if ctxkeys.IsGlobalScope(ctx) {
 		return true
 	}
 	var companyID string
 	err := pool.QueryRow(ctx,
-		"SELECT e.company_id::text FROM salary_records s JOIN employees e ON e.id = s.employee_id WHERE s.id = $1",
+		"SELECT company_id::text FROM salary_records WHERE id = $1",
 		salaryID,
 	).Scan(&companyID)
 	if err != nil {
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/middleware"
+	"manpower-backend/internal/models"
+)
+
+// tokenTTL is how long a login stays valid. The session row and the JWT
+// expire together.
+const tokenTTL = 7 * 24 * time.Hour
+
+// createSession records a new signed-in device for the user and returns its ID,
+// which is embedded in the JWT as the "sid" claim.
+func createSession(ctx context.Context, pool *pgxpool.Pool, r *http.Request, userID string) (string, error) {
+	userAgent := r.UserAgent()
+
+	var sessionID string
+	err := pool.QueryRow(ctx, `
+		INSERT INTO user_sessions (user_id, ip_address, user_agent, device, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+		RETURNING id
+	`, userID, middleware.ClientIP(r), userAgent, describeDevice(userAgent), int(tokenTTL.Seconds()),
+	).Scan(&sessionID)
+	return sessionID, err
+}
+
+// describeDevice turns a User-Agent string into a short label such as
+// "Chrome on Windows". Unknown agents fall back to "Unknown device".
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	default:
+		return "Unknown device"
+	}
+}
+
+// SessionHandler lets users see and sign out their own devices.
+type SessionHandler struct {
+	db database.Service
+}
+
+// NewSessionHandler creates a new SessionHandler.
+func NewSessionHandler(db database.Service) *SessionHandler {
+	return &SessionHandler{db: db}
+}
+
+// List handles GET /api/auth/sessions — the caller's active sessions, most
+// recently used first. The session making the request is flagged as current.
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	currentID, _ := r.Context().Value(ctxkeys.SessionID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	rows, err := pool.Query(ctx, `
+		SELECT id, ip_address, user_agent, device,
+			created_at::text, last_seen_at::text, expires_at::text
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() AND impersonator_id IS NULL
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Error fetching sessions for %s: %v", userID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to fetch sessions")
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(
+			&s.ID, &s.IPAddress, &s.UserAgent, &s.Device,
+			&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt,
+		); err != nil {
+			log.Printf("Error scanning session: %v", err)
+			continue
+		}
+		s.Current = s.ID == currentID
+		sessions = append(sessions, s)
+	}
+
+	JSON(w, http.StatusOK, map[string]interface{}{"data": sessions})
+}
+
+// Revoke handles DELETE /api/auth/sessions/{id} — signs out one of the
+// caller's devices. Revoking the current session is equivalent to logging out.
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	var device string
+	err := pool.QueryRow(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING device
+	`, sessionID, userID).Scan(&device)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	go logActivity(r.Context(), pool, userID, "revoked_session", "user", userID, map[string]interface{}{
+		"sessionId": sessionID,
+		"device":    device,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{"message": "Session revoked"})
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/config"
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/models"
+	"manpower-backend/internal/oidc"
+	"manpower-backend/internal/permissions"
+)
+
+// oidcRequestTTL is how long a user has to complete sign-in at the IdP.
+const oidcRequestTTL = 10 * time.Minute
+
+// oidcStateCookie binds a sign-in to the browser that started it: it holds
+// the hash of the request's state, and the callback only proceeds if the
+// state it receives matches. Otherwise an attacker could start a sign-in and
+// have a victim's browser finish it, signing the victim into the attacker's
+// account.
+const oidcStateCookie = "oidc_state"
+
+// stateCookie is the state cookie for hashed, or an expired one to clear it.
+func stateCookie(hashed string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    hashed,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// hashState is the hex SHA-256 of an OIDC state, as kept in the cookie.
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// passwordLoginEnabled reads the admin switch for email + password sign-in.
+// Defaults to enabled if the setting can't be read.
+func passwordLoginEnabled(ctx context.Context, pool *pgxpool.Pool) bool {
+	var enabled bool
+	err := pool.QueryRow(ctx,
+		`SELECT (value)::boolean FROM app_settings WHERE key = 'password_login_enabled'`,
+	).Scan(&enabled)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Error reading password_login_enabled: %v", err)
+		}
+		return true
+	}
+	return enabled
+}
+
+// SSOHandler implements OpenID Connect sign-in and the sign-in settings.
+type SSOHandler struct {
+	db       database.Service
+	auth     *AuthHandler
+	cfg      config.OIDCConfig
+	provider *oidc.Provider // nil when SSO is not configured
+}
+
+// NewSSOHandler creates an SSOHandler. Tokens are issued through auth so SSO
+// and password logins produce identical sessions.
+func NewSSOHandler(db database.Service, auth *AuthHandler, cfg config.OIDCConfig) *SSOHandler {
+	h := &SSOHandler{db: db, auth: auth, cfg: cfg}
+	if cfg.Enabled() {
+		h.provider = oidc.New(oidc.Config{
+			IssuerURL:    cfg.IssuerURL,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		})
+	}
+	return h
+}
+
+func (h *SSOHandler) settings(ctx context.Context) models.AuthSettings {
+	s := models.AuthSettings{
+		PasswordLoginEnabled: passwordLoginEnabled(ctx, h.db.GetPool()),
+		SSOEnabled:           h.provider != nil,
+	}
+	if s.SSOEnabled {
+		s.SSOName = h.cfg.DisplayName
+		s.SSOLoginURL = "/api/auth/oidc/login"
+	}
+	return s
+}
+
+// Providers handles GET /api/auth/providers — public, tells the login page
+// which sign-in methods to offer.
+func (h *SSOHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	JSON(w, http.StatusOK, map[string]interface{}{"data": h.settings(ctx)})
+}
+
+// Login handles GET /api/auth/oidc/login — starts the authorization-code flow
+// by redirecting the browser to the identity provider.
+func (h *SSOHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		JSONError(w, http.StatusNotFound, "Single sign-on is not configured")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		log.Printf("Failed to create OIDC auth request: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to start sign-in")
+		return
+	}
+
+	authURL, err := h.provider.AuthCodeURL(ctx, req)
+	if err != nil {
+		log.Printf("Failed to build OIDC authorization URL: %v", err)
+		JSONError(w, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	// Drop abandoned requests while we're here
+	if _, err := pool.Exec(ctx,
+		`DELETE FROM oidc_auth_requests WHERE created_at < NOW() - $1 * INTERVAL '1 second'`,
+		int(oidcRequestTTL.Seconds()),
+	); err != nil {
+		log.Printf("Failed to purge expired OIDC requests: %v", err)
+	}
+
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO oidc_auth_requests (state, nonce, code_verifier) VALUES ($1, $2, $3)
+	`, req.State, req.Nonce, req.CodeVerifier); err != nil {
+		log.Printf("Failed to store OIDC auth request: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to start sign-in")
+		return
+	}
+
+	http.SetCookie(w, stateCookie(hashState(req.State), int(oidcRequestTTL.Seconds())))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback handles GET /api/auth/oidc/callback — the IdP redirects here with
+// an authorization code. On success the browser is sent to the frontend's
+// post-login page with the JWT in the URL fragment; on failure with an error.
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		JSONError(w, http.StatusNotFound, "Single sign-on is not configured")
+		return
+	}
+
+	// The state must be the one this browser started with; the cookie is
+	// single-use like the state itself
+	q := r.URL.Query()
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, stateCookie("", -1))
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashState(q.Get("state")))) != 1 {
+		h.redirectError(w, r, "Sign-in request expired, please try again")
+		return
+	}
+
+	if idpErr := q.Get("error"); idpErr != "" {
+		log.Printf("OIDC provider returned error: %s %s", idpErr, q.Get("error_description"))
+		h.redirectError(w, r, "Sign-in was cancelled or rejected by the identity provider")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	// Each state is single-use and short-lived
+	var nonce, verifier string
+	err = pool.QueryRow(ctx, `
+		DELETE FROM oidc_auth_requests
+		WHERE state = $1 AND created_at > NOW() - $2 * INTERVAL '1 second'
+		RETURNING nonce, code_verifier
+	`, q.Get("state"), int(oidcRequestTTL.Seconds())).Scan(&nonce, &verifier)
+	if err != nil {
+		h.redirectError(w, r, "Sign-in request expired, please try again")
+		return
+	}
+
+	rawIDToken, err := h.provider.Exchange(ctx, q.Get("code"), verifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		h.redirectError(w, r, "Could not complete sign-in with the identity provider")
+		return
+	}
+
+	claims, err := h.provider.VerifyIDToken(ctx, rawIDToken, nonce)
+	if err != nil {
+		log.Printf("OIDC id_token rejected: %v", err)
+		h.redirectError(w, r, "Could not verify your identity")
+		return
+	}
+
+	user, err := h.resolveUser(ctx, pool, claims)
+	if err != nil {
+		log.Printf("OIDC sign-in for %s (%s) refused: %v", claims.Email, claims.Subject, err)
+		recordLoginEvent(pool, r, "", claims.Email, false, loginReasonSSONoAccount)
+		h.redirectError(w, r, "No account is linked to this identity. Ask an administrator for access.")
+		return
+	}
+
+	sessionID, err := createSession(ctx, pool, r, user.ID)
+	if err != nil {
+		log.Printf("Failed to create session: %v", err)
+		h.redirectError(w, r, "Sign-in failed")
+		return
+	}
+	token, err := h.auth.generateToken(user.ID, user.Role, sessionID)
+	if err != nil {
+		log.Printf("Failed to generate token: %v", err)
+		h.redirectError(w, r, "Sign-in failed")
+		return
+	}
+
+	recordLoginEvent(pool, r, user.ID, user.Email, true, loginReasonSSO)
+
+	// The fragment never reaches server logs or Referer headers
+	http.Redirect(w, r, h.cfg.PostLoginURL+"#token="+url.QueryEscape(token), http.StatusFound)
+}
+
+// resolveUser maps IdP claims to a users row: first by linked subject, then
+// by verified email (linking the account), then by JIT provisioning if enabled.
+// super_admin accounts are never linked by email, since whoever controls a
+// matching address at the IdP would get them; they keep signing in with a
+// password unless users.oidc_subject is set for them by hand.
+func (h *SSOHandler) resolveUser(ctx context.Context, pool *pgxpool.Pool, claims *oidc.Claims) (*models.User, error) {
+	var user models.User
+
+	err := pool.QueryRow(ctx, `
+		SELECT id, email, name, role, created_at::text, updated_at::text
+		FROM users WHERE oidc_subject = $1
+	`, claims.Subject).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("no linked account and no verified email")
+	}
+
+	err = pool.QueryRow(ctx, `
+		UPDATE users SET oidc_subject = $1, updated_at = NOW()
+		WHERE LOWER(email) = LOWER($2) AND oidc_subject IS NULL AND role <> $3
+		RETURNING id, email, name, role, created_at::text, updated_at::text
+	`, claims.Subject, claims.Email, permissions.SuperAdminRole,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err == nil {
+		go logActivity(ctx, pool, user.ID, "linked_sso", "user", user.ID, map[string]interface{}{
+			"email": user.Email,
+		})
+		return &user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	// An account with this email exists but can't be linked (super_admin, or
+	// linked to another subject): don't provision a second one.
+	var exists bool
+	if err := pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))`, claims.Email,
+	).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("an account with this email exists but cannot be linked automatically")
+	}
+
+	if !h.cfg.JITProvisioning {
+		return nil, errors.New("no matching account and JIT provisioning is disabled")
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	// SSO-only accounts get an empty password hash, which never matches.
+	err = pool.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, name, role, oidc_subject)
+		VALUES ($1, '', $2, $3, $4)
+		RETURNING id, email, name, role, created_at::text, updated_at::text
+	`, claims.Email, name, h.cfg.DefaultRole, claims.Subject,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	go logActivity(ctx, pool, user.ID, "provisioned_sso", "user", user.ID, map[string]interface{}{
+		"email": user.Email,
+		"role":  user.Role,
+	})
+	return &user, nil
+}
+
+func (h *SSOHandler) redirectError(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, h.cfg.PostLoginURL+"#error="+url.QueryEscape(msg), http.StatusFound)
+}
+
+// GetSettings handles GET /api/admin/auth-settings.
+func (h *SSOHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	JSON(w, http.StatusOK, map[string]interface{}{"data": h.settings(ctx)})
+}
+
+// UpdateSettings handles PUT /api/admin/auth-settings. Password login can only
+// be disabled while SSO is configured; super_admin can always use a password
+// so the system can't be locked out if the IdP is down.
+func (h *SSOHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateAuthSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	if errs := req.Validate(); len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	if !*req.PasswordLoginEnabled && h.provider == nil {
+		JSONError(w, http.StatusConflict, "Configure single sign-on before disabling password login")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+
+	value, _ := json.Marshal(*req.PasswordLoginEnabled)
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO app_settings (key, value, updated_by, updated_at)
+		VALUES ('password_login_enabled', $1::jsonb, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, string(value), userID); err != nil {
+		log.Printf("Failed to update auth settings: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to update settings")
+		return
+	}
+
+	go logActivity(r.Context(), pool, userID, "updated_auth_settings", "user", userID, map[string]interface{}{
+		"passwordLoginEnabled": *req.PasswordLoginEnabled,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data":    h.settings(ctx),
+		"message": "Sign-in settings updated",
+	})
+}
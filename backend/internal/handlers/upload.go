@@ -1,18 +1,36 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/database"
+	"manpower-backend/internal/filegc"
+	"manpower-backend/internal/filescan"
+	"manpower-backend/internal/resumable"
 	"manpower-backend/internal/storage"
+	"manpower-backend/internal/thumbnail"
 )
 
-// Allowed file types and size limit for uploads.
+// Allowed file types and size limit for uploads. Categories may have their
+// own limit (UPLOAD_SIZE_LIMITS); single-request uploads are buffered whole,
+// so they are capped at maxUploadSize and larger files use upload sessions.
 const maxUploadSize = 10 << 20 // 10 MB
 
 var allowedTypes = map[string]bool{
@@ -25,23 +43,51 @@ var allowedTypes = map[string]bool{
 // UploadHandler handles file upload requests.
 // It depends on the storage.Store interface, not a specific implementation.
 type UploadHandler struct {
-	store storage.Store
+	db       database.Service
+	store    storage.Store
+	scans    *filescan.Service
+	thumbs   *thumbnail.Generator
+	slots    chan struct{} // one per thumbnail generation in progress
+	gc       *filegc.Collector
+	sessions *resumable.Manager
+	limits   map[string]int64
+	files    fileLinks
 }
 
 // NewUploadHandler creates an UploadHandler with the given storage backend.
-func NewUploadHandler(store storage.Store) *UploadHandler {
-	return &UploadHandler{store: store}
+// Uploads are checked by scans before they are saved, and thumbs makes their
+// thumbnails afterwards, at most GOMAXPROCS at a time. Every upload is
+// claimed with gc for its uploader, which lets them attach it and keeps the
+// cleanup from taking it for an orphan. sessions holds resumable uploads;
+// limits are the per-category size limits in bytes. Signed file URLs are
+// valid for urlTTL.
+func NewUploadHandler(db database.Service, store storage.Store, scans *filescan.Service, thumbs *thumbnail.Generator, gc *filegc.Collector,
+	sessions *resumable.Manager, limits map[string]int64, urlTTL time.Duration) *UploadHandler {
+	return &UploadHandler{
+		db:       db,
+		store:    store,
+		scans:    scans,
+		thumbs:   thumbs,
+		slots:    make(chan struct{}, runtime.GOMAXPROCS(0)),
+		gc:       gc,
+		sessions: sessions,
+		limits:   limits,
+		files:    fileLinks{store: store, ttl: urlTTL},
+	}
 }
 
 // Upload handles multipart file uploads.
 // Accepts: POST with multipart/form-data containing a "file" field.
-// Returns: file metadata (url, name, size, type) as JSON.
+// Returns: file metadata (key, signed preview url, name, size, type, sha256,
+// scan status) as JSON. Clients persist the key; the url expires. Files are
+// stored under a content-addressed key, so uploading the same file twice
+// stores it once. Files with malware are quarantined and rejected with 422.
 func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	// Enforce size limit before reading body
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		JSONError(w, http.StatusBadRequest, "File too large. Maximum size is 10MB.")
+		JSONError(w, http.StatusBadRequest, "File too large. Maximum size is 10MB; use an upload session for larger files.")
 		return
 	}
 
@@ -52,71 +98,282 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
+	// Optional "category" param allows organizing (e.g., "documents", "photos")
+	category := uploadCategory(r.FormValue("category"))
+	if limit := h.sizeLimit(category); header.Size > limit {
+		JSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf(
+			"File too large. Maximum size for %s is %dMB.", category, limit>>20,
+		))
+		return
+	}
+
+	info, uerr := h.ingest(r.Context(), file, header.Filename, category)
+	if uerr != nil {
+		JSONError(w, uerr.status, uerr.message)
+		return
+	}
+	JSON(w, http.StatusOK, info)
+}
+
+// uploadError is an ingest failure and the response it maps to.
+type uploadError struct {
+	status  int
+	message string
+}
+
+// ingest checks, scans and stores one uploaded file and returns its metadata
+// with a signed URL. Both Upload and finished upload sessions go through it.
+func (h *UploadHandler) ingest(ctx context.Context, file io.ReadSeeker, fileName, category string) (*storage.FileInfo, *uploadError) {
 	// Validate file type by reading the first 512 bytes (MIME sniffing)
 	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		JSONError(w, http.StatusBadRequest, "Could not read file.")
-		return
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, &uploadError{http.StatusBadRequest, "Could not read file."}
 	}
 	contentType := http.DetectContentType(buffer[:n])
 
 	if !allowedTypes[contentType] {
-		JSONError(w, http.StatusBadRequest, fmt.Sprintf(
+		return nil, &uploadError{http.StatusBadRequest, fmt.Sprintf(
 			"File type '%s' not allowed. Accepted: PDF, JPG, PNG.", contentType,
-		))
-		return
+		)}
 	}
 
-	// Reset file reader to beginning after MIME sniffing
+	// Hash the whole upload (the sniffed bytes included) to derive its key
+	hasher := sha256.New()
+	hasher.Write(buffer[:n])
+	if _, err := io.Copy(hasher, file); err != nil {
+		return nil, &uploadError{http.StatusBadRequest, "Could not read file."}
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+
+	safeName := sanitizeFilename(fileName)
+	storagePath := storage.ContentKey(category, sum, filepath.Ext(safeName))
+
+	// Scan for malware before anything is stored
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		JSONError(w, http.StatusInternalServerError, "Failed to process file.")
-		return
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to process file."}
+	}
+	scanStatus, signature := h.scans.Scan(ctx, file)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to process file."}
+	}
+	if scanStatus == filescan.StatusInfected {
+		log.Printf("Upload %s rejected: %s detected", safeName, signature)
+		if err := h.scans.Quarantine(ctx, storagePath, sum, file, contentType, signature, safeName); err != nil {
+			log.Printf("Error quarantining infected upload %s: %v", safeName, err)
+		}
+		return nil, &uploadError{http.StatusUnprocessableEntity, "File rejected: malware detected."}
 	}
 
-	// Build storage path: category/timestamp_filename
-	// Optional "category" param allows organizing (e.g., "documents", "photos")
-	category := r.FormValue("category")
-	if category == "" {
-		category = "general"
+	// Identical content is already stored: nothing to write
+	info, err := h.existing(ctx, storagePath, safeName, sum)
+	if err != nil {
+		// Persist via the storage interface
+		info, err = h.store.Save(ctx, storagePath, file, contentType)
+		if err != nil {
+			log.Printf("Upload failed: %v", err)
+			return nil, &uploadError{http.StatusInternalServerError, "Failed to save file."}
+		}
+		info.FileName = safeName
+		info.SHA256 = sum
+	}
+	if err := h.claim(ctx, storagePath); err != nil {
+		log.Printf("Error claiming upload: %v", err)
+		return nil, &uploadError{http.StatusInternalServerError, "Failed to save file."}
+	}
+
+	// A pending result keeps whatever verdict the file already has
+	if err := h.scans.Record(ctx, storagePath, sum, scanStatus, ""); err != nil {
+		log.Printf("Error recording scan result: %v", err)
+	}
+	if info.ScanStatus, err = h.scans.Status(ctx, storagePath); err != nil {
+		info.ScanStatus = scanStatus
 	}
+	info.URL = h.files.sign(ctx, info.Key)
 
-	// Sanitize filename and add timestamp to prevent collisions
-	safeName := sanitizeFilename(header.Filename)
-	storagePath := fmt.Sprintf("%s/%d_%s", category, time.Now().Unix(), safeName)
+	// Thumbnails are made in the background; documents and employees pick
+	// them up once they exist.
+	h.generateThumbnails(storagePath)
 
-	// Persist via the storage interface
-	info, err := h.store.Save(r.Context(), storagePath, file, contentType)
+	return info, nil
+}
+
+// sizeLimit returns the maximum file size for category.
+func (h *UploadHandler) sizeLimit(category string) int64 {
+	if limit, ok := h.limits[category]; ok {
+		return limit
+	}
+	return maxUploadSize
+}
+
+// Lookup handles GET /api/upload/{sha256}?category=&fileName= — returns the
+// metadata Upload would if a file with this content is already stored, so
+// clients can hash locally and skip the transfer. 404 otherwise. Checksums
+// are not secret (documents, download logs and exports show them), so
+// knowing one does not prove having the file: there is no signed URL here.
+// Reading the file goes through the document endpoints, or an upload that
+// sends the bytes. For the same reason it only answers for files the caller
+// may already attach (see checkAttachable); for any other the client has to
+// upload the file, which makes it theirs.
+func (h *UploadHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	sum := strings.ToLower(chi.URLParam(r, "sha256"))
+	if _, ok := storage.SHA256FromKey(sum); !ok {
+		JSONError(w, http.StatusBadRequest, "Invalid SHA-256 digest")
+		return
+	}
+
+	q := r.URL.Query()
+	safeName := sanitizeFilename(q.Get("fileName"))
+	key := storage.ContentKey(uploadCategory(q.Get("category")), sum, filepath.Ext(safeName))
+
+	info, err := h.existing(r.Context(), key, safeName, sum)
 	if err != nil {
-		log.Printf("Upload failed: %v", err)
-		JSONError(w, http.StatusInternalServerError, "Failed to save file.")
+		JSONError(w, http.StatusNotFound, "File not stored yet")
+		return
+	}
+	if err := h.files.checkAttachable(r.Context(), h.db.GetPool(), key); err != nil {
+		if !errors.Is(err, errFileNotAttachable) {
+			log.Printf("Error checking access to %s: %v", key, err)
+		}
+		JSONError(w, http.StatusNotFound, "File not stored yet")
+		return
+	}
+	if info.ScanStatus, err = h.scans.Status(r.Context(), key); err != nil {
+		log.Printf("Error fetching scan status of %s: %v", key, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to look up file")
+		return
+	}
+	if info.ScanStatus == filescan.StatusInfected {
+		JSONError(w, http.StatusNotFound, "File not stored yet")
+		return
+	}
+	// The object may be an orphan older than the cleanup's grace age
+	if err := h.claim(r.Context(), key); err != nil {
+		log.Printf("Error claiming reused upload: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to look up file")
 		return
 	}
 
 	JSON(w, http.StatusOK, info)
 }
 
-// ServeFile serves uploaded files.
-// For R2 storage, redirects to the public CDN URL.
-// For local storage, serves from disk.
+// generateThumbnails makes the derivatives of a freshly stored upload in the
+// background. Decoding a large scan takes a lot of memory and CPU, so only
+// GOMAXPROCS run at once; when all are busy the upload gets no thumbnails
+// for now, and the backfill job (`api backfill-thumbnails`) makes them later.
+func (h *UploadHandler) generateThumbnails(key string) {
+	select {
+	case h.slots <- struct{}{}:
+	default:
+		log.Printf("[thumbnail] busy; skipped %s until the next backfill", key)
+		return
+	}
+	go func() {
+		defer func() { <-h.slots }()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := h.thumbs.Generate(ctx, key); err != nil && !errors.Is(err, thumbnail.ErrUnsupported) {
+			log.Printf("Error generating thumbnails for %s: %v", key, err)
+		}
+	}()
+}
+
+// claim records the calling user as having uploaded key.
+func (h *UploadHandler) claim(ctx context.Context, key string) error {
+	userID, _ := ctx.Value(ctxkeys.UserID).(string)
+	return h.gc.Claim(ctx, key, userID)
+}
+
+// existing returns upload metadata for an already stored content-addressed
+// key, or storage.ErrNotFound.
+func (h *UploadHandler) existing(ctx context.Context, key, fileName, sum string) (*storage.FileInfo, error) {
+	obj, err := h.store.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	contentType := obj.ContentType
+	if !allowedTypes[contentType] {
+		contentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+	return &storage.FileInfo{
+		Key:      key,
+		FileName: fileName,
+		FileSize: obj.Size,
+		FileType: contentType,
+		SHA256:   sum,
+	}, nil
+}
+
+// ServeFile handles GET /api/files/* for stores whose files the API serves
+// itself: local storage, and encrypted storage (decrypted here). It is public
+// so that <img> tags and new tabs work without a bearer token, but only serves
+// URLs carrying a valid, unexpired signature minted by the store's SignedURL.
 func (h *UploadHandler) ServeFile(w http.ResponseWriter, r *http.Request) {
-	// Extract the full file path from the URL (everything after /api/files/)
-	filePath := strings.TrimPrefix(r.URL.Path, "/api/files/")
-	if filePath == "" {
+	verifier, ok := h.store.(interface {
+		Verify(path, expires, sig string) error
+	})
+	if !ok {
+		JSONError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/api/files/")
+	if key == "" {
 		JSONError(w, http.StatusBadRequest, "File path required.")
 		return
 	}
 
-	// If the store returns an https:// URL (R2), redirect to CDN
-	if url := h.store.URL(filePath); strings.HasPrefix(url, "https://") {
-		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	q := r.URL.Query()
+	switch err := verifier.Verify(key, q.Get("expires"), q.Get("sig")); {
+	case errors.Is(err, storage.ErrURLExpired):
+		JSONError(w, http.StatusForbidden, "This file link has expired.")
+		return
+	case err != nil:
+		JSONError(w, http.StatusForbidden, "Invalid file link.")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	if local, ok := h.store.(*storage.LocalStore); ok {
+		http.ServeFile(w, r, local.FilePath(key))
 		return
 	}
 
-	// Otherwise serve from local disk
-	http.ServeFile(w, r, filepath.Join("uploads", filepath.Clean(filePath)))
+	rc, info, err := h.store.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		JSONError(w, http.StatusNotFound, "File not found.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error opening file %s: %v", key, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to read file.")
+		return
+	}
+	defer rc.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	if _, err := io.Copy(w, rc); err != nil {
+		log.Printf("Error serving file %s: %v", key, err)
+	}
 }
 
+// uploadCategory restricts the client-chosen category to a safe path segment.
+func uploadCategory(category string) string {
+	if category == "" || !validCategory.MatchString(category) {
+		return "general"
+	}
+	return category
+}
+
+var validCategory = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
 // sanitizeFilename removes path separators and unsafe characters.
 func sanitizeFilename(name string) string {
 	// Keep only the base name (no directory components)
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/resumable"
+)
+
+// Resumable uploads (see package resumable):
+//
+//	POST   /api/uploads               {fileName, fileSize, category} → session
+//	GET    /api/uploads/{id}          current offset
+//	PATCH  /api/uploads/{id}          chunk body, Upload-Offset header
+//	POST   /api/uploads/{id}/finalize → same result as POST /api/upload
+//	DELETE /api/uploads/{id}          abort
+//
+// The offset is also returned in the Upload-Offset header, as in tus.
+
+// createUploadRequest is the body of POST /api/uploads.
+type createUploadRequest struct {
+	FileName string `json:"fileName"`
+	FileSize int64  `json:"fileSize"`
+	Category string `json:"category"`
+}
+
+var uploadExtensions = map[string]bool{".pdf": true, ".jpg": true, ".jpeg": true, ".png": true}
+
+// CreateSession handles POST /api/uploads — starts a resumable upload.
+func (h *UploadHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var req createUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+
+	errs := map[string]string{}
+	if strings.TrimSpace(req.FileName) == "" {
+		errs["fileName"] = "File name is required"
+	} else if !uploadExtensions[strings.ToLower(filepath.Ext(req.FileName))] {
+		errs["fileName"] = "Only PDF, JPG and PNG files are accepted"
+	}
+	if req.FileSize <= 0 {
+		errs["fileSize"] = "File size must be positive"
+	}
+	if len(errs) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": "Validation failed", "details": errs})
+		return
+	}
+
+	category := uploadCategory(req.Category)
+	if limit := h.sizeLimit(category); req.FileSize > limit {
+		JSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf(
+			"File too large. Maximum size for %s is %dMB.", category, limit>>20,
+		))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	s, err := h.sessions.Create(ctx, userID, category, sanitizeFilename(req.FileName), req.FileSize)
+	if err != nil {
+		log.Printf("Error creating upload session: %v", err)
+		JSONError(w, http.StatusInternalServerError, "Failed to start upload")
+		return
+	}
+
+	w.Header().Set("Location", "/api/uploads/"+s.ID)
+	writeSession(w, http.StatusCreated, s)
+}
+
+// GetSession handles GET /api/uploads/{id} — where to resume from.
+func (h *UploadHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	s, err := h.sessions.Get(ctx, chi.URLParam(r, "id"), userID)
+	if err != nil {
+		h.sessionError(w, err)
+		return
+	}
+	writeSession(w, http.StatusOK, s)
+}
+
+// PatchSession handles PATCH /api/uploads/{id} — appends the request body,
+// which must start at the Upload-Offset header. On 409 the client should
+// resume from the returned offset.
+func (h *UploadHandler) PatchSession(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		JSONError(w, http.StatusBadRequest, "Upload-Offset header is required")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, resumable.MaxChunkSize+1)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	s, err := h.sessions.Append(ctx, chi.URLParam(r, "id"), userID, offset, r.Body)
+	switch {
+	case errors.Is(err, resumable.ErrOffsetMismatch):
+		w.Header().Set("Upload-Offset", strconv.FormatInt(s.Offset, 10))
+		JSON(w, http.StatusConflict, map[string]interface{}{
+			"error":   http.StatusText(http.StatusConflict),
+			"message": "Offset does not match; resume from the current offset",
+			"status":  http.StatusConflict,
+			"data":    s,
+		})
+	case errors.Is(err, resumable.ErrTooLarge):
+		JSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf(
+			"Chunk too large: at most %dMB per request and no more than the declared file size", resumable.MaxChunkSize>>20,
+		))
+	case err != nil:
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			JSONError(w, http.StatusRequestEntityTooLarge, "Chunk too large")
+			return
+		}
+		h.sessionError(w, err)
+	default:
+		writeSession(w, http.StatusOK, s)
+	}
+}
+
+// FinalizeSession handles POST /api/uploads/{id}/finalize — assembles the
+// chunks and stores the file exactly like POST /api/upload.
+func (h *UploadHandler) FinalizeSession(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	s, err := h.sessions.Get(ctx, chi.URLParam(r, "id"), userID)
+	if err != nil {
+		h.sessionError(w, err)
+		return
+	}
+
+	f, err := h.sessions.Assemble(ctx, s)
+	if errors.Is(err, resumable.ErrIncomplete) {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(s.Offset, 10))
+		JSONError(w, http.StatusConflict, fmt.Sprintf("Upload incomplete: %d of %d bytes received", s.Offset, s.Length))
+		return
+	}
+	if err != nil {
+		log.Printf("Error assembling upload %s: %v", s.ID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to assemble upload")
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	info, uerr := h.ingest(ctx, f, s.FileName, s.Category)
+	if uerr != nil && uerr.status >= 500 {
+		// Keep the chunks so the client can retry the finalize
+		JSONError(w, uerr.status, uerr.message)
+		return
+	}
+	if err := h.sessions.Delete(ctx, s.ID); err != nil {
+		log.Printf("Error cleaning up upload %s: %v", s.ID, err)
+	}
+	if uerr != nil {
+		JSONError(w, uerr.status, uerr.message)
+		return
+	}
+	JSON(w, http.StatusOK, info)
+}
+
+// DeleteSession handles DELETE /api/uploads/{id} — abandons an upload.
+func (h *UploadHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	userID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	s, err := h.sessions.Get(ctx, chi.URLParam(r, "id"), userID)
+	if err != nil {
+		h.sessionError(w, err)
+		return
+	}
+	if err := h.sessions.Delete(ctx, s.ID); err != nil {
+		log.Printf("Error deleting upload %s: %v", s.ID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to cancel upload")
+		return
+	}
+	JSON(w, http.StatusOK, map[string]string{"message": "Upload cancelled"})
+}
+
+func (h *UploadHandler) sessionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, resumable.ErrNotFound) {
+		JSONError(w, http.StatusNotFound, "Upload not found or expired")
+		return
+	}
+	log.Printf("Error handling upload session: %v", err)
+	JSONError(w, http.StatusInternalServerError, "Upload failed")
+}
+
+// writeSession responds with the session and tus-style offset headers.
+func writeSession(w http.ResponseWriter, status int, s *resumable.Session) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(s.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(s.Length, 10))
+	w.Header().Set("Upload-Expires", s.ExpiresAt.UTC().Format(http.TimeFormat))
+	JSON(w, status, map[string]interface{}{"data": s})
+}
@@ -33,7 +33,9 @@ func (h *UserManagementHandler) List(w http.ResponseWriter, r *http.Request) {
 	currentRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
 
 	query := `
-		SELECT id, email, name, role, created_at::text, updated_at::text
+		SELECT id, email, name, role,
+			CASE WHEN locked_until > NOW() THEN locked_until::text END,
+			created_at::text, updated_at::text
 		FROM users
 	`
 	if currentRole != "super_admin" {
@@ -52,7 +54,7 @@ func (h *UserManagementHandler) List(w http.ResponseWriter, r *http.Request) {
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.LockedUntil, &u.CreatedAt, &u.UpdatedAt); err != nil {
 			log.Printf("Failed to scan user row: %v", err)
 			continue
 		}
@@ -91,12 +93,34 @@ func (h *UserManagementHandler) UpdateRole(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Admin can only assign company_owner or viewer
+	var roleExists bool
+	if err := h.db.GetPool().QueryRow(r.Context(),
+		"SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)", req.Role,
+	).Scan(&roleExists); err != nil || !roleExists {
+		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Validation failed",
+			"details": map[string]string{"role": "Unknown role '" + req.Role + "'"},
+		})
+		return
+	}
+
+	// Admin can only assign non-admin roles
 	if currentRole != "super_admin" {
 		if req.Role == "admin" || req.Role == "super_admin" {
 			JSONError(w, http.StatusForbidden, "Only super_admin can assign admin or super_admin roles")
 			return
 		}
+		// Nor a custom role that grants more than the admin holds themselves
+		var granted []string
+		h.db.GetPool().QueryRow(r.Context(),
+			"SELECT COALESCE(ARRAY(SELECT permission FROM role_permissions WHERE role = $1), '{}')", req.Role,
+		).Scan(&granted)
+		for _, p := range granted {
+			if !ctxkeys.HasPermission(r.Context(), p) {
+				JSONError(w, http.StatusForbidden, "Cannot assign a role with permissions you do not hold")
+				return
+			}
+		}
 		// Admin cannot change roles of admin/super_admin users
 		var targetRole string
 		h.db.GetPool().QueryRow(r.Context(), "SELECT role FROM users WHERE id = $1", targetID).Scan(&targetRole)
@@ -124,7 +148,7 @@ func (h *UserManagementHandler) UpdateRole(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	go logActivity(pool, currentUserID, "updated_role", "user", targetID, map[string]interface{}{
+	go logActivity(r.Context(), pool, currentUserID, "updated_role", "user", targetID, map[string]interface{}{
 		"newRole": req.Role,
 		"email":   user.Email,
 	})
@@ -176,13 +200,103 @@ func (h *UserManagementHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go logActivity(pool, currentUserID, "deleted", "user", targetID, map[string]interface{}{
+	go logActivity(r.Context(), pool, currentUserID, "deleted", "user", targetID, map[string]interface{}{
 		"email": email,
 	})
 
 	JSON(w, http.StatusOK, map[string]interface{}{"message": "User deleted successfully"})
 }
 
+// Unlock clears a user's failed-login counter and lockout. Admins cannot
+// unlock admin or super_admin users.
+func (h *UserManagementHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "id")
+	currentUserID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	currentRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	var email, targetRole string
+	err := pool.QueryRow(ctx, `SELECT email, role FROM users WHERE id = $1`, targetID).Scan(&email, &targetRole)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if currentRole != "super_admin" && (targetRole == "admin" || targetRole == "super_admin") {
+		JSONError(w, http.StatusForbidden, "Cannot modify admin or super_admin users")
+		return
+	}
+
+	tag, err := pool.Exec(ctx, `
+		UPDATE users SET failed_login_count = 0, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, targetID)
+	if err != nil {
+		log.Printf("Failed to unlock user %s: %v", targetID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to unlock account")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		JSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	go logActivity(r.Context(), pool, currentUserID, "unlocked", "user", targetID, map[string]interface{}{
+		"email": email,
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{"message": "Account unlocked"})
+}
+
+// RevokeSessions signs a user out of every device. Admins cannot sign out
+// admin or super_admin users.
+func (h *UserManagementHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "id")
+	currentUserID, _ := r.Context().Value(ctxkeys.UserID).(string)
+	currentRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pool := h.db.GetPool()
+
+	var email, targetRole string
+	err := pool.QueryRow(ctx, `SELECT email, role FROM users WHERE id = $1`, targetID).Scan(&email, &targetRole)
+	if err != nil {
+		JSONError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if currentRole != "super_admin" && (targetRole == "admin" || targetRole == "super_admin") {
+		JSONError(w, http.StatusForbidden, "Cannot modify admin or super_admin users")
+		return
+	}
+
+	tag, err := pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, targetID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of %s: %v", targetID, err)
+		JSONError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	go logActivity(r.Context(), pool, currentUserID, "revoked_sessions", "user", targetID, map[string]interface{}{
+		"email":    email,
+		"sessions": tag.RowsAffected(),
+	})
+
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"data":    map[string]interface{}{"revoked": tag.RowsAffected()},
+		"message": "User signed out of all sessions",
+	})
+}
+
 // ── Company Assignment ─────────────────────────────────────────
 
 // GetUserCompanies returns the company IDs assigned to a user.
@@ -272,7 +386,7 @@ func (h *UserManagementHandler) SetUserCompanies(w http.ResponseWriter, r *http.
 	}
 
 	currentUserID, _ := r.Context().Value(ctxkeys.UserID).(string)
-	go logActivity(pool, currentUserID, "assigned_companies", "user", userID, map[string]interface{}{
+	go logActivity(r.Context(), pool, currentUserID, "assigned_companies", "user", userID, map[string]interface{}{
 		"companyIds": req.CompanyIDs,
 	})
 
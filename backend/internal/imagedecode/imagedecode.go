@@ -0,0 +1,30 @@
+// Package imagedecode decodes uploaded JPEG and PNG scans with a bound on
+// their size, for the packages that rasterise them (thumbnail, watermark).
+// A small compressed file can declare a huge canvas, so dimensions are
+// checked before any pixels are allocated.
+package imagedecode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register the JPEG decoder
+	_ "image/png"  // register the PNG decoder
+)
+
+// MaxPixels bounds decoded images (about 8000×6000) to keep memory in check.
+const MaxPixels = 50_000_000
+
+// Decode decodes a JPEG or PNG image, refusing dimensions beyond MaxPixels
+// before allocating them.
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("image too large (%d×%d)", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
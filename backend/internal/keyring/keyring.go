@@ -0,0 +1,323 @@
+// Package keyring holds the keys used to sign and verify our JWTs.
+//
+// Keys are PEM files in a directory, one per key, named <kid>.pem. Private
+// keys (RSA → RS256, Ed25519 → EdDSA) can sign; public-only keys just verify.
+// Every loaded key verifies, so tokens signed by a retired key stay valid until
+// its file is removed. The signing key is the newest private key (by kid sort
+// order) that has been on disk for at least the activation delay, which gives
+// other instances time to pick it up before tokens signed with it appear.
+//
+// Zero-downtime rotation:
+//  1. Drop a new key file, e.g. 2026-11-01.pem, into the directory on every instance.
+//  2. After the activation delay it starts signing; the old key keeps verifying.
+//  3. Once the old key's tokens have expired (token TTL), delete its file.
+//
+// The directory is re-read periodically (StartAutoReload), so no restart is needed.
+// When a shared HMAC secret is configured it is kept as an extra HS256 key
+// ("hs256"), used for signing only when no key files exist and otherwise for
+// verifying tokens issued before the switch to asymmetric keys. Set
+// HMACVerifyUntil to retire it: after that time, once key files exist, it no
+// longer verifies anything, and the secret can keep serving other purposes.
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// HMACKeyID is the kid of the shared-secret key.
+const HMACKeyID = "hs256"
+
+// Options configure where keys come from.
+type Options struct {
+	Dir             string        // directory of <kid>.pem files; empty for HMAC-only
+	SigningKeyID    string        // optional: force this kid to sign
+	ActivationDelay time.Duration // how long a new key only verifies before signing
+	HMACSecret      string        // optional legacy/shared secret
+	HMACVerifyUntil time.Time     // optional: stop accepting HS256 tokens after this, if key files exist
+}
+
+// Keyring is safe for concurrent use.
+type Keyring struct {
+	opts Options
+
+	mu      sync.RWMutex
+	keys    map[string]*key
+	signing *key
+}
+
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{} // nil for verify-only keys
+	public  interface{}
+	loaded  time.Time // file modification time
+}
+
+// Load reads all keys and picks the signing key.
+func Load(opts Options) (*Keyring, error) {
+	k := &Keyring{opts: opts}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload re-reads the key directory. On error the previous keys stay in use.
+func (k *Keyring) Reload() error {
+	keys := map[string]*key{}
+
+	if k.opts.HMACSecret != "" {
+		keys[HMACKeyID] = &key{
+			id:      HMACKeyID,
+			method:  jwt.SigningMethodHS256,
+			private: []byte(k.opts.HMACSecret),
+			public:  []byte(k.opts.HMACSecret),
+		}
+	}
+
+	if k.opts.Dir != "" {
+		files, err := filepath.Glob(filepath.Join(k.opts.Dir, "*.pem"))
+		if err != nil {
+			return fmt.Errorf("list key files: %w", err)
+		}
+		for _, path := range files {
+			kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+			if kid == HMACKeyID {
+				return fmt.Errorf("key file %s: kid %q is reserved", path, kid)
+			}
+			ky, err := loadKeyFile(path, kid)
+			if err != nil {
+				return err
+			}
+			keys[kid] = ky
+		}
+	}
+
+	// Past its retirement the shared secret is dropped, unless it is the
+	// only key there is.
+	if _, ok := keys[HMACKeyID]; ok && len(keys) > 1 &&
+		!k.opts.HMACVerifyUntil.IsZero() && time.Now().After(k.opts.HMACVerifyUntil) {
+		delete(keys, HMACKeyID)
+	}
+
+	signing, err := k.pickSigningKey(keys)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.signing == nil || k.signing.id != signing.id {
+		log.Printf("[keyring] signing with key %q (%s), %d key(s) verifying", signing.id, signing.method.Alg(), len(keys))
+	}
+	k.keys = keys
+	k.signing = signing
+	return nil
+}
+
+func (k *Keyring) pickSigningKey(keys map[string]*key) (*key, error) {
+	if k.opts.SigningKeyID != "" {
+		ky, ok := keys[k.opts.SigningKeyID]
+		if !ok || ky.private == nil {
+			return nil, fmt.Errorf("signing key %q not found or has no private key", k.opts.SigningKeyID)
+		}
+		return ky, nil
+	}
+
+	var candidates []*key
+	for _, ky := range keys {
+		if ky.private != nil && ky.id != HMACKeyID {
+			candidates = append(candidates, ky)
+		}
+	}
+	if len(candidates) == 0 {
+		if hk, ok := keys[HMACKeyID]; ok {
+			return hk, nil
+		}
+		return nil, errors.New("no signing key: add a private key file or set a JWT secret")
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].id > candidates[j].id })
+	for _, ky := range candidates {
+		if time.Since(ky.loaded) >= k.opts.ActivationDelay {
+			return ky, nil
+		}
+	}
+	// Fresh install: every key is new, so nobody holds tokens yet.
+	return candidates[len(candidates)-1], nil
+}
+
+// StartAutoReload re-reads the key directory every interval in the background.
+func (k *Keyring) StartAutoReload(interval time.Duration) {
+	if k.opts.Dir == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := k.Reload(); err != nil {
+				log.Printf("[keyring] reload failed, keeping previous keys: %v", err)
+			}
+		}
+	}()
+}
+
+// Sign signs the claims with the current signing key and sets the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	signing := k.signing
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(signing.method, claims)
+	token.Header["kid"] = signing.id
+	return token.SignedString(signing.private)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+// Tokens without a kid predate the keyring and are checked against the HMAC key.
+// The token's algorithm must match the key's, preventing algorithm confusion.
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = HMACKeyID
+	}
+
+	k.mu.RLock()
+	ky, ok := k.keys[kid]
+	k.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if t.Method.Alg() != ky.method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return ky.public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys for /.well-known/jwks.json. The HMAC key is
+// never published.
+func (k *Keyring) JWKS() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := []JWK{}
+	for _, id := range ids {
+		ky := k.keys[id]
+		switch pub := ky.public.(type) {
+		case *rsa.PublicKey:
+			set = append(set, JWK{
+				Kty: "RSA", Kid: id, Use: "sig", Alg: ky.method.Alg(),
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set = append(set, JWK{
+				Kty: "OKP", Kid: id, Use: "sig", Alg: ky.method.Alg(),
+				Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// ValidMethods lists the algorithms of the loaded keys, for jwt.WithValidMethods.
+func (k *Keyring) ValidMethods() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	seen := map[string]bool{}
+	var methods []string
+	for _, ky := range k.keys {
+		if alg := ky.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// loadKeyFile parses a PEM private key (PKCS#8 or PKCS#1) or public key (PKIX).
+func loadKeyFile(path, kid string) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file %s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat key file %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key file %s: no PEM block", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key file %s: unsupported PEM type %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+
+	ky := &key{id: kid, loaded: info.ModTime()}
+	switch v := parsed.(type) {
+	case *rsa.PrivateKey:
+		ky.method, ky.private, ky.public = jwt.SigningMethodRS256, v, &v.PublicKey
+	case *rsa.PublicKey:
+		ky.method, ky.public = jwt.SigningMethodRS256, v
+	case ed25519.PrivateKey:
+		ky.method, ky.private, ky.public = jwt.SigningMethodEdDSA, v, v.Public()
+	case ed25519.PublicKey:
+		ky.method, ky.public = jwt.SigningMethodEdDSA, v
+	default:
+		return nil, fmt.Errorf("key file %s: only RSA and Ed25519 keys are supported", path)
+	}
+	if rk, ok := ky.public.(*rsa.PublicKey); ok && rk.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("key file %s: RSA keys must be at least 2048 bits", path)
+	}
+	return ky, nil
+}
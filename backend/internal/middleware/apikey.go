@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/ctxkeys"
+)
+
+// APIKeyPrefix marks a bearer token as an API key rather than a JWT.
+const APIKeyPrefix = "mpk_"
+
+// HashAPIKey returns the hex SHA-256 digest stored in api_keys.key_hash.
+// Keys carry 256 bits of randomness, so a fast hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateAPIKey resolves an API key to its owner and serves the request
+// as that user. Read-only keys are limited to safe methods, and every request
+// made with a key is written to activity_log against the key.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, pool *pgxpool.Pool, key string) {
+	var (
+		keyID, keyName, access, userID, role string
+		allCompanies                         bool
+		companyIDs                           []string
+		lastUsed                             *time.Time
+	)
+	err := pool.QueryRow(r.Context(), `
+		SELECT k.id, k.name, k.access, k.all_companies, k.last_used_at, u.id, u.role,
+			COALESCE(ARRAY(SELECT company_id::text FROM api_key_companies WHERE api_key_id = k.id), '{}')
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL
+			AND (k.expires_at IS NULL OR k.expires_at > NOW())
+	`, HashAPIKey(key)).Scan(&keyID, &keyName, &access, &allCompanies, &lastUsed, &userID, &role, &companyIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, http.StatusUnauthorized, "Invalid, revoked or expired API key")
+		return
+	}
+	if err != nil {
+		log.Printf("[auth] failed to verify API key: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to verify API key")
+		return
+	}
+
+	if access == "read" && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusForbidden, "This API key is read-only")
+		return
+	}
+
+	if lastUsed == nil || time.Since(*lastUsed) > sessionTouchInterval {
+		if _, err := pool.Exec(r.Context(),
+			`UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1`,
+			keyID, ClientIP(r),
+		); err != nil {
+			log.Printf("[auth] failed to touch API key %s: %v", keyID, err)
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), ctxkeys.UserID, userID)
+	ctx = context.WithValue(ctx, ctxkeys.UserRole, role)
+	ctx = context.WithValue(ctx, ctxkeys.APIKeyID, keyID)
+	if !allCompanies {
+		ctx = context.WithValue(ctx, ctxkeys.APIKeyCompanies, companyIDs)
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r.WithContext(ctx))
+
+	go logAPIKeyRequest(pool, userID, keyID, keyName, r.Method, r.URL.Path, rec.status)
+}
+
+// logAPIKeyRequest records a request made with an API key in activity_log.
+// Best-effort, like the handlers' logActivity.
+func logAPIKeyRequest(pool *pgxpool.Pool, userID, keyID, keyName, method, path string, status int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"key":    keyName,
+		"method": method,
+		"path":   path,
+		"status": status,
+	})
+
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO activity_log (user_id, action, entity_type, entity_id, details)
+		VALUES ($1::uuid, 'api_request', 'api_key', $2::uuid, $3::jsonb)
+	`, userID, keyID, string(details)); err != nil {
+		log.Printf("audit: failed to log API key request: %v", err)
+	}
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
@@ -4,21 +4,30 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"manpower-backend/internal/ctxkeys"
+	"manpower-backend/internal/keyring"
+	"manpower-backend/internal/permissions"
 )
 
-// Auth validates the JWT token from the Authorization header and
-// injects the user's ID and role into the request context.
-func Auth(jwtSecret string) func(http.Handler) http.Handler {
-	secret := []byte(jwtSecret)
+// sessionTouchInterval throttles last_seen_at writes to one per session per minute.
+const sessionTouchInterval = time.Minute
 
+// Auth validates the JWT token from the Authorization header against the
+// keyring (by its kid header), checks that the
+// session it belongs to is still active, and injects the user's ID, role and
+// session ID (and impersonator, if any) into the request context. Bearer tokens starting with
+// APIKeyPrefix are treated as API keys instead (see authenticateAPIKey).
+func Auth(keys *keyring.Keyring, pool *pgxpool.Pool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -33,12 +42,12 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.ErrSignatureInvalid
-				}
-				return secret, nil
-			})
+			if strings.HasPrefix(parts[1], APIKeyPrefix) {
+				authenticateAPIKey(w, r, next, pool, parts[1])
+				return
+			}
+
+			token, err := jwt.Parse(parts[1], keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
 
 			if err != nil || !token.Valid {
 				writeError(w, http.StatusUnauthorized, "Invalid or expired token")
@@ -53,33 +62,106 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 
 			userID, _ := claims["userId"].(string)
 			role, _ := claims["role"].(string)
+			sessionID, _ := claims["sid"].(string)
+			impersonator, _ := claims["impersonator"].(string)
 
 			if userID == "" {
 				writeError(w, http.StatusUnauthorized, "Invalid token: missing user ID")
 				return
 			}
 
+			// Tokens issued before sessions existed carry no sid and must sign in again.
+			if sessionID == "" {
+				writeError(w, http.StatusUnauthorized, "Session expired, please sign in again")
+				return
+			}
+
+			var lastSeen time.Time
+			err = pool.QueryRow(r.Context(), `
+				SELECT last_seen_at FROM user_sessions
+				WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+					AND COALESCE(impersonator_id::text, '') = $3
+			`, sessionID, userID, impersonator).Scan(&lastSeen)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, http.StatusUnauthorized, "Session has been revoked or expired")
+				return
+			}
+			if err != nil {
+				log.Printf("[auth] failed to verify session %s: %v", sessionID, err)
+				writeError(w, http.StatusInternalServerError, "Failed to verify session")
+				return
+			}
+
+			if time.Since(lastSeen) > sessionTouchInterval {
+				if _, err := pool.Exec(r.Context(),
+					`UPDATE user_sessions SET last_seen_at = NOW(), ip_address = $2 WHERE id = $1`,
+					sessionID, ClientIP(r),
+				); err != nil {
+					log.Printf("[auth] failed to touch session %s: %v", sessionID, err)
+				}
+			}
+
 			ctx := context.WithValue(r.Context(), ctxkeys.UserID, userID)
 			ctx = context.WithValue(ctx, ctxkeys.UserRole, role)
+			ctx = context.WithValue(ctx, ctxkeys.SessionID, sessionID)
+			if impersonator != "" {
+				ctx = context.WithValue(ctx, ctxkeys.ImpersonatorID, impersonator)
+			}
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// RequireMinRole returns middleware that restricts access to users with at least
-// the specified role level. Role hierarchy: super_admin > admin > company_owner > viewer.
-func RequireMinRole(minRole string) func(http.Handler) http.Handler {
-	minLevel := ctxkeys.RoleLevel[minRole]
-
+// LoadPermissions resolves the effective permissions of the user's role and
+// injects them into the request context. super_admin always holds every
+// permission; other roles read role_permissions, falling back to the built-in
+// defaults if the table can't be queried. Must be used after Auth middleware.
+func LoadPermissions(pool *pgxpool.Pool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
-			level := ctxkeys.RoleLevel[userRole]
+			role, _ := r.Context().Value(ctxkeys.UserRole).(string)
 
-			if level < minLevel {
-				writeError(w, http.StatusForbidden, "Insufficient permissions")
-				return
+			var perms []string
+			if role == permissions.SuperAdminRole {
+				perms = permissions.Keys()
+			} else {
+				rows, err := pool.Query(r.Context(),
+					`SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission`, role)
+				if err != nil {
+					log.Printf("[permissions] failed to query role_permissions for %s: %v", role, err)
+					perms = permissions.Defaults[role]
+				} else {
+					for rows.Next() {
+						var p string
+						if err := rows.Scan(&p); err != nil {
+							continue
+						}
+						perms = append(perms, p)
+					}
+					rows.Close()
+				}
+			}
+			if perms == nil {
+				perms = []string{}
+			}
+
+			ctx := context.WithValue(r.Context(), ctxkeys.Permissions, perms)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission returns middleware that restricts access to users holding
+// all of the given permissions. Must be used after LoadPermissions.
+func RequirePermission(perms ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range perms {
+				if !ctxkeys.HasPermission(r.Context(), p) {
+					writeError(w, http.StatusForbidden, "Insufficient permissions")
+					return
+				}
 			}
 
 			next.ServeHTTP(w, r)
@@ -88,14 +170,20 @@ func RequireMinRole(minRole string) func(http.Handler) http.Handler {
 }
 
 // InjectCompanyScope queries user_companies and injects the accessible company IDs
-// into the request context. For admin/super_admin the scope is nil (all companies).
-// Must be used after Auth middleware.
+// into the request context. For users holding companies.all the scope is nil
+// (all companies). Company-restricted API keys narrow the scope further to the
+// key's companies. Must be used after LoadPermissions.
 func InjectCompanyScope(pool *pgxpool.Pool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userRole, _ := r.Context().Value(ctxkeys.UserRole).(string)
+			keyCompanies, keyRestricted := r.Context().Value(ctxkeys.APIKeyCompanies).([]string)
 
-			if userRole == "admin" || userRole == "super_admin" {
+			if ctxkeys.HasPermission(r.Context(), permissions.CompaniesAll) {
+				if keyRestricted {
+					ctx := context.WithValue(r.Context(), ctxkeys.CompanyScope, keyCompanies)
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -119,6 +207,9 @@ func InjectCompanyScope(pool *pgxpool.Pool) func(http.Handler) http.Handler {
 				}
 				ids = append(ids, id)
 			}
+			if keyRestricted {
+				ids = intersect(ids, keyCompanies)
+			}
 			if ids == nil {
 				ids = []string{}
 			}
@@ -129,6 +220,37 @@ func InjectCompanyScope(pool *pgxpool.Pool) func(http.Handler) http.Handler {
 	}
 }
 
+// RequireDirectLogin blocks requests made with an API key or an impersonation
+// token. Used on account and credential management routes that must be
+// driven by the signed-in person themselves.
+func RequireDirectLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctxkeys.IsAPIKey(r.Context()) {
+			writeError(w, http.StatusForbidden, "This endpoint is not available to API keys")
+			return
+		}
+		if ctxkeys.IsImpersonating(r.Context()) {
+			writeError(w, http.StatusForbidden, "This action is blocked while impersonating")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func intersect(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, v := range b {
+		in[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if in[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func writeError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
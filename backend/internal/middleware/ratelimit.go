@@ -72,7 +72,7 @@ func RateLimit(r rate.Limit, burst int) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := extractIP(r)
+			ip := ClientIP(r)
 			limiter := ipl.getLimiter(ip)
 
 			if !limiter.Allow() {
@@ -90,8 +90,8 @@ func RateLimit(r rate.Limit, burst int) func(http.Handler) http.Handler {
 	}
 }
 
-// extractIP gets the client IP, respecting X-Forwarded-For from reverse proxies (Render).
-func extractIP(r *http.Request) string {
+// ClientIP gets the client IP, respecting X-Forwarded-For from reverse proxies (Render).
+func ClientIP(r *http.Request) string {
 	// Render (and most proxies) set X-Forwarded-For
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		// Take the first IP (the original client)
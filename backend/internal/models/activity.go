@@ -1,15 +1,42 @@
 package models
 
+import "time"
+
 // ActivityLog records who changed what and when.
 // The Details field uses JSONB to flexibly store change specifics
 // (e.g., which fields were modified, old vs new values).
 type ActivityLog struct {
-	ID         string      `json:"id"`
-	UserID     string      `json:"userId"`
-	UserName   string      `json:"userName,omitempty"`
-	Action     string      `json:"action"`     // "create", "update", "delete"
-	EntityType string      `json:"entityType"` // "employee", "document", "company"
-	EntityID   string      `json:"entityId"`
-	Details    interface{} `json:"details,omitempty"` // Flexible JSON  data
-	CreatedAt  string      `json:"createdAt"`
+	ID       string `json:"id"`
+	UserID   string `json:"userId"`
+	UserName string `json:"userName,omitempty"`
+	// Set when the action was taken by a super admin impersonating UserID
+	ImpersonatorID   *string     `json:"impersonatorId,omitempty"`
+	ImpersonatorName *string     `json:"impersonatorName,omitempty"`
+	Action           string      `json:"action"`     // "create", "update", "delete"
+	EntityType       string      `json:"entityType"` // "employee", "document", "company"
+	EntityID         string      `json:"entityId"`
+	Details          interface{} `json:"details,omitempty"` // Flexible JSON  data
+	CreatedAt        string      `json:"createdAt"`
+}
+
+// FieldChange is one field of an edit, with its values before and after as
+// text; nil stands for an empty (NULL) value.
+type FieldChange struct {
+	Field string  `json:"field"` // column name, e.g. "expiry_date"
+	From  *string `json:"from"`
+	To    *string `json:"to"`
+}
+
+// ChangeSet is the fields one edit of an employee or document changed.
+type ChangeSet struct {
+	ID               string        `json:"id"`
+	EntityType       string        `json:"entityType"` // "employee", "document"
+	EntityID         string        `json:"entityId"`
+	Action           string        `json:"action"` // "updated"
+	Changes          []FieldChange `json:"changes"`
+	UserID           *string       `json:"userId"`
+	UserName         *string       `json:"userName,omitempty"`
+	ImpersonatorID   *string       `json:"impersonatorId,omitempty"`
+	ImpersonatorName *string       `json:"impersonatorName,omitempty"`
+	CreatedAt        time.Time     `json:"createdAt"`
 }
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential for scripts. It acts as its owner,
+// narrowed to read or write access and to a set of companies.
+// The secret itself is never stored or returned after creation.
+type APIKey struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Prefix       string   `json:"prefix"` // first characters of the key, for recognising it
+	Access       string   `json:"access"` // read, write
+	AllCompanies bool     `json:"allCompanies"`
+	CompanyIDs   []string `json:"companyIds"`
+	ExpiresAt    *string  `json:"expiresAt,omitempty"`
+	LastUsedAt   *string  `json:"lastUsedAt,omitempty"`
+	LastUsedIP   *string  `json:"lastUsedIp,omitempty"`
+	CreatedAt    string   `json:"createdAt"`
+}
+
+// CreatedAPIKey is returned once, on creation, with the plaintext key.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest defines a new API key. Either allCompanies or at least
+// one company ID is required. ExpiresAt is an optional YYYY-MM-DD date.
+type CreateAPIKeyRequest struct {
+	Name         string   `json:"name"`
+	Access       string   `json:"access"`
+	AllCompanies bool     `json:"allCompanies"`
+	CompanyIDs   []string `json:"companyIds"`
+	ExpiresAt    *string  `json:"expiresAt,omitempty"`
+}
+
+// Validate checks the name, access level, company restriction and expiry.
+func (r *CreateAPIKeyRequest) Validate() map[string]string {
+	errors := map[string]string{}
+	if r.Name == "" || len(r.Name) > 100 {
+		errors["name"] = "Name is required (max 100 characters)"
+	}
+	if r.Access != "read" && r.Access != "write" {
+		errors["access"] = "Access must be 'read' or 'write'"
+	}
+	if !r.AllCompanies && len(r.CompanyIDs) == 0 {
+		errors["companyIds"] = "Select at least one company or allow all companies"
+	}
+	if r.ExpiresAt != nil && *r.ExpiresAt != "" {
+		t, err := time.Parse("2006-01-02", *r.ExpiresAt)
+		if err != nil {
+			errors["expiresAt"] = "Expiry must be a date in YYYY-MM-DD format"
+		} else if !t.After(time.Now()) {
+			errors["expiresAt"] = "Expiry must be in the future"
+		}
+	}
+	return errors
+}
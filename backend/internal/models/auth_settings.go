@@ -0,0 +1,23 @@
+package models
+
+// AuthSettings describes how users can sign in.
+type AuthSettings struct {
+	PasswordLoginEnabled bool   `json:"passwordLoginEnabled"`
+	SSOEnabled           bool   `json:"ssoEnabled"` // an identity provider is configured
+	SSOName              string `json:"ssoName,omitempty"`
+	SSOLoginURL          string `json:"ssoLoginUrl,omitempty"`
+}
+
+// UpdateAuthSettingsRequest toggles the password-login fallback.
+type UpdateAuthSettingsRequest struct {
+	PasswordLoginEnabled *bool `json:"passwordLoginEnabled"`
+}
+
+// Validate checks that a setting was provided.
+func (r *UpdateAuthSettingsRequest) Validate() map[string]string {
+	errors := map[string]string{}
+	if r.PasswordLoginEnabled == nil {
+		errors["passwordLoginEnabled"] = "passwordLoginEnabled is required"
+	}
+	return errors
+}
@@ -16,14 +16,37 @@ type Document struct {
 	IssueDate      *string `json:"issueDate"`      // when the document was issued
 	ExpiryDate     *string `json:"expiryDate"`     // nullable — nil means no expiry set yet
 
-	IsMandatory bool            `json:"isMandatory"` // computed from document_types table, not stored
-	Metadata    json.RawMessage `json:"metadata"`    // type-specific fields (JSONB)
-	FileURL     string          `json:"fileUrl"`
-	FileName    string          `json:"fileName"`
-	FileSize    int64           `json:"fileSize"`
-	FileType    string          `json:"fileType"`
-	LastUpdated time.Time       `json:"lastUpdated"`
-	CreatedAt   time.Time       `json:"createdAt"`
+	IsMandatory  bool            `json:"isMandatory"` // computed from document_types table, not stored
+	Metadata     json.RawMessage `json:"metadata"`    // type-specific fields (JSONB)
+	FileURL      string          `json:"fileUrl"`
+	FileName     string          `json:"fileName"`
+	FileSize     int64           `json:"fileSize"`
+	FileType     string          `json:"fileType"`
+	FileSHA256   *string         `json:"fileSha256,omitempty"`   // nil for files uploaded before content addressing
+	ThumbnailURL *string         `json:"thumbnailUrl,omitempty"` // signed; only for callers who may download the file
+	PreviewURL   *string         `json:"previewUrl,omitempty"`
+	LastUpdated  time.Time       `json:"lastUpdated"`
+	CreatedAt    time.Time       `json:"createdAt"`
+
+	// Every attached file, in order. FileURL … FileSHA256 above mirror the
+	// first one, so single-file consumers keep working.
+	Files []DocumentFile `json:"files,omitempty"`
+}
+
+// DocumentFile is one attachment of a document: a side of a card, a page
+// of a contract, etc.
+type DocumentFile struct {
+	ID           string    `json:"id"`
+	Position     int       `json:"position"` // 0-based; position 0 is the document's primary file
+	Label        *string   `json:"label"`    // e.g. "Front", "Back", "Page 2"
+	FileURL      string    `json:"fileUrl"`
+	FileName     string    `json:"fileName"`
+	FileSize     int64     `json:"fileSize"`
+	FileType     string    `json:"fileType"`
+	FileSHA256   *string   `json:"fileSha256,omitempty"`
+	ThumbnailURL *string   `json:"thumbnailUrl,omitempty"`
+	PreviewURL   *string   `json:"previewUrl,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
 }
 
 // ── Document with Computed Compliance Fields ─────────────────────
@@ -64,6 +87,10 @@ type CreateDocumentRequest struct {
 	FileName       string          `json:"fileName"`
 	FileSize       int64           `json:"fileSize"`
 	FileType       string          `json:"fileType"`
+
+	// Files attaches several files at once (e.g. front and back) instead of
+	// the single FileURL.
+	Files []AddDocumentFileRequest `json:"files,omitempty"`
 }
 
 // UpdateDocumentRequest holds the fields that can be partially updated.
@@ -89,6 +116,112 @@ func (r *CreateDocumentRequest) Validate() map[string]string {
 	}
 	// File fields are only required for non-mandatory (ad-hoc) documents.
 	// Mandatory slots are created without files initially.
+	if r.FileURL != "" && len(r.Files) > 0 {
+		errors["files"] = "Use either fileUrl or files, not both"
+	}
+	validateFiles(r.Files, errors)
+
+	return errors
+}
+
+// ── Attachments ──────────────────────────────────────────────────
+
+// MaxDocumentFiles caps the attachments of one document.
+const MaxDocumentFiles = 20
+
+// AddDocumentFileRequest attaches an uploaded file to a document. Position
+// inserts it before the file currently there; by default it is appended.
+type AddDocumentFileRequest struct {
+	FileURL  string  `json:"fileUrl"`
+	FileName string  `json:"fileName"`
+	FileSize int64   `json:"fileSize"`
+	FileType string  `json:"fileType"`
+	Label    *string `json:"label,omitempty"`
+	Position *int    `json:"position,omitempty"`
+}
+
+// Validate checks the add-file request.
+func (r *AddDocumentFileRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+	validateFiles([]AddDocumentFileRequest{*r}, errors)
+	if r.Position != nil && *r.Position < 0 {
+		errors["position"] = "Position must not be negative"
+	}
+	return errors
+}
+
+func validateFiles(files []AddDocumentFileRequest, errors map[string]string) {
+	if len(files) > MaxDocumentFiles {
+		errors["files"] = "At most 20 files per document"
+	}
+	for _, f := range files {
+		if f.FileURL == "" {
+			errors["fileUrl"] = "File is required"
+		}
+		if f.Label != nil && len(*f.Label) > 50 {
+			errors["label"] = "Label must be at most 50 characters"
+		}
+	}
+}
+
+// ReorderDocumentFilesRequest lists every file of a document in its new order.
+type ReorderDocumentFilesRequest struct {
+	FileIDs []string `json:"fileIds"`
+}
+
+// ── Archive ──────────────────────────────────────────────────────
+
+// MaxArchiveEmployees caps the employee IDs one archive request may list.
+const MaxArchiveEmployees = 500
+
+// DocumentArchiveRequest selects the documents for a ZIP download. Filters
+// combine with AND; at least one of EmployeeIDs or CompanyID is required so
+// that nobody downloads every file by accident.
+type DocumentArchiveRequest struct {
+	EmployeeIDs   []string `json:"employeeIds,omitempty"`
+	CompanyID     string   `json:"companyId,omitempty"`
+	DocumentTypes []string `json:"documentTypes,omitempty"`
+	Statuses      []string `json:"statuses,omitempty"` // computed compliance status, e.g. "expiring_soon"
+}
+
+// archiveStatuses are the statuses an archive can filter on.
+var archiveStatuses = map[string]bool{
+	"valid": true, "expiring_soon": true, "in_grace": true, "penalty_active": true, "incomplete": true,
+}
+
+// Validate checks the archive request.
+func (r *DocumentArchiveRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if len(r.EmployeeIDs) == 0 && r.CompanyID == "" {
+		errors["employeeIds"] = "Select employees or a company"
+	}
+	if len(r.EmployeeIDs) > MaxArchiveEmployees {
+		errors["employeeIds"] = "At most 500 employees per archive"
+	}
+	for _, s := range r.Statuses {
+		if !archiveStatuses[s] {
+			errors["statuses"] = "Unknown status: " + s
+			break
+		}
+	}
 
 	return errors
 }
+
+// ── Download log ─────────────────────────────────────────────────
+
+// DocumentDownload is one entry in a document's download log. Reference,
+// Recipient and Purpose are set for issued (watermarked) copies.
+type DocumentDownload struct {
+	ID         string    `json:"id"`
+	FileID     *string   `json:"fileId,omitempty"` // nil when every attachment was downloaded
+	UserID     *string   `json:"userId"`
+	UserName   *string   `json:"userName,omitempty"`
+	Reference  *string   `json:"reference,omitempty"`
+	Recipient  *string   `json:"recipient,omitempty"`
+	Purpose    *string   `json:"purpose,omitempty"`
+	Redactions int       `json:"redactions"`
+	CopySHA256 string    `json:"copySha256"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -11,6 +11,8 @@ type Employee struct {
 	Mobile          string    `json:"mobile"`
 	JoiningDate     string    `json:"joiningDate"`
 	PhotoURL        *string   `json:"photoUrl"`
+	ThumbnailURL    *string   `json:"thumbnailUrl,omitempty"` // signed, derived from the photo
+	PreviewURL      *string   `json:"previewUrl,omitempty"`
 	Gender          *string   `json:"gender,omitempty"`
 	DateOfBirth     *string   `json:"dateOfBirth,omitempty"`
 	Nationality     *string   `json:"nationality,omitempty"`
@@ -103,3 +105,65 @@ func (r *CreateEmployeeRequest) Validate() map[string]string {
 
 	return errors
 }
+
+// ── Transfers ────────────────────────────────────────────────────
+
+// TransferEmployeeRequest moves an employee to another company from
+// EffectiveDate (YYYY-MM-DD, not in the future) on.
+type TransferEmployeeRequest struct {
+	CompanyID     string `json:"companyId"`
+	EffectiveDate string `json:"effectiveDate"`
+	Reason        string `json:"reason"`
+}
+
+// Validate checks the transfer request.
+func (r *TransferEmployeeRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if r.CompanyID == "" {
+		errors["companyId"] = "Company is required"
+	}
+	if d, err := time.Parse("2006-01-02", r.EffectiveDate); err != nil {
+		errors["effectiveDate"] = "Effective date is required (YYYY-MM-DD)"
+	} else if d.After(time.Now()) {
+		errors["effectiveDate"] = "Effective date cannot be in the future"
+	}
+	if len(r.Reason) < 3 || len(r.Reason) > 500 {
+		errors["reason"] = "Reason must be between 3 and 500 characters"
+	}
+
+	return errors
+}
+
+// EmploymentPeriod is a stretch of time an employee belonged to a company,
+// from StartDate up to (not including) EndDate.
+type EmploymentPeriod struct {
+	ID                string    `json:"id"`
+	EmployeeID        string    `json:"employeeId"`
+	CompanyID         string    `json:"companyId"`
+	CompanyName       string    `json:"companyName"`
+	StartDate         string    `json:"startDate"`
+	EndDate           *string   `json:"endDate"` // nil for the current company
+	Reason            *string   `json:"reason"`  // why the employee moved here; nil for the first period
+	TransferredBy     *string   `json:"transferredBy"`
+	TransferredByName *string   `json:"transferredByName,omitempty"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// RuleChange is a compliance rule of one of the employee's document types
+// that differs between the old and the new company.
+type RuleChange struct {
+	DocumentType string `json:"documentType"`
+	Field        string `json:"field"` // gracePeriodDays, finePerDay, fineType, fineCap, isMandatory
+	From         string `json:"from"`
+	To           string `json:"to"`
+}
+
+// TransferResult is the response of a transfer.
+type TransferResult struct {
+	Employee           Employee           `json:"employee"`
+	History            []EmploymentPeriod `json:"history"`
+	RuleChanges        []RuleChange       `json:"ruleChanges"`        // how the employee's documents are now judged
+	AddedDocuments     []string           `json:"addedDocuments"`     // mandatory slots the new company requires
+	SalaryRecordsMoved int64              `json:"salaryRecordsMoved"` // months from the effective date on, now counted for the new company
+}
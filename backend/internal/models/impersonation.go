@@ -0,0 +1,32 @@
+package models
+
+// Impersonation limits, in minutes.
+const (
+	DefaultImpersonationMinutes = 30
+	MaxImpersonationMinutes     = 120
+)
+
+// ImpersonateRequest starts a support session as another user.
+type ImpersonateRequest struct {
+	Reason  string `json:"reason"`
+	Minutes int    `json:"minutes"` // 0 = DefaultImpersonationMinutes
+}
+
+// Validate checks the reason and duration.
+func (r *ImpersonateRequest) Validate() map[string]string {
+	errors := map[string]string{}
+	if len(r.Reason) < 5 {
+		errors["reason"] = "Reason is required (min 5 characters), e.g. a ticket reference"
+	}
+	if r.Minutes < 0 || r.Minutes > MaxImpersonationMinutes {
+		errors["minutes"] = "Minutes must be between 1 and 120"
+	}
+	return errors
+}
+
+// ImpersonationResponse carries the short-lived token for the impersonated user.
+type ImpersonationResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+	User      User   `json:"user"`
+}
@@ -0,0 +1,56 @@
+package models
+
+// MaxImportRows caps the data rows of one spreadsheet import.
+const MaxImportRows = 2000
+
+// ImportColumn maps a column of an uploaded sheet to a field. Field is
+// empty for columns that are ignored.
+type ImportColumn struct {
+	Header string `json:"header"`
+	Field  string `json:"field"`
+}
+
+// ImportRow is the outcome of one data row of an import.
+type ImportRow struct {
+	Row      int                     `json:"row"`                // line in the sheet; the header is line 1
+	Label    string                  `json:"label,omitempty"`    // e.g. the employee's name
+	Status   string                  `json:"status"`             // "valid" (not saved), "created", "updated", "unchanged", "error"
+	ID       *string                 `json:"id,omitempty"`       // the record created or updated
+	Errors   map[string]string       `json:"errors,omitempty"`   // field → message, as in a 422
+	Warnings []string                `json:"warnings,omitempty"` // saved anyway
+	Changes  map[string]ImportChange `json:"changes,omitempty"`  // field → old and new value, for updates
+	Files    []string                `json:"files,omitempty"`    // scans attached from the ZIP
+}
+
+// ImportChange is the old and new value of a field changed by an import.
+type ImportChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ImportReport is the response of an import, dry run or not.
+type ImportReport struct {
+	DryRun    bool           `json:"dryRun"`
+	Columns   []ImportColumn `json:"columns"` // the mapping applied, in sheet order
+	Fields    []string       `json:"fields"`  // every field a column can map to
+	Total     int            `json:"total"`
+	Valid     int            `json:"valid"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Failed    int            `json:"failed"`
+	Rows      []ImportRow    `json:"rows"`
+
+	// Document imports: scans in the ZIP that no row took, and the
+	// mandatory documents of the employees in the sheet that are still
+	// incomplete (no number or expiry date) once the rows are applied.
+	UnmatchedFiles []string            `json:"unmatchedFiles,omitempty"`
+	Outstanding    []ImportOutstanding `json:"outstanding,omitempty"`
+}
+
+// ImportOutstanding lists an employee's incomplete mandatory documents.
+type ImportOutstanding struct {
+	EmployeeID    string   `json:"employeeId"`
+	Name          string   `json:"name"`
+	DocumentTypes []string `json:"documentTypes"`
+}
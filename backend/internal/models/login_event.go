@@ -0,0 +1,14 @@
+package models
+
+// LoginEvent records a single sign-in attempt, successful or not.
+type LoginEvent struct {
+	ID        string  `json:"id"`
+	UserID    *string `json:"userId,omitempty"` // nil when the email matched no account
+	UserName  *string `json:"userName,omitempty"`
+	Email     string  `json:"email"`
+	IPAddress string  `json:"ipAddress"`
+	UserAgent string  `json:"userAgent"`
+	Success   bool    `json:"success"`
+	Reason    string  `json:"reason"` // success, sso, invalid_password, unknown_email, account_locked, password_disabled, sso_no_account
+	CreatedAt string  `json:"createdAt"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"regexp"
+
+	"manpower-backend/internal/permissions"
+)
+
+// Role is a named set of permissions that can be assigned to users.
+// System roles (viewer, company_owner, admin, super_admin) can be edited but not deleted.
+type Role struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName"`
+	Description string   `json:"description"`
+	IsSystem    bool     `json:"isSystem"`
+	Permissions []string `json:"permissions"`
+	UserCount   int      `json:"userCount"`
+	CreatedAt   string   `json:"createdAt"`
+	UpdatedAt   string   `json:"updatedAt"`
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// CreateRoleRequest is used by admins to define a custom role.
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// Validate checks the role slug, display name and permission keys.
+func (r *CreateRoleRequest) Validate() map[string]string {
+	errors := map[string]string{}
+	if !roleNamePattern.MatchString(r.Name) {
+		errors["name"] = "Name must be 2-50 lowercase letters, digits or underscores, starting with a letter"
+	}
+	if len(r.DisplayName) < 2 {
+		errors["displayName"] = "Display name is required (min 2 characters)"
+	}
+	if msg := validatePermissionKeys(r.Permissions); msg != "" {
+		errors["permissions"] = msg
+	}
+	return errors
+}
+
+// UpdateRoleDefinitionRequest edits a role's labels and/or replaces its permission set.
+type UpdateRoleDefinitionRequest struct {
+	DisplayName *string   `json:"displayName,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Permissions *[]string `json:"permissions,omitempty"`
+}
+
+// Validate checks the optional fields that were provided.
+func (r *UpdateRoleDefinitionRequest) Validate() map[string]string {
+	errors := map[string]string{}
+	if r.DisplayName != nil && len(*r.DisplayName) < 2 {
+		errors["displayName"] = "Display name must be at least 2 characters"
+	}
+	if r.Permissions != nil {
+		if msg := validatePermissionKeys(*r.Permissions); msg != "" {
+			errors["permissions"] = msg
+		}
+	}
+	return errors
+}
+
+func validatePermissionKeys(keys []string) string {
+	for _, k := range keys {
+		if !permissions.IsValid(k) {
+			return "Unknown permission '" + k + "'"
+		}
+	}
+	return ""
+}
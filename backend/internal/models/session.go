@@ -0,0 +1,14 @@
+package models
+
+// Session is a signed-in device. Each login creates one; revoking it
+// invalidates the JWT that carries its ID.
+type Session struct {
+	ID         string `json:"id"`
+	IPAddress  string `json:"ipAddress"` // last seen from
+	UserAgent  string `json:"userAgent"`
+	Device     string `json:"device"` // e.g. "Chrome on Windows"
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"` // true for the session making the request
+}
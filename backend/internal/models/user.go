@@ -3,13 +3,14 @@ package models
 // User represents an authenticated user in the system.
 // Each user owns companies and their employees/documents.
 type User struct {
-	ID           string `json:"id"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"-"` // Never expose in JSON responses
-	Name         string `json:"name"`
-	Role         string `json:"role"`
-	CreatedAt    string `json:"createdAt"`
-	UpdatedAt    string `json:"updatedAt"`
+	ID           string  `json:"id"`
+	Email        string  `json:"email"`
+	PasswordHash string  `json:"-"` // Never expose in JSON responses
+	Name         string  `json:"name"`
+	Role         string  `json:"role"`
+	LockedUntil  *string `json:"lockedUntil,omitempty"` // set while the account is locked out
+	CreatedAt    string  `json:"createdAt"`
+	UpdatedAt    string  `json:"updatedAt"`
 }
 
 // RegisterRequest contains the fields needed to create a new account.
@@ -25,12 +26,12 @@ type UpdateRoleRequest struct {
 	Role string `json:"role"`
 }
 
-// Validate checks that the role is one of the allowed values.
+// Validate checks that a role was given. Whether it exists is checked
+// against the roles table by the handler, since roles are admin-editable.
 func (r *UpdateRoleRequest) Validate() map[string]string {
 	errors := map[string]string{}
-	valid := map[string]bool{"viewer": true, "company_owner": true, "admin": true, "super_admin": true}
-	if !valid[r.Role] {
-		errors["role"] = "Role must be 'viewer', 'company_owner', 'admin', or 'super_admin'"
+	if r.Role == "" {
+		errors["role"] = "Role is required"
 	}
 	return errors
 }
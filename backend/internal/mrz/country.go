@@ -0,0 +1,35 @@
+package mrz
+
+import (
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// icaoCodes maps the MRZ codes that are not ISO 3166 alpha-3 codes to the
+// country they stand for.
+var icaoCodes = map[string]string{
+	"D":   "DEU", // Germany
+	"GBD": "GBR", // British Overseas Territories citizen
+	"GBN": "GBR", // British National (Overseas)
+	"GBO": "GBR", // British Overseas citizen
+	"GBP": "GBR", // British protected person
+	"GBS": "GBR", // British subject
+}
+
+// CountryName returns the English name of the country an MRZ state or
+// nationality code stands for, e.g. "IND" → "India". Codes without a
+// country (UN documents, stateless persons) are returned unchanged.
+func CountryName(code string) string {
+	iso := code
+	if c, ok := icaoCodes[code]; ok {
+		iso = c
+	}
+	region, err := language.ParseRegion(iso)
+	if err != nil || !region.IsCountry() {
+		return code
+	}
+	if name := display.English.Regions().Name(region); name != "" {
+		return name
+	}
+	return code
+}
@@ -0,0 +1,37 @@
+package mrz
+
+import "strings"
+
+// EmiratesID returns the holder's Emirates ID number, formatted
+// 784-YYYY-NNNNNNN-C, from a UAE identity card's MRZ. The 15-digit number
+// sits in the TD1 optional data and ends with a Luhn check digit; ok is
+// false when the MRZ is not a UAE card or the number does not verify.
+func (r *Result) EmiratesID() (string, bool) {
+	if r.Format != TD1 || r.IssuingState != "ARE" {
+		return "", false
+	}
+	n := numeric(strings.SplitN(r.OptionalData, "<", 2)[0])
+	if len(n) != 15 || !strings.HasPrefix(n, "784") || !luhn(n) {
+		return "", false
+	}
+	return n[0:3] + "-" + n[3:7] + "-" + n[7:14] + "-" + n[14:15], true
+}
+
+// luhn reports whether the digit string s passes the Luhn check.
+func luhn(s string) bool {
+	sum := 0
+	for i := 0; i < len(s); i++ {
+		d := s[len(s)-1-i]
+		if d < '0' || d > '9' {
+			return false
+		}
+		v := int(d - '0')
+		if i%2 == 1 {
+			if v *= 2; v > 9 {
+				v -= 9
+			}
+		}
+		sum += v
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,374 @@
+// Package mrz parses the machine readable zone of travel documents
+// (ICAO Doc 9303): passports (TD3, 2×44), the older TD2 cards (2×36) and ID
+// cards such as the Emirates ID (TD1, 3×30).
+//
+// Input is OCR or PDF text, so Parse looks for the MRZ among other lines,
+// ignores spaces, and undoes the usual letter/digit confusions in fields
+// whose character class is fixed. Every check digit is verified and
+// reported; a failed check does not stop parsing, since the caller decides
+// how far to trust the values.
+package mrz
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Document formats.
+const (
+	TD1 = "TD1"
+	TD2 = "TD2"
+	TD3 = "TD3"
+)
+
+// ErrNotFound means no MRZ was found in the text.
+var ErrNotFound = errors.New("no machine readable zone found")
+
+// Checks reports the result of each check digit. OptionalData is true when
+// the format has no check digit for it, or the optional data is empty.
+type Checks struct {
+	DocumentNumber bool `json:"documentNumber"`
+	BirthDate      bool `json:"birthDate"`
+	ExpiryDate     bool `json:"expiryDate"`
+	OptionalData   bool `json:"optionalData"`
+	Composite      bool `json:"composite"`
+}
+
+// Result is a parsed MRZ. Dates are YYYY-MM-DD, or "" when the MRZ holds no
+// valid date.
+type Result struct {
+	Format         string   `json:"format"`
+	DocumentCode   string   `json:"documentCode"` // e.g. "P", "ID"
+	IssuingState   string   `json:"issuingState"`
+	Surname        string   `json:"surname"`
+	GivenNames     string   `json:"givenNames"`
+	DocumentNumber string   `json:"documentNumber"`
+	Nationality    string   `json:"nationality"`
+	BirthDate      string   `json:"birthDate"`
+	Sex            string   `json:"sex"` // "M", "F" or "" (unspecified)
+	ExpiryDate     string   `json:"expiryDate"`
+	OptionalData   string   `json:"optionalData"`
+	Lines          []string `json:"lines"`
+	Line           int      `json:"-"` // index of the first MRZ line in the input
+	Checks         Checks   `json:"checks"`
+}
+
+// Valid reports whether every check digit matched.
+func (r *Result) Valid() bool {
+	c := r.Checks
+	return c.DocumentNumber && c.BirthDate && c.ExpiryDate && c.OptionalData && c.Composite
+}
+
+// Name returns the holder's name as "Given Names Surname", title-cased.
+func (r *Result) Name() string {
+	return titleCase(strings.TrimSpace(r.GivenNames + " " + r.Surname))
+}
+
+// Parse finds and parses the MRZ in text lines. When several candidates are
+// present (e.g. a multi-page PDF), the one with the most passing checks wins.
+func Parse(lines []string) (*Result, error) {
+	cleaned := make([]string, len(lines))
+	for i, l := range lines {
+		cleaned[i] = clean(l)
+	}
+
+	var best *Result
+	score := -1
+	for i := range cleaned {
+		for _, f := range formats {
+			if i+f.lines > len(cleaned) {
+				continue
+			}
+			block, ok := f.match(cleaned[i : i+f.lines])
+			if !ok {
+				continue
+			}
+			r := f.parse(block)
+			r.Format, r.Lines, r.Line = f.name, block, i
+			if s := r.Checks.score(); s > score {
+				best, score = r, s
+			}
+		}
+	}
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	return best, nil
+}
+
+// ParseText splits text into lines and calls Parse.
+func ParseText(text string) (*Result, error) {
+	return Parse(strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n"))
+}
+
+func (c Checks) score() int {
+	n := 0
+	for _, ok := range []bool{c.DocumentNumber, c.BirthDate, c.ExpiryDate, c.OptionalData, c.Composite} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// ── Formats ────────────────────────────────────────────────────
+
+type format struct {
+	name   string
+	lines  int
+	width  int
+	prefix string // first character(s) the first line must start with
+	parse  func(block []string) *Result
+}
+
+// Longest first, so a TD3 line is not mistaken for a padded TD2 one.
+var formats = []format{
+	{TD3, 2, 44, "P", parseTD3},
+	{TD2, 2, 36, "", parseTD2},
+	{TD1, 3, 30, "", parseTD1},
+}
+
+// slack is how many trailing characters OCR may drop from a line; the
+// missing positions are almost always '<' filler.
+const slack = 2
+
+// match returns block padded to the format's width when every line has
+// the right length and looks like MRZ text.
+func (f format) match(block []string) ([]string, bool) {
+	out := make([]string, len(block))
+	for i, l := range block {
+		if len(l) > f.width || len(l) < f.width-slack || !strings.Contains(l, "<") {
+			return nil, false
+		}
+		out[i] = l + strings.Repeat("<", f.width-len(l))
+	}
+	// Document codes start with A, C, I or P (V for visas, which are not
+	// parsed here); the first line of TD1/TD2 carries no dates.
+	first := out[0][0]
+	if f.prefix != "" && !strings.HasPrefix(out[0], f.prefix) {
+		return nil, false
+	}
+	if first != 'A' && first != 'C' && first != 'I' && first != 'P' {
+		return nil, false
+	}
+	return out, true
+}
+
+func parseTD3(b []string) *Result {
+	l1, l2 := b[0], b[1]
+	r := &Result{
+		DocumentCode: code(l1[0:2]),
+		IssuingState: alpha(l1[2:5]),
+	}
+	r.Surname, r.GivenNames = names(l1[5:44])
+
+	number, numberCheck := l2[0:9], numeric(l2[9:10])
+	birth, birthCheck := numeric(l2[13:19]), numeric(l2[19:20])
+	expiry, expiryCheck := numeric(l2[21:27]), numeric(l2[27:28])
+	optional, optionalCheck := l2[28:42], numeric(l2[42:43])
+
+	r.DocumentNumber = filler(number)
+	r.Nationality = alpha(l2[10:13])
+	r.BirthDate = date(birth, false)
+	r.Sex = sex(l2[20:21])
+	r.ExpiryDate = date(expiry, true)
+	r.OptionalData = filler(optional)
+
+	r.Checks = Checks{
+		DocumentNumber: verify(number, numberCheck),
+		BirthDate:      verify(birth, birthCheck),
+		ExpiryDate:     verify(expiry, expiryCheck),
+		// An empty personal number may have '<' instead of a 0 check digit
+		OptionalData: verify(optional, optionalCheck) || (r.OptionalData == "" && l2[42] == '<'),
+		Composite: verify(number+numberCheck+birth+birthCheck+expiry+expiryCheck+optional+optionalCheck,
+			numeric(l2[43:44])),
+	}
+	return r
+}
+
+func parseTD2(b []string) *Result {
+	l1, l2 := b[0], b[1]
+	r := &Result{
+		DocumentCode: code(l1[0:2]),
+		IssuingState: alpha(l1[2:5]),
+	}
+	r.Surname, r.GivenNames = names(l1[5:36])
+
+	number, numberCheck := l2[0:9], numeric(l2[9:10])
+	birth, birthCheck := numeric(l2[13:19]), numeric(l2[19:20])
+	expiry, expiryCheck := numeric(l2[21:27]), numeric(l2[27:28])
+	optional := l2[28:35]
+
+	// A number longer than 9 characters continues in the optional data,
+	// with '<' in the check digit position
+	if numberCheck == "<" {
+		number, numberCheck, optional = extendNumber(number, optional)
+	}
+
+	r.DocumentNumber = filler(number)
+	r.Nationality = alpha(l2[10:13])
+	r.BirthDate = date(birth, false)
+	r.Sex = sex(l2[20:21])
+	r.ExpiryDate = date(expiry, true)
+	r.OptionalData = filler(optional)
+
+	r.Checks = Checks{
+		DocumentNumber: verify(number, numberCheck),
+		BirthDate:      verify(birth, birthCheck),
+		ExpiryDate:     verify(expiry, expiryCheck),
+		OptionalData:   true,
+		Composite:      verify(l2[0:10]+birth+birthCheck+expiry+expiryCheck+l2[28:35], numeric(l2[35:36])),
+	}
+	return r
+}
+
+func parseTD1(b []string) *Result {
+	l1, l2, l3 := b[0], b[1], b[2]
+	r := &Result{
+		DocumentCode: code(l1[0:2]),
+		IssuingState: alpha(l1[2:5]),
+	}
+	r.Surname, r.GivenNames = names(l3)
+
+	number, numberCheck := l1[5:14], numeric(l1[14:15])
+	optional := l1[15:30]
+	if numberCheck == "<" {
+		number, numberCheck, optional = extendNumber(number, optional)
+	}
+	birth, birthCheck := numeric(l2[0:6]), numeric(l2[6:7])
+	expiry, expiryCheck := numeric(l2[8:14]), numeric(l2[14:15])
+
+	r.DocumentNumber = filler(number)
+	r.Nationality = alpha(l2[15:18])
+	r.BirthDate = date(birth, false)
+	r.Sex = sex(l2[7:8])
+	r.ExpiryDate = date(expiry, true)
+	r.OptionalData = filler(strings.Trim(optional, "<") + "<" + strings.Trim(l2[18:29], "<"))
+
+	r.Checks = Checks{
+		DocumentNumber: verify(number, numberCheck),
+		BirthDate:      verify(birth, birthCheck),
+		ExpiryDate:     verify(expiry, expiryCheck),
+		OptionalData:   true,
+		// Everything but the sex and nationality
+		Composite: verify(l1[5:30]+birth+birthCheck+expiry+expiryCheck+l2[18:29], numeric(l2[29:30])),
+	}
+	return r
+}
+
+// extendNumber handles a document number longer than nine characters: the
+// rest of it, then its check digit, open the optional data field.
+func extendNumber(number, optional string) (string, string, string) {
+	rest, tail, _ := strings.Cut(optional, "<")
+	if rest == "" {
+		return number, "<", optional
+	}
+	return number + rest[:len(rest)-1], numeric(rest[len(rest)-1:]), tail
+}
+
+// ── Check digits ───────────────────────────────────────────────
+
+var weights = [3]int{7, 3, 1}
+
+// CheckDigit computes the ICAO 9303 check digit of s: digits count as
+// themselves, A–Z as 10–35 and '<' as 0, weighted 7, 3, 1 repeating.
+func CheckDigit(s string) byte {
+	sum := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		v := 0
+		switch {
+		case c >= '0' && c <= '9':
+			v = int(c - '0')
+		case c >= 'A' && c <= 'Z':
+			v = int(c-'A') + 10
+		}
+		sum += v * weights[i%3]
+	}
+	return byte('0' + sum%10)
+}
+
+func verify(s, check string) bool {
+	return len(check) == 1 && CheckDigit(s) == check[0]
+}
+
+// ── Character handling ─────────────────────────────────────────
+
+// clean uppercases a line and drops everything that cannot be MRZ text
+// (spaces OCR inserts between characters included). '«' and '‹' are read
+// as the '<' filler they usually are.
+func clean(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(s) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '<':
+			b.WriteRune(c)
+		case c == '«' || c == '‹':
+			b.WriteString("<")
+		}
+	}
+	return b.String()
+}
+
+var toDigit = strings.NewReplacer("O", "0", "Q", "0", "D", "0",
+	"I", "1", "L", "1", "Z", "2", "S", "5", "B", "8", "G", "6")
+
+var toLetter = strings.NewReplacer("0", "O", "1", "I", "2", "Z", "5", "S", "8", "B", "6", "G")
+
+// numeric undoes letter/digit confusions in a field that holds only digits
+// (dates and check digits). Filler is left as is.
+func numeric(s string) string { return toDigit.Replace(s) }
+
+// alpha undoes digit/letter confusions in a field that holds only letters.
+func alpha(s string) string { return strings.TrimRight(toLetter.Replace(s), "<") }
+
+func code(s string) string { return strings.TrimRight(alpha(s), "<") }
+
+func filler(s string) string { return strings.Trim(s, "<") }
+
+// names splits the name field at the first "<<" into surname and given
+// names.
+func names(s string) (string, string) {
+	s = toLetter.Replace(strings.TrimRight(s, "<"))
+	surname, given, _ := strings.Cut(s, "<<")
+	return spaced(surname), spaced(given)
+}
+
+func spaced(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == '<' }), " ")
+}
+
+func sex(s string) string {
+	switch s {
+	case "M", "F":
+		return s
+	}
+	return ""
+}
+
+// date converts YYMMDD. MRZ dates carry no century: expiry dates are taken
+// to be 2000–2069 (1970–1999 otherwise), birth dates the latest century
+// that does not put them in the future.
+func date(s string, expiry bool) string {
+	t, err := time.Parse("060102", s)
+	if err != nil {
+		return ""
+	}
+	y := t.Year() % 100
+	switch {
+	case expiry && y < 70, !expiry && y <= time.Now().Year()%100:
+		y += 2000
+	default:
+		y += 1900
+	}
+	return time.Date(y, t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
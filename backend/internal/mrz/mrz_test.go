@@ -0,0 +1,257 @@
+package mrz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ICAO 9303 specimens (Part 4 TD3, Part 5 TD1, Part 6 TD2).
+var (
+	td3 = []string{
+		"P<UTOERIKSSON<<ANNA<MARIA<<<<<<<<<<<<<<<<<<<",
+		"L898902C36UTO7408122F1204159ZE184226B<<<<<10",
+	}
+	td1 = []string{
+		"I<UTOD231458907<<<<<<<<<<<<<<<",
+		"7408122F1204159UTO<<<<<<<<<<<6",
+		"ERIKSSON<<ANNA<MARIA<<<<<<<<<<",
+	}
+	td2 = []string{
+		"I<UTOERIKSSON<<ANNA<MARIA<<<<<<<<<<<",
+		"D231458907UTO7408122F1204159<<<<<<<6",
+	}
+)
+
+// replace returns lines with line i's byte at pos set to c.
+func replace(lines []string, i, pos int, c byte) []string {
+	out := append([]string(nil), lines...)
+	b := []byte(out[i])
+	b[pos] = c
+	out[i] = string(b)
+	return out
+}
+
+// wrongDigit returns a check digit other than the one at lines[i][pos].
+func wrongDigit(lines []string, i, pos int) byte {
+	if lines[i][pos] == '9' {
+		return '0'
+	}
+	return lines[i][pos] + 1
+}
+
+func TestParseSpecimens(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  Result
+	}{
+		{"TD3", td3, Result{
+			Format: TD3, DocumentCode: "P", IssuingState: "UTO",
+			Surname: "ERIKSSON", GivenNames: "ANNA MARIA",
+			DocumentNumber: "L898902C3", Nationality: "UTO",
+			BirthDate: "1974-08-12", Sex: "F", ExpiryDate: "2012-04-15",
+			OptionalData: "ZE184226B",
+		}},
+		{"TD1", td1, Result{
+			Format: TD1, DocumentCode: "I", IssuingState: "UTO",
+			Surname: "ERIKSSON", GivenNames: "ANNA MARIA",
+			DocumentNumber: "D23145890", Nationality: "UTO",
+			BirthDate: "1974-08-12", Sex: "F", ExpiryDate: "2012-04-15",
+		}},
+		{"TD2", td2, Result{
+			Format: TD2, DocumentCode: "I", IssuingState: "UTO",
+			Surname: "ERIKSSON", GivenNames: "ANNA MARIA",
+			DocumentNumber: "D23145890", Nationality: "UTO",
+			BirthDate: "1974-08-12", Sex: "F", ExpiryDate: "2012-04-15",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.lines)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got := []string{r.Format, r.DocumentCode, r.IssuingState, r.Surname, r.GivenNames,
+				r.DocumentNumber, r.Nationality, r.BirthDate, r.Sex, r.ExpiryDate, r.OptionalData}
+			want := []string{tt.want.Format, tt.want.DocumentCode, tt.want.IssuingState, tt.want.Surname, tt.want.GivenNames,
+				tt.want.DocumentNumber, tt.want.Nationality, tt.want.BirthDate, tt.want.Sex, tt.want.ExpiryDate, tt.want.OptionalData}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("fields:\n got %q\nwant %q", got, want)
+			}
+			if !r.Valid() {
+				t.Errorf("checks = %+v, want all valid", r.Checks)
+			}
+			if name := r.Name(); name != "Anna Maria Eriksson" {
+				t.Errorf("Name() = %q, want %q", name, "Anna Maria Eriksson")
+			}
+		})
+	}
+}
+
+func TestParseCheckDigits(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		line, pos int
+		want      Checks
+	}{
+		{"TD3 document number", td3, 1, 9, Checks{BirthDate: true, ExpiryDate: true, OptionalData: true}},
+		{"TD3 birth date", td3, 1, 19, Checks{DocumentNumber: true, ExpiryDate: true, OptionalData: true}},
+		{"TD3 expiry date", td3, 1, 27, Checks{DocumentNumber: true, BirthDate: true, OptionalData: true}},
+		{"TD3 optional data", td3, 1, 42, Checks{DocumentNumber: true, BirthDate: true, ExpiryDate: true}},
+		{"TD3 composite", td3, 1, 43, Checks{DocumentNumber: true, BirthDate: true, ExpiryDate: true, OptionalData: true}},
+		{"TD1 document number", td1, 0, 14, Checks{BirthDate: true, ExpiryDate: true, OptionalData: true}},
+		{"TD1 birth date", td1, 1, 6, Checks{DocumentNumber: true, ExpiryDate: true, OptionalData: true}},
+		{"TD1 expiry date", td1, 1, 14, Checks{DocumentNumber: true, BirthDate: true, OptionalData: true}},
+		{"TD1 composite", td1, 1, 29, Checks{DocumentNumber: true, BirthDate: true, ExpiryDate: true, OptionalData: true}},
+		{"TD2 document number", td2, 1, 9, Checks{BirthDate: true, ExpiryDate: true, OptionalData: true}},
+		{"TD2 birth date", td2, 1, 19, Checks{DocumentNumber: true, ExpiryDate: true, OptionalData: true}},
+		{"TD2 expiry date", td2, 1, 27, Checks{DocumentNumber: true, BirthDate: true, OptionalData: true}},
+		{"TD2 composite", td2, 1, 35, Checks{DocumentNumber: true, BirthDate: true, ExpiryDate: true, OptionalData: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(replace(tt.lines, tt.line, tt.pos, wrongDigit(tt.lines, tt.line, tt.pos)))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if r.Checks != tt.want {
+				t.Errorf("checks = %+v, want %+v", r.Checks, tt.want)
+			}
+			if r.Valid() {
+				t.Error("Valid() = true, want false")
+			}
+		})
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want byte
+	}{
+		{"L898902C3", '6'},
+		{"740812", '2'},
+		{"120415", '9'},
+		{"ZE184226B<<<<<", '1'},
+		{"D23145890", '7'},
+		{"<<<", '0'},
+	}
+	for _, tt := range tests {
+		if got := CheckDigit(tt.in); got != tt.want {
+			t.Errorf("CheckDigit(%q) = %c, want %c", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseOCRNoise(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"spaces", []string{
+			"P<UTO ERIKSSON<<ANNA<MARIA <<<<<<<<<<<<<<<<<<<",
+			"L898902C3 6UTO 7408122 F 1204159 ZE184226B<<<<<10",
+		}},
+		{"O for 0 in numeric fields", []string{
+			td3[0],
+			"L898902C36UTO74O8122F12O4159ZE184226B<<<<<1O",
+		}},
+		{"lower case and angle quotes", []string{
+			"p<utoeriksson<<anna<maria«««««««««««««««««««",
+			strings.ToLower(td3[1]),
+		}},
+		{"dropped trailing fillers", []string{
+			"P<UTOERIKSSON<<ANNA<MARIA<<<<<<<<<<<<<<<<<",
+			td3[1],
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.lines)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if r.DocumentNumber != "L898902C3" || r.BirthDate != "1974-08-12" || r.ExpiryDate != "2012-04-15" {
+				t.Errorf("got number %q, birth %q, expiry %q", r.DocumentNumber, r.BirthDate, r.ExpiryDate)
+			}
+			if !r.Valid() {
+				t.Errorf("checks = %+v, want all valid", r.Checks)
+			}
+		})
+	}
+}
+
+func TestParseText(t *testing.T) {
+	text := "PASSPORT\nUtopia\n  Eriksson, Anna Maria\n\n" + strings.Join(td3, "\n") + "\n"
+	r, err := ParseText(text)
+	if err != nil {
+		t.Fatalf("ParseText: %v", err)
+	}
+	if r.Format != TD3 || r.DocumentNumber != "L898902C3" || !r.Valid() {
+		t.Errorf("got %+v", r)
+	}
+
+	for _, text := range []string{"", "PASSPORT\nUtopia\n", "P<UTOERIKSSON<<ANNA"} {
+		if _, err := ParseText(text); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ParseText(%q) error = %v, want ErrNotFound", text, err)
+		}
+	}
+}
+
+func TestDateWindowing(t *testing.T) {
+	yy := time.Now().Year() % 100
+	tests := []struct {
+		in     string
+		expiry bool
+		want   string
+	}{
+		{"740812", false, "1974-08-12"},
+		{"120415", true, "2012-04-15"},
+		{"000101", false, "2000-01-01"},
+		{fmt.Sprintf("%02d0101", yy), false, fmt.Sprintf("20%02d-01-01", yy)},
+		{fmt.Sprintf("%02d0101", (yy+1)%100), false, fmt.Sprintf("19%02d-01-01", (yy+1)%100)},
+		{"690101", true, "2069-01-01"},
+		{"700101", true, "1970-01-01"},
+		{"991231", false, "1999-12-31"},
+		{"0A0101", false, ""},
+	}
+	for _, tt := range tests {
+		if got := date(tt.in, tt.expiry); got != tt.want {
+			t.Errorf("date(%q, %v) = %q, want %q", tt.in, tt.expiry, got, tt.want)
+		}
+	}
+}
+
+func TestEmiratesID(t *testing.T) {
+	tests := []struct {
+		name   string
+		r      Result
+		want   string
+		wantOK bool
+	}{
+		{"valid", Result{Format: TD1, IssuingState: "ARE", OptionalData: "784198012345678"},
+			"784-1980-1234567-8", true},
+		{"valid with trailing optional data", Result{Format: TD1, IssuingState: "ARE", OptionalData: "784199007654322<ABC"},
+			"784-1990-0765432-2", true},
+		{"OCR letters in digits", Result{Format: TD1, IssuingState: "ARE", OptionalData: "784I98OI2345678"},
+			"784-1980-1234567-8", true},
+		{"bad Luhn digit", Result{Format: TD1, IssuingState: "ARE", OptionalData: "784198012345679"}, "", false},
+		{"transposed digits", Result{Format: TD1, IssuingState: "ARE", OptionalData: "784189012345678"}, "", false},
+		{"not 784", Result{Format: TD1, IssuingState: "ARE", OptionalData: "785198012345678"}, "", false},
+		{"short", Result{Format: TD1, IssuingState: "ARE", OptionalData: "78419801234567"}, "", false},
+		{"not UAE", Result{Format: TD1, IssuingState: "UTO", OptionalData: "784198012345678"}, "", false},
+		{"not TD1", Result{Format: TD3, IssuingState: "ARE", OptionalData: "784198012345678"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.r.EmiratesID()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("EmiratesID() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Package ocr reads text from document scans for field extraction.
+//
+// An Engine recognises text in images; Reader sits in front of it and
+// handles PDFs, using the text layer when the PDF has one (pdftotext) and
+// otherwise rasterising the first page (pdftoppm) for the engine. Both
+// poppler tools are optional.
+package ocr
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnavailable means no OCR engine is configured, so only PDFs with a
+// text layer can be read.
+var ErrUnavailable = errors.New("no OCR engine configured")
+
+// ErrUnsupported means the file is neither an image nor a PDF.
+var ErrUnsupported = errors.New("unsupported file type for text extraction")
+
+// Line is one line of recognised text. Confidence is between 0 and 1; text
+// from a PDF text layer has confidence 1.
+type Line struct {
+	Text       string
+	Confidence float64
+}
+
+// Engine recognises text in an image (PNG, JPEG, TIFF, …). Implementations
+// must be safe for concurrent use.
+type Engine interface {
+	Name() string
+	Recognize(ctx context.Context, image []byte) ([]Line, error)
+}
+
+// None is the engine used when OCR is disabled.
+type None struct{}
+
+// Name implements Engine.
+func (None) Name() string { return "none" }
+
+// Recognize implements Engine.
+func (None) Recognize(ctx context.Context, image []byte) ([]Line, error) {
+	return nil, ErrUnavailable
+}
@@ -0,0 +1,101 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// minTextLayer is how much text a PDF must yield before its text layer is
+// trusted; scanned PDFs often carry a few stray characters.
+const minTextLayer = 20
+
+// Reader extracts text lines from images and PDFs.
+type Reader struct {
+	engine    Engine
+	pdftotext string // "" skips PDF text layers
+	pdftoppm  string // "" leaves scanned PDFs unread
+}
+
+// NewReader creates a Reader. pdftotext and pdftoppm are the poppler
+// binaries to use (names are looked up in PATH); either may be missing.
+func NewReader(engine Engine, pdftotext, pdftoppm string) *Reader {
+	r := &Reader{engine: engine}
+	if p, err := exec.LookPath(pdftotext); pdftotext != "" && err == nil {
+		r.pdftotext = p
+	}
+	if p, err := exec.LookPath(pdftoppm); pdftoppm != "" && err == nil {
+		r.pdftoppm = p
+	}
+	return r
+}
+
+// Engine returns the OCR engine's name.
+func (r *Reader) Engine() string { return r.engine.Name() }
+
+// Read returns the text lines of an image or PDF, and where they came from:
+// "text-layer" or the engine's name.
+func (r *Reader) Read(ctx context.Context, data []byte) ([]Line, string, error) {
+	switch ct := http.DetectContentType(data); {
+	case ct == "application/pdf":
+		return r.readPDF(ctx, data)
+	case strings.HasPrefix(ct, "image/"):
+		lines, err := r.engine.Recognize(ctx, data)
+		return lines, r.engine.Name(), err
+	default:
+		return nil, "", ErrUnsupported
+	}
+}
+
+func (r *Reader) readPDF(ctx context.Context, data []byte) ([]Line, string, error) {
+	dir, err := os.MkdirTemp("", "ocr-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.pdf")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, "", err
+	}
+
+	// Text layer first: exact, and no OCR needed. The MRZ is on the first
+	// page of a passport scan but may follow a cover page.
+	if r.pdftotext != "" {
+		cmd := exec.CommandContext(ctx, r.pdftotext, "-layout", "-f", "1", "-l", "2", in, "-")
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, "", fmt.Errorf("pdftotext: %w", err)
+		}
+		if len(bytes.Join(bytes.Fields(out), nil)) >= minTextLayer {
+			var lines []Line
+			for _, l := range strings.Split(string(out), "\n") {
+				if strings.TrimSpace(l) != "" {
+					lines = append(lines, Line{Text: l, Confidence: 1})
+				}
+			}
+			return lines, "text-layer", nil
+		}
+	}
+
+	// A scan: rasterise the first page for the engine
+	if r.pdftoppm == "" {
+		return nil, "", ErrUnsupported
+	}
+	out := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, r.pdftoppm, "-f", "1", "-l", "1", "-singlefile", "-png", "-r", "300", in, out)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return nil, "", fmt.Errorf("pdftoppm: %v: %s", err, bytes.TrimSpace(msg))
+	}
+	page, err := os.ReadFile(out + ".png")
+	if err != nil {
+		return nil, "", err
+	}
+	lines, err := r.engine.Recognize(ctx, page)
+	return lines, r.engine.Name(), err
+}
@@ -0,0 +1,117 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Tesseract runs the tesseract command-line tool. Its TSV output gives
+// per-word confidences, which are averaged per line.
+type Tesseract struct {
+	path      string
+	languages string // -l argument, e.g. "eng" or "eng+mrz"
+}
+
+// NewTesseract creates a Tesseract engine. path is the binary (a name is
+// looked up in PATH); languages is passed as -l and defaults to "eng".
+func NewTesseract(path, languages string) (*Tesseract, error) {
+	p, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found: %w", err)
+	}
+	if languages == "" {
+		languages = "eng"
+	}
+	return &Tesseract{path: p, languages: languages}, nil
+}
+
+// Name implements Engine.
+func (t *Tesseract) Name() string { return "tesseract" }
+
+// Recognize implements Engine.
+func (t *Tesseract) Recognize(ctx context.Context, image []byte) ([]Line, error) {
+	f, err := os.CreateTemp("", "ocr-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(image)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, f.Name(), "stdout", "-l", t.languages, "--psm", "3", "tsv")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return parseTSV(&stdout)
+}
+
+// parseTSV groups tesseract's word rows (level 5) into lines. Columns:
+// level page_num block_num par_num line_num word_num left top width height
+// conf text.
+func parseTSV(r io.Reader) ([]Line, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = '\t'
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+
+	type acc struct {
+		words []string
+		conf  float64
+	}
+	var (
+		lines []Line
+		cur   *acc
+		curID string
+	)
+	flush := func() {
+		if cur != nil && len(cur.words) > 0 {
+			lines = append(lines, Line{
+				Text:       strings.Join(cur.words, " "),
+				Confidence: cur.conf / float64(len(cur.words)) / 100,
+			})
+		}
+		cur = nil
+	}
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tesseract output: %w", err)
+		}
+		if len(rec) < 12 || rec[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(rec[11])
+		conf, err := strconv.ParseFloat(rec[10], 64)
+		if text == "" || err != nil || conf < 0 {
+			continue
+		}
+		id := rec[1] + "." + rec[2] + "." + rec[3] + "." + rec[4]
+		if id != curID {
+			flush()
+			cur, curID = &acc{}, id
+		}
+		cur.words = append(cur.words, text)
+		cur.conf += conf
+	}
+	flush()
+	return lines, nil
+}
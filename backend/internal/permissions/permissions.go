@@ -0,0 +1,131 @@
+// Package permissions defines the named permissions that guard API routes.
+//
+// Permissions are grouped into roles stored in the roles / role_permissions
+// tables (migration 012). Admins can edit those grants at runtime; this package
+// only holds the catalogue of valid keys and the built-in defaults used when
+// the tables are unavailable.
+package permissions
+
+// Permission keys. Format is "<area>.<action>".
+const (
+	DashboardRead = "dashboard.read"
+	ActivityRead  = "activity.read"
+
+	CompaniesRead  = "companies.read"
+	CompaniesWrite = "companies.write"
+	CompaniesAll   = "companies.all" // global scope: not limited to user_companies
+
+	EmployeesRead   = "employees.read"
+	EmployeesWrite  = "employees.write"
+	EmployeesDelete = "employees.delete"
+	EmployeesExport = "employees.export"
+
+	DocumentsRead     = "documents.read"
+	DocumentsWrite    = "documents.write"
+	DocumentsDelete   = "documents.delete"
+	DocumentsDownload = "documents.download"
+	DocumentsExport   = "documents.export"
+	FilesUpload       = "files.upload"
+
+	SalaryRead    = "salary.read"
+	SalaryWrite   = "salary.write"
+	SalaryApprove = "salary.approve"
+	SalaryExport  = "salary.export"
+
+	UsersManage      = "users.manage"
+	UsersImpersonate = "users.impersonate" // no role has it by default; super_admin holds it implicitly
+	RolesManage      = "roles.manage"
+	SettingsManage   = "settings.manage"
+	APIKeysManage    = "api_keys.manage"
+)
+
+// SuperAdminRole always holds every permission, regardless of role_permissions,
+// so that the system can never be locked out of role management.
+const SuperAdminRole = "super_admin"
+
+// Definition describes a permission for the role editor UI.
+type Definition struct {
+	Key         string `json:"key"`
+	Group       string `json:"group"`
+	Description string `json:"description"`
+}
+
+// All is the catalogue of permissions, in display order.
+var All = []Definition{
+	{DashboardRead, "Dashboard", "View dashboard metrics and expiry alerts"},
+	{ActivityRead, "Dashboard", "View the activity log"},
+
+	{CompaniesRead, "Companies", "View companies"},
+	{CompaniesWrite, "Companies", "Create, edit and delete companies"},
+	{CompaniesAll, "Companies", "Access every company, not only assigned ones"},
+
+	{EmployeesRead, "Employees", "View employees"},
+	{EmployeesWrite, "Employees", "Create and edit employees, record exits"},
+	{EmployeesDelete, "Employees", "Delete employees"},
+	{EmployeesExport, "Employees", "Export employees to CSV or Excel"},
+
+	{DocumentsRead, "Documents", "View documents and their compliance status"},
+	{DocumentsWrite, "Documents", "Create, edit and renew documents"},
+	{DocumentsDelete, "Documents", "Delete documents"},
+	{DocumentsDownload, "Documents", "Download document files (passport scans etc.)"},
+	{DocumentsExport, "Documents", "Export document lists (numbers, dates, status) to CSV or Excel"},
+	{FilesUpload, "Documents", "Upload files"},
+
+	{SalaryRead, "Salary", "View salary records and summaries"},
+	{SalaryWrite, "Salary", "Generate salary records"},
+	{SalaryApprove, "Salary", "Change salary payment status"},
+	{SalaryExport, "Salary", "Export salary records to CSV or Excel"},
+
+	{UsersManage, "Administration", "Manage users and their company assignments"},
+	{UsersImpersonate, "Administration", "Sign in as another user for support (time-limited and audited)"},
+	{RolesManage, "Administration", "Create and edit roles and their permissions"},
+	{SettingsManage, "Administration", "Manage document types, compliance rules and dependencies"},
+	{APIKeysManage, "Administration", "Create and revoke personal API keys for scripts"},
+}
+
+var valid = func() map[string]bool {
+	m := make(map[string]bool, len(All))
+	for _, d := range All {
+		m[d.Key] = true
+	}
+	return m
+}()
+
+// IsValid reports whether key is a known permission.
+func IsValid(key string) bool {
+	return valid[key]
+}
+
+// Keys returns every permission key, in catalogue order.
+func Keys() []string {
+	keys := make([]string, len(All))
+	for i, d := range All {
+		keys[i] = d.Key
+	}
+	return keys
+}
+
+// Defaults mirrors the seed grants in migrations 012, 015 and 027. Used as a fallback when
+// role_permissions can't be read (e.g. migration not yet applied).
+var Defaults = map[string][]string{
+	"viewer": {
+		DashboardRead, ActivityRead, CompaniesRead,
+		EmployeesRead, EmployeesExport,
+		DocumentsRead, DocumentsDownload, DocumentsExport,
+		SalaryRead, SalaryExport,
+	},
+	"company_owner": {
+		DashboardRead, ActivityRead, CompaniesRead,
+		EmployeesRead, EmployeesWrite, EmployeesDelete, EmployeesExport,
+		DocumentsRead, DocumentsWrite, DocumentsDelete, DocumentsDownload, DocumentsExport, FilesUpload,
+		SalaryRead, SalaryWrite, SalaryApprove, SalaryExport,
+		APIKeysManage,
+	},
+	"admin": {
+		DashboardRead, ActivityRead, CompaniesRead, CompaniesWrite, CompaniesAll,
+		EmployeesRead, EmployeesWrite, EmployeesDelete, EmployeesExport,
+		DocumentsRead, DocumentsWrite, DocumentsDelete, DocumentsDownload, DocumentsExport, FilesUpload,
+		SalaryRead, SalaryWrite, SalaryApprove, SalaryExport,
+		UsersManage, SettingsManage, APIKeysManage,
+	},
+}
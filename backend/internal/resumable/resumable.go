@@ -0,0 +1,310 @@
+// Package resumable implements create/patch/finalize uploads for files too
+// large to send reliably in one request.
+//
+// A client creates a session declaring the file's size, then sends chunks
+// with the offset they start at (like tus's Upload-Offset). Each chunk is
+// stored as its own object under staging/<session>/ so any API instance can
+// accept the next one; if a chunk is lost the client asks for the current
+// offset and resumes from there. Finalize assembles the chunks into a local
+// temporary file that goes through the normal upload pipeline. Sessions that
+// are not finished before they expire are deleted with their chunks.
+package resumable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"manpower-backend/internal/storage"
+)
+
+// StagingPrefix is where chunks of unfinished uploads are stored.
+const StagingPrefix = "staging/"
+
+// MaxChunkSize bounds a single PATCH body.
+const MaxChunkSize = 8 << 20 // 8 MB
+
+var (
+	ErrNotFound       = errors.New("upload session not found")
+	ErrOffsetMismatch = errors.New("chunk offset does not match the upload offset")
+	ErrTooLarge       = errors.New("chunk goes past the declared upload length")
+	ErrIncomplete     = errors.New("upload is not complete")
+)
+
+// Session is an upload in progress.
+type Session struct {
+	ID        string    `json:"id"`
+	Category  string    `json:"category"`
+	FileName  string    `json:"fileName"`
+	Length    int64     `json:"length"` // declared file size
+	Offset    int64     `json:"offset"` // bytes received so far
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Manager stores sessions in upload_sessions and chunks in a Store.
+type Manager struct {
+	pool  *pgxpool.Pool
+	store storage.Store
+	ttl   time.Duration
+}
+
+// New creates a Manager. Sessions expire ttl after their last chunk.
+func New(pool *pgxpool.Pool, store storage.Store, ttl time.Duration) *Manager {
+	return &Manager{pool: pool, store: store, ttl: ttl}
+}
+
+const sessionCols = `id, category, file_name, upload_length, upload_offset, expires_at, created_at`
+
+func scanSession(row pgx.Row, s *Session) error {
+	return row.Scan(&s.ID, &s.Category, &s.FileName, &s.Length, &s.Offset, &s.ExpiresAt, &s.CreatedAt)
+}
+
+// Create starts a session for userID.
+func (m *Manager) Create(ctx context.Context, userID, category, fileName string, length int64) (*Session, error) {
+	var s Session
+	err := scanSession(m.pool.QueryRow(ctx, `
+		INSERT INTO upload_sessions (user_id, category, file_name, upload_length, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+		RETURNING `+sessionCols,
+		userID, category, fileName, length, int64(m.ttl.Seconds()),
+	), &s)
+	if err != nil {
+		return nil, fmt.Errorf("create upload session: %w", err)
+	}
+	return &s, nil
+}
+
+// Get returns userID's unexpired session id.
+func (m *Manager) Get(ctx context.Context, id, userID string) (*Session, error) {
+	var s Session
+	err := scanSession(m.pool.QueryRow(ctx, `
+		SELECT `+sessionCols+` FROM upload_sessions
+		WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+	`, id, userID), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get upload session: %w", err)
+	}
+	return &s, nil
+}
+
+// Append stores the chunk read from r, which must start at offset, and
+// returns the session with its new offset. The session row stays locked while
+// the chunk is stored, so concurrent PATCHes for one upload can't interleave.
+func (m *Manager) Append(ctx context.Context, id, userID string, offset int64, r io.Reader) (*Session, error) {
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var s Session
+	err = scanSession(tx.QueryRow(ctx, `
+		SELECT `+sessionCols+` FROM upload_sessions
+		WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+		FOR UPDATE
+	`, id, userID), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock upload session: %w", err)
+	}
+	if offset != s.Offset {
+		return &s, ErrOffsetMismatch
+	}
+
+	// Read one byte past what may remain, to detect overlong chunks
+	remaining := min(s.Length-s.Offset, MaxChunkSize)
+	chunk, err := os.CreateTemp("", "chunk-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(chunk.Name())
+	defer chunk.Close()
+	n, err := io.Copy(chunk, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return nil, fmt.Errorf("read chunk: %w", err)
+	}
+	if n > remaining {
+		return &s, ErrTooLarge
+	}
+	if n == 0 {
+		return &s, nil
+	}
+	if _, err := chunk.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.store.Save(ctx, chunkKey(s.ID, s.Offset), chunk, "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("store chunk: %w", err)
+	}
+	err = scanSession(tx.QueryRow(ctx, `
+		UPDATE upload_sessions
+		SET upload_offset = upload_offset + $2, expires_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $1
+		RETURNING `+sessionCols,
+		s.ID, n, int64(m.ttl.Seconds()),
+	), &s)
+	if err != nil {
+		return nil, fmt.Errorf("advance upload offset: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Assemble concatenates a complete session's chunks into a temporary file.
+// The caller must close and remove it.
+func (m *Manager) Assemble(ctx context.Context, s *Session) (*os.File, error) {
+	if s.Offset != s.Length {
+		return nil, ErrIncomplete
+	}
+
+	parts, err := m.chunks(ctx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "upload-")
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*os.File, error) {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	var next int64
+	for _, p := range parts {
+		if p.offset != next {
+			return fail(fmt.Errorf("upload %s: chunk at %d missing", s.ID, next))
+		}
+		r, _, err := m.store.Open(ctx, p.key)
+		if err != nil {
+			return fail(fmt.Errorf("open chunk %s: %w", p.key, err))
+		}
+		n, err := io.Copy(f, r)
+		r.Close()
+		if err != nil {
+			return fail(fmt.Errorf("read chunk %s: %w", p.key, err))
+		}
+		next += n
+	}
+	if next != s.Length {
+		return fail(fmt.Errorf("upload %s: assembled %d of %d bytes", s.ID, next, s.Length))
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+	return f, nil
+}
+
+// Delete removes a session and its chunks.
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	parts, err := m.chunks(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if err := m.store.Delete(ctx, p.key); err != nil {
+			return fmt.Errorf("delete chunk %s: %w", p.key, err)
+		}
+	}
+	if _, err := m.pool.Exec(ctx, `DELETE FROM upload_sessions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete upload session: %w", err)
+	}
+	return nil
+}
+
+// Expire deletes sessions past their expiry, and chunks whose session no
+// longer exists, and returns how many sessions were removed.
+func (m *Manager) Expire(ctx context.Context) (int, error) {
+	rows, err := m.pool.Query(ctx, `SELECT id::text FROM upload_sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("query expired uploads: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("scan expired uploads: %w", err)
+	}
+
+	removed := 0
+	for _, id := range ids {
+		if err := m.Delete(ctx, id); err != nil {
+			log.Printf("[resumable] failed to expire upload %s: %v", id, err)
+			continue
+		}
+		removed++
+	}
+
+	// Chunks left behind by a session row that is already gone
+	live := map[string]bool{}
+	rows, err = m.pool.Query(ctx, `SELECT id::text FROM upload_sessions`)
+	if err != nil {
+		return removed, fmt.Errorf("query uploads: %w", err)
+	}
+	liveIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return removed, fmt.Errorf("scan uploads: %w", err)
+	}
+	for _, id := range liveIDs {
+		live[id] = true
+	}
+	err = m.store.List(ctx, StagingPrefix, func(obj storage.ObjectInfo) error {
+		id, _, _ := strings.Cut(strings.TrimPrefix(obj.Key, StagingPrefix), "/")
+		if live[id] || time.Since(obj.ModTime) < time.Hour {
+			return nil
+		}
+		if err := m.store.Delete(ctx, obj.Key); err != nil {
+			log.Printf("[resumable] failed to delete stray chunk %s: %v", obj.Key, err)
+		}
+		return nil
+	})
+	return removed, err
+}
+
+type chunk struct {
+	key    string
+	offset int64
+}
+
+// chunks lists a session's chunks in offset order.
+func (m *Manager) chunks(ctx context.Context, id string) ([]chunk, error) {
+	var parts []chunk
+	prefix := StagingPrefix + id + "/"
+	err := m.store.List(ctx, prefix, func(obj storage.ObjectInfo) error {
+		off, err := strconv.ParseInt(strings.TrimPrefix(obj.Key, prefix), 10, 64)
+		if err != nil {
+			return nil // not ours
+		}
+		parts = append(parts, chunk{key: obj.Key, offset: off})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list chunks of %s: %w", id, err)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].offset < parts[j].offset })
+	return parts, nil
+}
+
+// chunkKey names the chunk starting at offset; zero-padding keeps listings
+// in order.
+func chunkKey(id string, offset int64) string {
+	return fmt.Sprintf("%s%s/%012d", StagingPrefix, id, offset)
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ContentKey returns the content-addressed key for a file:
+// <category>/<first two hex digits>/<sha256><ext>. Identical content in the
+// same category always maps to the same key, so re-uploads are free and
+// concurrent uploads can't overwrite each other's files.
+func ContentKey(category, sum, ext string) string {
+	return category + "/" + sum[:2] + "/" + sum + strings.ToLower(ext)
+}
+
+// SHA256FromKey extracts the checksum from a content-addressed key.
+// Legacy keys (category/<unix>_<name>) report false.
+func SHA256FromKey(key string) (string, bool) {
+	base := path.Base(key)
+	sum := strings.TrimSuffix(base, path.Ext(base))
+	if !sha256Hex.MatchString(sum) {
+		return "", false
+	}
+	return sum, true
+}
@@ -4,37 +4,43 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // LocalStore saves files to the local filesystem.
 // Implements the Store interface for development and single-server deployments.
+// Files are served by the API at baseURL, which only accepts URLs carrying an
+// HMAC signature and expiry minted by SignedURL.
 type LocalStore struct {
-	basePath string // Root directory for uploads (e.g., "./uploads")
-	baseURL  string // URL prefix for serving files (e.g., "http://localhost:8080/api/files")
+	basePath string     // Root directory for uploads (e.g., "./uploads")
+	urls     *URLSigner // signs URLs served by the API (e.g., "http://localhost:8080/api/files")
 }
 
 // NewLocalStore creates a LocalStore and ensures the upload directory exists.
-func NewLocalStore(basePath, baseURL string) (*LocalStore, error) {
+func NewLocalStore(basePath, baseURL string, secret []byte) (*LocalStore, error) {
+	urls, err := NewURLSigner(baseURL, secret)
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return nil, fmt.Errorf("create upload directory: %w", err)
 	}
 
-	// Ensure base URL doesn't have a trailing slash
-	baseURL = strings.TrimRight(baseURL, "/")
-
 	return &LocalStore{
 		basePath: basePath,
-		baseURL:  baseURL,
+		urls:     urls,
 	}, nil
 }
 
 // Save writes the file to disk under basePath/path.
 // Creates subdirectories automatically (e.g., basePath/documents/employee-id/).
 func (s *LocalStore) Save(ctx context.Context, path string, file io.Reader, contentType string) (*FileInfo, error) {
-	fullPath := filepath.Join(s.basePath, filepath.Clean(path))
+	fullPath := s.FilePath(path)
 
 	// Create parent directories (e.g., uploads/documents/abc-123/)
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
@@ -55,16 +61,83 @@ func (s *LocalStore) Save(ctx context.Context, path string, file io.Reader, cont
 	}
 
 	return &FileInfo{
-		URL:      s.URL(path),
+		Key:      path,
 		FileName: filepath.Base(path),
 		FileSize: written,
 		FileType: contentType,
 	}, nil
 }
 
+// Open opens the file for reading.
+func (s *LocalStore) Open(ctx context.Context, path string) (io.ReadCloser, *ObjectInfo, error) {
+	f, err := os.Open(s.FilePath(path))
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("open file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("stat file: %w", err)
+	}
+	return f, s.objectInfo(cleanKey(path), fi), nil
+}
+
+// Stat returns the file's size and modification time. The content type is
+// guessed from the extension, since the filesystem doesn't record it.
+func (s *LocalStore) Stat(ctx context.Context, path string) (*ObjectInfo, error) {
+	fi, err := os.Stat(s.FilePath(path))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	return s.objectInfo(cleanKey(path), fi), nil
+}
+
+// List walks basePath and reports every regular file under prefix.
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	return filepath.WalkDir(s.basePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.basePath, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(*s.objectInfo(key, fi))
+	})
+}
+
+func (s *LocalStore) objectInfo(key string, fi fs.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:         key,
+		Size:        fi.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ModTime:     fi.ModTime(),
+	}
+}
+
 // Delete removes a file from disk. Returns nil if file doesn't exist.
 func (s *LocalStore) Delete(ctx context.Context, path string) error {
-	fullPath := filepath.Join(s.basePath, filepath.Clean(path))
+	fullPath := s.FilePath(path)
 
 	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("delete file: %w", err)
@@ -72,8 +145,24 @@ func (s *LocalStore) Delete(ctx context.Context, path string) error {
 	return nil
 }
 
-// URL returns the HTTP URL to access the file via the API.
-func (s *LocalStore) URL(path string) string {
-	// Convert backslashes to forward slashes for URL compatibility
-	return s.baseURL + "/" + strings.ReplaceAll(filepath.Clean(path), "\\", "/")
+// SignedURL returns a URL served by the API; see URLSigner.
+func (s *LocalStore) SignedURL(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	return s.urls.Sign(path, ttl), nil
+}
+
+// Verify checks the expiry and signature of a URL minted by SignedURL.
+func (s *LocalStore) Verify(path, expires, sig string) error {
+	return s.urls.Verify(path, expires, sig)
+}
+
+// FilePath maps a storage key to its location on disk. Keys can never
+// resolve outside basePath.
+func (s *LocalStore) FilePath(path string) string {
+	return filepath.Join(s.basePath, filepath.FromSlash(cleanKey(path)))
+}
+
+// cleanKey normalises a key to a slash-separated relative path without "..".
+func cleanKey(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean("/"+path)), "/")
 }
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Options configure an S3Store.
+type S3Options struct {
+	Endpoint     string // e.g. "http://localhost:9000" for MinIO; empty for AWS
+	Region       string // "auto" for R2; defaults to "us-east-1"
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	UsePathStyle bool // address the bucket as /<bucket>/<key> (MinIO, most self-hosted servers)
+}
+
+// S3Store saves files to any S3-compatible object storage: AWS S3, Cloudflare
+// R2, MinIO. Implements the Store interface for production deployments.
+// The bucket should not have public access enabled; reads go through
+// presigned GET URLs.
+type S3Store struct {
+	client    *s3.Client
+	presign   *s3.PresignClient
+	bucket    string
+	pathStyle bool
+}
+
+// NewS3Store creates an S3Store from opts.
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(opts.AccessKey, opts.SecretKey, ""),
+		),
+		config.WithRegion(opts.Region),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(strings.TrimRight(opts.Endpoint, "/"))
+		}
+		o.UsePathStyle = opts.UsePathStyle
+	})
+
+	return &S3Store{
+		client:    client,
+		presign:   s3.NewPresignClient(client),
+		bucket:    opts.Bucket,
+		pathStyle: opts.UsePathStyle,
+	}, nil
+}
+
+// NewR2Store creates an S3Store configured for the given Cloudflare account.
+func NewR2Store(accountID, accessKey, secretKey, bucket string) (*S3Store, error) {
+	return NewS3Store(S3Options{
+		Endpoint:  fmt.Sprintf("https://%s.r2.cloudflarestorage.com", accountID),
+		Region:    "auto",
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	})
+}
+
+// Save uploads a file and returns its metadata.
+func (s *S3Store) Save(ctx context.Context, path string, file io.Reader, contentType string) (*FileInfo, error) {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path),
+		Body:        file,
+		ContentType: aws.String(contentType),
+	}
+
+	_, err := s.client.PutObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("s3 put object: %w", err)
+	}
+
+	// Get file size by heading the object (PutObject doesn't return size)
+	info, err := s.Stat(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileInfo{
+		Key:      path,
+		FileName: path[strings.LastIndex(path, "/")+1:],
+		FileSize: info.Size,
+		FileType: contentType,
+	}, nil
+}
+
+// Open streams the object.
+func (s *S3Store) Open(ctx context.Context, path string) (io.ReadCloser, *ObjectInfo, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, nil, s.wrapErr("get object", err)
+	}
+	return out.Body, &ObjectInfo{
+		Key:         path,
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+		ModTime:     aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// Stat heads the object.
+func (s *S3Store) Stat(ctx context.Context, path string) (*ObjectInfo, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, s.wrapErr("head object", err)
+	}
+	return &ObjectInfo{
+		Key:         path,
+		Size:        aws.ToInt64(head.ContentLength),
+		ContentType: aws.ToString(head.ContentType),
+		ModTime:     aws.ToTime(head.LastModified),
+	}, nil
+}
+
+// List pages through the bucket with ListObjectsV2.
+func (s *S3Store) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("s3 list objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			if err := fn(ObjectInfo{
+				Key:     aws.ToString(obj.Key),
+				Size:    aws.ToInt64(obj.Size),
+				ModTime: aws.ToTime(obj.LastModified),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delete removes an object. Returns nil if the object doesn't exist.
+func (s *S3Store) Delete(ctx context.Context, path string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 delete object: %w", err)
+	}
+	return nil
+}
+
+// SignedURL returns a presigned GET URL for the object, valid for ttl.
+func (s *S3Store) SignedURL(ctx context.Context, path string, ttl time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(strings.TrimLeft(path, "/")),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("s3 presign get object: %w", err)
+	}
+	return req.URL, nil
+}
+
+// KeyFromURL maps a URL minted by SignedURL back to its key. Path-style URLs
+// carry the bucket as the first path segment.
+func (s *S3Store) KeyFromURL(raw string) string {
+	key := KeyFromURL(raw)
+	if u, err := url.Parse(strings.TrimSpace(raw)); err == nil && u.Scheme != "" && s.pathStyle {
+		key = strings.TrimPrefix(key, s.bucket+"/")
+	}
+	return key
+}
+
+// wrapErr maps missing-object errors to ErrNotFound.
+func (s *S3Store) wrapErr(op string, err error) error {
+	var noKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noKey) || errors.As(err, &notFound) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("s3 %s: %w", op, err)
+}
@@ -5,19 +5,42 @@
 //  1. Implement the Store interface in a new file (e.g., s3.go)
 //  2. Change one line in main.go to inject the new implementation
 //  3. No handler code changes needed
+//
+// Files are private. The database stores storage keys (e.g.
+// "documents/1700000000_visa.pdf"), and handlers mint short-lived signed URLs
+// for them with SignedURL after checking the caller's company scope.
 package storage
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net/url"
+	"strings"
+	"time"
 )
 
+// ErrNotFound is returned by Open and Stat when no file exists at the path.
+var ErrNotFound = errors.New("file not found")
+
 // FileInfo holds metadata returned after a successful upload.
 type FileInfo struct {
-	URL      string `json:"url"`
+	Key      string `json:"key"`           // storage key — what gets persisted
+	URL      string `json:"url,omitempty"` // short-lived signed URL, for previews; not set by Lookup
 	FileName string `json:"fileName"`
 	FileSize int64  `json:"fileSize"`
 	FileType string `json:"fileType"`
+	SHA256   string `json:"sha256,omitempty"` // hex digest, for content-addressed uploads
+
+	ScanStatus string `json:"scanStatus,omitempty"` // malware scan: pending, clean or infected
+}
+
+// ObjectInfo describes a stored file.
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string // may be empty when the backend doesn't record it
+	ModTime     time.Time
 }
 
 // Store defines the contract for file storage operations.
@@ -27,10 +50,55 @@ type Store interface {
 	// The path should be relative (e.g., "documents/employee-id/visa.pdf").
 	Save(ctx context.Context, path string, file io.Reader, contentType string) (*FileInfo, error)
 
+	// Open returns a reader for the file at path. The caller must close it.
+	// Returns ErrNotFound if the file doesn't exist.
+	Open(ctx context.Context, path string) (io.ReadCloser, *ObjectInfo, error)
+
+	// Stat returns metadata for the file at path without reading it.
+	// Returns ErrNotFound if the file doesn't exist.
+	Stat(ctx context.Context, path string) (*ObjectInfo, error)
+
+	// List calls fn for every file whose key starts with prefix ("" for all),
+	// stopping at the first error fn returns.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+
 	// Delete removes a file at the given path.
 	// Returns nil if the file doesn't exist (idempotent).
 	Delete(ctx context.Context, path string) error
 
-	// URL returns the publicly accessible URL for a stored file.
-	URL(path string) string
+	// SignedURL returns a URL that grants read access to the file for ttl.
+	// Callers are responsible for checking the user may see the file.
+	SignedURL(ctx context.Context, path string, ttl time.Duration) (string, error)
+}
+
+// KeyFor normalises raw to a storage key for store. Stores that mint URLs of
+// their own shape (e.g. path-style S3, where the bucket leads the path)
+// implement KeyFromURL themselves.
+func KeyFor(store Store, raw string) string {
+	if k, ok := store.(interface{ KeyFromURL(string) string }); ok {
+		return k.KeyFromURL(raw)
+	}
+	return KeyFromURL(raw)
+}
+
+// KeyFromURL returns the storage key for a value that may already be a key,
+// a legacy public URL (".../api/files/<key>" or "<bucket public URL>/<key>")
+// or a signed URL minted by SignedURL. Clients tend to send back whatever URL
+// they were given, so handlers normalise file references through this.
+func KeyFromURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		if i := strings.Index(raw, "?"); i >= 0 {
+			raw = raw[:i]
+		}
+		raw = strings.TrimPrefix(raw, "/api/files/")
+		return strings.TrimLeft(raw, "/")
+	}
+
+	path := u.Path
+	if i := strings.Index(path, "/api/files/"); i >= 0 {
+		path = path[i+len("/api/files/"):]
+	}
+	return strings.TrimLeft(path, "/")
 }
@@ -0,0 +1,83 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"manpower-backend/internal/storage"
+)
+
+// BackfillReport summarises a backfill run.
+type BackfillReport struct {
+	DryRun      bool     `json:"dryRun"`
+	Files       int      `json:"files"`       // referenced files missing derivatives
+	Generated   int      `json:"generated"`   // files that now have every variant
+	Unsupported int      `json:"unsupported"` // not an image, or a PDF without a renderer
+	Missing     int      `json:"missing"`     // not found in storage
+	Failed      int      `json:"failed"`
+	Errors      []string `json:"errors"`
+}
+
+// Backfill generates derivatives for every file referenced by
+// documents.file_url, document_files.file_url or employees.photo_url that
+// doesn't have them yet, such as uploads from before thumbnails existed. Files flagged by the malware
+// scan are skipped. In a dry run it only counts them.
+func (g *Generator) Backfill(ctx context.Context, dryRun bool) (*BackfillReport, error) {
+	rows, err := g.pool.Query(ctx, `
+		SELECT ref FROM (
+			SELECT file_url AS ref FROM documents WHERE COALESCE(file_url, '') <> ''
+			UNION
+			SELECT file_url FROM document_files WHERE COALESCE(file_url, '') <> ''
+			UNION
+			SELECT photo_url FROM employees WHERE COALESCE(photo_url, '') <> ''
+		) refs
+		WHERE NOT EXISTS (SELECT 1 FROM file_scans fs WHERE fs.key = refs.ref AND fs.status = 'infected')
+		  AND (SELECT COUNT(*) FROM file_derivatives fd WHERE fd.key = refs.ref) < $1
+		ORDER BY ref
+	`, len(Variants))
+	if err != nil {
+		return nil, fmt.Errorf("query files without derivatives: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan file reference: %w", err)
+		}
+		keys = append(keys, storage.KeyFor(g.store, ref))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &BackfillReport{DryRun: dryRun, Files: len(keys), Errors: []string{}}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		err := g.Generate(ctx, key)
+		switch {
+		case err == nil:
+			report.Generated++
+		case errors.Is(err, ErrUnsupported):
+			report.Unsupported++
+		case errors.Is(err, storage.ErrNotFound):
+			report.Missing++
+		default:
+			report.Failed++
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	log.Printf("[thumbnail] backfill: %d file(s), %d generated, %d unsupported, %d missing, %d failed",
+		report.Files, report.Generated, report.Unsupported, report.Missing, report.Failed)
+	return report, nil
+}
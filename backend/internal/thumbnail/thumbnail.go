@@ -0,0 +1,271 @@
+// Package thumbnail generates downscaled JPEG derivatives of stored images
+// and PDFs, so lists and document tabs don't load full-size scans.
+//
+// Each variant is stored next to its original: "documents/ab/<sha256>.pdf"
+// gets "documents/ab/<sha256>.thumb.jpg" and so on. PDFs are rasterised
+// (first page only) with poppler's pdftoppm when it is installed; without it
+// they simply have no derivatives. What exists is recorded in
+// file_derivatives, which handlers read to add thumbnailUrl and previewUrl.
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/image/draw"
+
+	"manpower-backend/internal/imagedecode"
+	"manpower-backend/internal/storage"
+)
+
+// Variant is one derivative size: the longer side is scaled down to MaxSide.
+type Variant struct {
+	Name    string
+	MaxSide int
+}
+
+// Variants are generated for every image and PDF, smallest first.
+var Variants = []Variant{
+	{Thumb, 160},
+	{"medium", 480},
+	{Preview, 1200},
+}
+
+// Variant names exposed as thumbnailUrl and previewUrl.
+const (
+	Thumb   = "thumb"
+	Preview = "preview"
+)
+
+// ErrUnsupported is returned for files no derivative can be made from.
+var ErrUnsupported = errors.New("no thumbnail for this file type")
+
+// Key returns where variant of the original at key is stored.
+func Key(key, variant string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "." + variant + ".jpg"
+}
+
+// Set maps variant names to derivative keys.
+type Set map[string]string
+
+// Generator makes and records derivatives for one store.
+type Generator struct {
+	pool     *pgxpool.Pool
+	store    storage.Store
+	pdftoppm string // path to pdftoppm; "" renders no PDFs
+}
+
+// New creates a Generator. pdfRenderer is the pdftoppm binary to use (a name
+// looked up in PATH, or a path); if it can't be found, PDFs get no
+// derivatives.
+func New(pool *pgxpool.Pool, store storage.Store, pdfRenderer string) *Generator {
+	g := &Generator{pool: pool, store: store}
+	if pdfRenderer != "" {
+		if p, err := exec.LookPath(pdfRenderer); err == nil {
+			g.pdftoppm = p
+		} else {
+			log.Printf("[thumbnail] PDF renderer %q not found; PDFs get no previews", pdfRenderer)
+		}
+	}
+	return g
+}
+
+// RendersPDF reports whether a PDF renderer is available.
+func (g *Generator) RendersPDF() bool {
+	return g.pdftoppm != ""
+}
+
+// Generate makes every variant of the stored file at key, unless it already
+// has them. Returns ErrUnsupported for files that can't have derivatives.
+func (g *Generator) Generate(ctx context.Context, key string) error {
+	var n int
+	if err := g.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM file_derivatives WHERE key = $1`, key,
+	).Scan(&n); err != nil {
+		return fmt.Errorf("query derivatives of %s: %w", key, err)
+	}
+	if n == len(Variants) {
+		return nil
+	}
+
+	r, info, err := g.store.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var img image.Image
+	switch contentType(key, info.ContentType) {
+	case "image/jpeg", "image/png":
+		img, err = decode(r)
+	case "application/pdf":
+		img, err = g.renderPDF(ctx, r)
+	default:
+		return ErrUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", key, err)
+	}
+
+	for _, v := range Variants {
+		if err := g.save(ctx, key, v, img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save scales img for variant v, stores it and records it.
+func (g *Generator) save(ctx context.Context, key string, v Variant, img image.Image) error {
+	scaled := scale(img, v.MaxSide)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 80}); err != nil {
+		return fmt.Errorf("encode %s of %s: %w", v.Name, key, err)
+	}
+
+	dst := Key(key, v.Name)
+	if _, err := g.store.Save(ctx, dst, &buf, "image/jpeg"); err != nil {
+		return fmt.Errorf("store %s: %w", dst, err)
+	}
+	b := scaled.Bounds()
+	if _, err := g.pool.Exec(ctx, `
+		INSERT INTO file_derivatives (key, variant, derivative_key, width, height)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key, variant) DO UPDATE SET
+			derivative_key = EXCLUDED.derivative_key, width = EXCLUDED.width,
+			height = EXCLUDED.height, created_at = NOW()
+	`, key, v.Name, dst, b.Dx(), b.Dy()); err != nil {
+		return fmt.Errorf("record %s: %w", dst, err)
+	}
+	return nil
+}
+
+// Lookup returns the derivatives recorded for each of keys.
+func Lookup(ctx context.Context, pool *pgxpool.Pool, keys []string) (map[string]Set, error) {
+	sets := map[string]Set{}
+	if len(keys) == 0 {
+		return sets, nil
+	}
+	rows, err := pool.Query(ctx,
+		`SELECT key, variant, derivative_key FROM file_derivatives WHERE key = ANY($1)`, keys)
+	if err != nil {
+		return nil, fmt.Errorf("query derivatives: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, variant, dst string
+		if err := rows.Scan(&key, &variant, &dst); err != nil {
+			return nil, fmt.Errorf("scan derivative: %w", err)
+		}
+		if sets[key] == nil {
+			sets[key] = Set{}
+		}
+		sets[key][variant] = dst
+	}
+	return sets, rows.Err()
+}
+
+// contentType prefers the stored type and falls back to the extension.
+func contentType(key, stored string) string {
+	switch {
+	case stored == "image/jpg":
+		return "image/jpeg"
+	case stored != "" && stored != "application/octet-stream":
+		return stored
+	}
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".pdf":
+		return "application/pdf"
+	}
+	return ""
+}
+
+// decode reads an image within imagedecode.MaxPixels.
+func decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return imagedecode.Decode(data)
+}
+
+// renderPDF rasterises the first page of a PDF with pdftoppm, sized for the
+// largest variant.
+func (g *Generator) renderPDF(ctx context.Context, r io.Reader) (image.Image, error) {
+	if g.pdftoppm == "" {
+		return nil, ErrUnsupported
+	}
+
+	dir, err := os.MkdirTemp("", "thumbnail-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.pdf")
+	f, err := os.Create(in)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := filepath.Join(dir, "page")
+	maxSide := Variants[len(Variants)-1].MaxSide
+	cmd := exec.CommandContext(ctx, g.pdftoppm,
+		"-f", "1", "-l", "1", "-singlefile", "-png",
+		"-scale-to", strconv.Itoa(maxSide), in, out)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm: %v: %s", err, bytes.TrimSpace(msg))
+	}
+
+	page, err := os.Open(out + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+	return decode(page)
+}
+
+// scale fits img within maxSide×maxSide on a white background (JPEG has no
+// transparency). Smaller images keep their size.
+func scale(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, max(1, h*maxSide/w)
+		} else {
+			w, h = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,126 @@
+package watermark
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"manpower-backend/internal/imagedecode"
+)
+
+// renderPDF rasterises every page of a PDF at dpi with pdftoppm.
+func (s *Stamper) renderPDF(ctx context.Context, data []byte) ([]image.Image, error) {
+	if s.pdftoppm == "" {
+		return nil, ErrNoRenderer
+	}
+
+	dir, err := os.MkdirTemp("", "watermark-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.pdf")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+	// One page more than allowed, to tell a long PDF from one of MaxPages
+	cmd := exec.CommandContext(ctx, s.pdftoppm,
+		"-r", strconv.Itoa(dpi), "-l", strconv.Itoa(MaxPages+1), "-png",
+		in, filepath.Join(dir, "page"))
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm: %v: %s", err, bytes.TrimSpace(msg))
+	}
+
+	// pdftoppm names pages page-1.png or page-01.png, depending on the count
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > MaxPages {
+		return nil, ErrTooManyPages
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("pdftoppm rendered no pages")
+	}
+	pageNo := func(f string) int {
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), "page-"), ".png"))
+		return n
+	}
+	sort.Slice(files, func(i, j int) bool { return pageNo(files[i]) < pageNo(files[j]) })
+
+	pages := make([]image.Image, len(files))
+	for i, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if pages[i], err = imagedecode.Decode(raw); err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+	}
+	return pages, nil
+}
+
+// writePDF builds a PDF with one full-page JPEG per page, sized so that
+// the images print at resolution dpi.
+func writePDF(pages []image.Image, resolution int) ([]byte, error) {
+	var buf bytes.Buffer
+	var offsets []int // byte offset of object i+1
+	obj := func(body string, stream []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			buf.WriteString("stream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream\n")
+		}
+		buf.WriteString("endobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1 and 2 are the catalog and page tree; each page then takes
+	// three: the page, its content stream and its image.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+3*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)), nil)
+
+	for i, page := range pages {
+		var img bytes.Buffer
+		if err := jpeg.Encode(&img, page, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, fmt.Errorf("encode page %d: %w", i+1, err)
+		}
+		b := page.Bounds()
+		w := float64(b.Dx()) * 72 / float64(resolution)
+		h := float64(b.Dy()) * 72 / float64(resolution)
+		self := 3 + 3*i
+		content := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", w, h)
+
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			w, h, self+2, self+1), nil)
+		obj(fmt.Sprintf("<< /Length %d >>", len(content)), []byte(content))
+		obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d "+
+			"/ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			b.Dx(), b.Dy(), img.Len()), img.Bytes())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes(), nil
+}
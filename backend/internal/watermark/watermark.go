@@ -0,0 +1,249 @@
+// Package watermark stamps copies of document scans that are handed to
+// third parties: a repeated diagonal notice ("Copy issued to … on … for …")
+// across every page, the same notice in a legible footer, and opaque boxes
+// over redacted regions.
+//
+// Copies are always raster. PDFs are rendered page by page with poppler's
+// pdftoppm and rebuilt as image-only PDFs, so redacted text cannot be
+// recovered from the copy and the stamp cannot be removed as a layer.
+package watermark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+
+	"manpower-backend/internal/imagedecode"
+)
+
+// Limits on what gets stamped.
+const (
+	MaxPages = 50  // PDF pages per copy
+	dpi      = 150 // PDF rendering resolution
+)
+
+var (
+	// ErrUnsupported is returned for files that are neither PDFs nor
+	// JPEG/PNG images.
+	ErrUnsupported = errors.New("only PDFs and JPEG/PNG images can be watermarked")
+	// ErrNoRenderer is returned for PDFs when pdftoppm is not installed.
+	ErrNoRenderer = errors.New("PDF watermarking needs pdftoppm, which is not installed")
+	// ErrTooManyPages is returned for PDFs longer than MaxPages.
+	ErrTooManyPages = fmt.Errorf("PDFs longer than %d pages cannot be watermarked", MaxPages)
+)
+
+// Rect is a region to redact, as fractions (0–1) of the page width and
+// height from the top-left corner. Page is 1-based; 0 means every page.
+type Rect struct {
+	Page       int
+	X, Y, W, H float64
+}
+
+// ParseRect parses "x,y,w,h" or "page:x,y,w,h", e.g. "1:0.05,0.6,0.9,0.3"
+// for the lower part of the first page.
+func ParseRect(s string) (Rect, error) {
+	var r Rect
+	if page, rest, ok := strings.Cut(s, ":"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(page))
+		if err != nil || n < 1 {
+			return r, fmt.Errorf("invalid page in %q", s)
+		}
+		r.Page, s = n, rest
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return r, fmt.Errorf("redaction %q must be x,y,w,h", s)
+	}
+	v := make([]float64, 4)
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || f < 0 || f > 1 {
+			return r, fmt.Errorf("redaction %q: values must be fractions between 0 and 1", s)
+		}
+		v[i] = f
+	}
+	r.X, r.Y, r.W, r.H = v[0], v[1], v[2], v[3]
+	if r.W == 0 || r.H == 0 {
+		return r, fmt.Errorf("redaction %q is empty", s)
+	}
+	return r, nil
+}
+
+// Options describe one copy.
+type Options struct {
+	Lines  []string // the notice; the first line is repeated across the page
+	Redact []Rect
+}
+
+// Stamper makes watermarked copies.
+type Stamper struct {
+	pdftoppm string // "" stamps no PDFs
+}
+
+// New creates a Stamper. pdfRenderer is the pdftoppm binary to use (a name
+// looked up in PATH, or a path); without it only images can be stamped.
+func New(pdfRenderer string) *Stamper {
+	s := &Stamper{}
+	if pdfRenderer != "" {
+		if p, err := exec.LookPath(pdfRenderer); err == nil {
+			s.pdftoppm = p
+		} else {
+			log.Printf("[watermark] PDF renderer %q not found; PDFs cannot be watermarked", pdfRenderer)
+		}
+	}
+	return s
+}
+
+// Stamp returns a watermarked, redacted copy of data and its content type:
+// a PDF for PDFs, otherwise the image's own format.
+func (s *Stamper) Stamp(ctx context.Context, data []byte, opts Options) ([]byte, string, error) {
+	switch ct := http.DetectContentType(data); ct {
+	case "application/pdf":
+		pages, err := s.renderPDF(ctx, data)
+		if err != nil {
+			return nil, "", err
+		}
+		for i, page := range pages {
+			pages[i] = stampPage(page, i+1, opts)
+		}
+		out, err := writePDF(pages, dpi)
+		return out, ct, err
+
+	case "image/jpeg", "image/png":
+		img, err := imagedecode.Decode(data)
+		if err != nil {
+			return nil, "", err
+		}
+		page := stampPage(img, 1, opts)
+		var buf bytes.Buffer
+		if ct == "image/png" {
+			err = png.Encode(&buf, page)
+		} else {
+			err = jpeg.Encode(&buf, page, &jpeg.Options{Quality: 90})
+		}
+		return buf.Bytes(), ct, err
+	}
+	return nil, "", ErrUnsupported
+}
+
+// ── Drawing ──────────────────────────────────────────────────────
+
+var (
+	markColor   = color.NRGBA{R: 170, G: 20, B: 20, A: 72}
+	footerBG    = color.NRGBA{R: 255, G: 255, B: 255, A: 230}
+	footerColor = color.NRGBA{R: 20, G: 20, B: 20, A: 255}
+)
+
+var boldFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(gobold.TTF)
+})
+
+func face(size float64) font.Face {
+	f, err := boldFont()
+	if err != nil {
+		return nil
+	}
+	fc, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	return fc
+}
+
+// stampPage draws page number n of a copy onto a white, opaque canvas.
+func stampPage(img image.Image, n int, opts Options) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+
+	w, h := float64(b.Dx()), float64(b.Dy())
+	for _, r := range opts.Redact {
+		if r.Page != 0 && r.Page != n {
+			continue
+		}
+		box := image.Rect(int(r.X*w), int(r.Y*h), int(math.Ceil((r.X+r.W)*w)), int(math.Ceil((r.Y+r.H)*h)))
+		draw.Draw(dst, box.Intersect(dst.Bounds()), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+
+	if len(opts.Lines) > 0 {
+		diagonal(dst, opts.Lines[0])
+		footer(dst, opts.Lines)
+	}
+	return dst
+}
+
+// diagonal repeats text in rising rows across the whole of dst.
+func diagonal(dst *image.RGBA, text string) {
+	b := dst.Bounds()
+	size := max(14, float64(min(b.Dx(), b.Dy()))/28)
+	fc := face(size)
+	if fc == nil {
+		return
+	}
+	defer fc.Close()
+
+	// Lay the rows out on a square overlay large enough to cover dst at any
+	// angle, then rotate it onto dst.
+	side := int(math.Hypot(float64(b.Dx()), float64(b.Dy()))) + 1
+	overlay := image.NewNRGBA(image.Rect(0, 0, side, side))
+	advance := font.MeasureString(fc, text+"     ").Ceil()
+	rowHeight := int(size * 3.2)
+	d := font.Drawer{Dst: overlay, Src: image.NewUniform(markColor), Face: fc}
+	for row, y := 0, rowHeight; y < side; row, y = row+1, y+rowHeight {
+		for x := -(row % 2) * advance / 2; x < side; x += advance {
+			d.Dot = fixed.P(x, y)
+			d.DrawString(text)
+		}
+	}
+
+	theta := -math.Pi / 6
+	sin, cos := math.Sincos(theta)
+	cs := float64(side) / 2
+	cx, cy := float64(b.Dx())/2, float64(b.Dy())/2
+	m := f64.Aff3{
+		cos, -sin, cx - cos*cs + sin*cs,
+		sin, cos, cy - sin*cs - cos*cs,
+	}
+	draw.BiLinear.Transform(dst, m, overlay, overlay.Bounds(), draw.Over, nil)
+}
+
+// footer writes lines in a band along the bottom of dst.
+func footer(dst *image.RGBA, lines []string) {
+	b := dst.Bounds()
+	size := max(10, float64(b.Dx())/70)
+	fc := face(size)
+	if fc == nil {
+		return
+	}
+	defer fc.Close()
+
+	lineHeight := int(size * 1.4)
+	pad := int(size / 2)
+	band := image.Rect(b.Min.X, b.Max.Y-len(lines)*lineHeight-2*pad, b.Max.X, b.Max.Y)
+	draw.Draw(dst, band, image.NewUniform(footerBG), image.Point{}, draw.Over)
+	d := font.Drawer{Dst: dst, Src: image.NewUniform(footerColor), Face: fc}
+	for i, line := range lines {
+		d.Dot = fixed.P(band.Min.X+pad, band.Min.Y+pad+(i+1)*lineHeight-lineHeight/4)
+		d.DrawString(line)
+	}
+}
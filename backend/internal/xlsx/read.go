@@ -0,0 +1,399 @@
+// Package xlsx reads the Office Open XML spreadsheets (.xlsx) that clients
+// send in, as plain rows of text, and writes the single-sheet workbooks of
+// the exports (see Writer).
+//
+// Only cell values are read: shared, inline and formula strings, numbers
+// and booleans. Numbers formatted as dates come back as "2006-01-02" (or
+// "2006-01-02 15:04:05" with a time), other numbers in their shortest
+// decimal form, so a phone number stored as a number reads "971501234567".
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits guard against zip bombs and runaway sheets.
+const (
+	maxPartSize = 64 << 20 // decompressed bytes per XML part
+	MaxRows     = 100_000
+	MaxColumns  = 200
+)
+
+// ErrNotSpreadsheet is returned for data that is not an .xlsx workbook.
+var ErrNotSpreadsheet = errors.New("not an .xlsx workbook")
+
+// ReadRows returns the cells of the workbook's first worksheet, row by row.
+// Empty rows and cells in between are kept as empty strings, so row i is
+// line i+1 of the sheet; trailing empty rows are dropped.
+func ReadRows(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrNotSpreadsheet
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	if parts["xl/workbook.xml"] == nil {
+		return nil, ErrNotSpreadsheet
+	}
+
+	sheet, date1904, err := firstSheet(parts)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := sharedStrings(parts)
+	if err != nil {
+		return nil, err
+	}
+	dateStyles, err := dateStyles(parts)
+	if err != nil {
+		return nil, err
+	}
+	f := parts[sheet]
+	if f == nil {
+		return nil, fmt.Errorf("worksheet %s is missing", sheet)
+	}
+	return readSheet(f, shared, dateStyles, date1904)
+}
+
+// open returns a reader of a part, cut off at maxPartSize.
+func open(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, maxPartSize), rc}, nil
+}
+
+func decodePart(f *zip.File, v interface{}) error {
+	rc, err := open(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// firstSheet resolves the part name of the first worksheet in the workbook.
+func firstSheet(parts map[string]*zip.File) (string, bool, error) {
+	var wb struct {
+		Pr struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(parts["xl/workbook.xml"], &wb); err != nil {
+		return "", false, fmt.Errorf("read workbook: %w", err)
+	}
+	if len(wb.Sheets) == 0 {
+		return "", false, errors.New("the workbook has no worksheets")
+	}
+	date1904 := wb.Pr.Date1904 == "1" || wb.Pr.Date1904 == "true"
+
+	if rels := parts["xl/_rels/workbook.xml.rels"]; rels != nil {
+		var rs struct {
+			Rels []struct {
+				ID     string `xml:"Id,attr"`
+				Target string `xml:"Target,attr"`
+			} `xml:"Relationship"`
+		}
+		if err := decodePart(rels, &rs); err != nil {
+			return "", false, fmt.Errorf("read workbook relationships: %w", err)
+		}
+		for _, r := range rs.Rels {
+			if r.ID == wb.Sheets[0].RID {
+				if strings.HasPrefix(r.Target, "/") {
+					return strings.TrimPrefix(r.Target, "/"), date1904, nil
+				}
+				return path.Join("xl", r.Target), date1904, nil
+			}
+		}
+	}
+	return "xl/worksheets/sheet1.xml", date1904, nil
+}
+
+// richText is a string item: plain <t>, or runs of <r><t>.
+type richText struct {
+	T    string   `xml:"t"`
+	Runs []string `xml:"r>t"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	return t.T + strings.Join(t.Runs, "")
+}
+
+func sharedStrings(parts map[string]*zip.File) ([]string, error) {
+	f := parts["xl/sharedStrings.xml"]
+	if f == nil {
+		return nil, nil
+	}
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodePart(f, &sst); err != nil {
+		return nil, fmt.Errorf("read shared strings: %w", err)
+	}
+	out := make([]string, len(sst.Items))
+	for i, it := range sst.Items {
+		out[i] = it.String()
+	}
+	return out, nil
+}
+
+// dateStyles reports, per cell style index, whether its number format is a
+// date (and whether it shows a time).
+func dateStyles(parts map[string]*zip.File) ([]dateFormat, error) {
+	f := parts["xl/styles.xml"]
+	if f == nil {
+		return nil, nil
+	}
+	var ss struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodePart(f, &ss); err != nil {
+		return nil, fmt.Errorf("read styles: %w", err)
+	}
+	custom := map[int]string{}
+	for _, n := range ss.NumFmts {
+		custom[n.ID] = n.Code
+	}
+	out := make([]dateFormat, len(ss.Xfs))
+	for i, xf := range ss.Xfs {
+		if code, ok := custom[xf.NumFmtID]; ok {
+			out[i] = classifyFormat(code)
+		} else {
+			out[i] = builtinFormat(xf.NumFmtID)
+		}
+	}
+	return out, nil
+}
+
+type dateFormat uint8
+
+const (
+	notDate dateFormat = iota
+	dateOnly
+	dateTime
+)
+
+// builtinFormat classifies the number formats predefined by ECMA-376.
+func builtinFormat(id int) dateFormat {
+	switch {
+	case id >= 14 && id <= 17, id >= 27 && id <= 31, id >= 34 && id <= 36, id >= 50 && id <= 58:
+		return dateOnly
+	case id == 22:
+		return dateTime
+	}
+	return notDate
+}
+
+// classifyFormat tells a custom date format ("dd/mm/yyyy", "d-mmm-yy h:mm")
+// from a number format by its d, y and h tokens, outside quoted text and
+// [bracketed] colours and locales.
+func classifyFormat(code string) dateFormat {
+	var date, clock bool
+	quoted, bracket := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '\\':
+			i++
+		case c == '[':
+			bracket = true
+		case c == ']':
+			bracket = false
+		case bracket:
+		case c == 'd' || c == 'D' || c == 'y' || c == 'Y':
+			date = true
+		case c == 'h' || c == 'H' || c == 's' || c == 'S':
+			clock = true
+		}
+	}
+	switch {
+	case date && clock:
+		return dateTime
+	case date:
+		return dateOnly
+	}
+	return notDate
+}
+
+type cell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+// readSheet streams the rows of a worksheet.
+func readSheet(f *zip.File, shared []string, styles []dateFormat, date1904 bool) ([][]string, error) {
+	rc, err := open(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows [][]string
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read worksheet: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row struct {
+			Num   int    `xml:"r,attr"`
+			Cells []cell `xml:"c"`
+		}
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("read worksheet: %w", err)
+		}
+		n := row.Num
+		if n == 0 {
+			n = len(rows) + 1
+		}
+		if n > MaxRows {
+			return nil, fmt.Errorf("the sheet has more than %d rows", MaxRows)
+		}
+		for len(rows) < n {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for _, c := range row.Cells {
+			col := len(values)
+			if c.Ref != "" {
+				if col, err = column(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			if col >= MaxColumns {
+				return nil, fmt.Errorf("the sheet has more than %d columns", MaxColumns)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = c.text(shared, styles, date1904)
+		}
+		rows[n-1] = values
+	}
+
+	for len(rows) > 0 && blank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+// text renders a cell value as text.
+func (c cell) text(shared []string, styles []dateFormat, date1904 bool) string {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "inlineStr":
+		return c.Inline.String()
+	case "str", "e":
+		return c.Value
+	case "b":
+		if c.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+
+	v := strings.TrimSpace(c.Value)
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if c.Style >= 0 && c.Style < len(styles) && styles[c.Style] != notDate {
+		t := serialTime(f, date1904)
+		if styles[c.Style] == dateTime && t.Hour()+t.Minute()+t.Second() > 0 {
+			return t.Format("2006-01-02 15:04:05")
+		}
+		return t.Format("2006-01-02")
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// serialTime converts a spreadsheet date serial to a time. In the 1900
+// system day 60 is the nonexistent 29 February 1900, so day 0 is taken as
+// 30 December 1899 for every later date.
+func serialTime(serial float64, date1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	secs := math.Round((serial - days) * 86400)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second)
+}
+
+// column returns the 0-based column of a cell reference such as "AB12".
+func column(ref string) (int, error) {
+	col := 0
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			if i == 0 {
+				return 0, fmt.Errorf("invalid cell reference %q", ref)
+			}
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		if col > MaxColumns {
+			return col - 1, nil
+		}
+	}
+	return col - 1, nil
+}
+
+func blank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Format is how a column shows its numbers and dates.
+type Format uint8
+
+const (
+	General  Format = iota
+	Decimal         // #,##0.00
+	Date            // yyyy-mm-dd
+	DateTime        // yyyy-mm-dd hh:mm
+)
+
+// Column is a column of a written sheet.
+type Column struct {
+	Header string
+	Width  float64 // in characters; 0 for Excel's default
+	Format Format
+}
+
+// Cell styles, as indices into cellXfs in stylesXML.
+const (
+	styleHeader   = 1
+	styleDecimal  = 2
+	styleDate     = 3
+	styleDateTime = 4
+)
+
+// maxCellText is the longest text Excel keeps in a cell.
+const maxCellText = 32767
+
+// Writer streams a single-sheet workbook: a bold, frozen header row, then
+// the rows as they are written. Close must be called to finish the file.
+type Writer struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	columns []Column
+	row     int
+	err     error
+}
+
+// NewWriter starts a workbook on w with one sheet of the given columns.
+func NewWriter(w io.Writer, sheetName string, columns []Column) (*Writer, error) {
+	if len(columns) == 0 || len(columns) > MaxColumns {
+		return nil, fmt.Errorf("a sheet needs 1 to %d columns", MaxColumns)
+	}
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, attr(sheetTitle(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &Writer{zw: zw, sheet: bufio.NewWriter(f), columns: columns}
+	xw.printf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0">` +
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`</sheetView></sheetViews><cols>`)
+	for i, c := range columns {
+		width := c.Width
+		if width == 0 {
+			width = float64(max(10, min(50, utf8.RuneCountInString(c.Header)+2)))
+		}
+		xw.printf(`<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	xw.printf(`</cols><sheetData>`)
+
+	headers := make([]interface{}, len(columns))
+	for i, c := range columns {
+		headers[i] = c.Header
+	}
+	if err := xw.writeRow(headers, styleHeader); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+// WriteRow appends a row, one value per column: a string, bool, integer,
+// float or time.Time, or nil for an empty cell. Numbers and times take the
+// column's Format; a time in a General column shows as a date, with the
+// time of day if it has one.
+func (w *Writer) WriteRow(values ...interface{}) error {
+	if len(values) > len(w.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(values), len(w.columns))
+	}
+	return w.writeRow(values, 0)
+}
+
+func (w *Writer) writeRow(values []interface{}, style int) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.row >= MaxRows {
+		return fmt.Errorf("the sheet has more than %d rows", MaxRows)
+	}
+	w.row++
+	w.printf(`<row r="%d">`, w.row)
+	for i, v := range values {
+		w.writeCell(cellRef(i, w.row), v, w.columns[i].Format, style)
+	}
+	w.printf(`</row>`)
+	return w.err
+}
+
+func (w *Writer) writeCell(ref string, v interface{}, format Format, style int) {
+	var num string
+	switch v := v.(type) {
+	case nil:
+		return
+	case string:
+		w.writeText(ref, v, style)
+		return
+	case bool:
+		b := "0"
+		if v {
+			b = "1"
+		}
+		w.printf(`<c r="%s" t="b"%s><v>%s</v></c>`, ref, styleAttr(style), b)
+		return
+	case int:
+		num = strconv.Itoa(v)
+	case int32:
+		num = strconv.FormatInt(int64(v), 10)
+	case int64:
+		num = strconv.FormatInt(v, 10)
+	case float32:
+		num = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		num = strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		style = styleDate
+		if format == DateTime || format == General && v.Hour()+v.Minute()+v.Second() > 0 {
+			style = styleDateTime
+		}
+		num = strconv.FormatFloat(serial(v), 'f', -1, 64)
+	default:
+		w.writeText(ref, fmt.Sprint(v), style)
+		return
+	}
+	if style == 0 && format == Decimal {
+		style = styleDecimal
+	}
+	w.printf(`<c r="%s"%s><v>%s</v></c>`, ref, styleAttr(style), num)
+}
+
+func (w *Writer) writeText(ref, s string, style int) {
+	if s == "" {
+		return
+	}
+	if utf8.RuneCountInString(s) > maxCellText {
+		s = string([]rune(s)[:maxCellText])
+	}
+	w.printf(`<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, styleAttr(style))
+	if w.err == nil {
+		w.err = xml.EscapeText(w.sheet, []byte(s))
+	}
+	w.printf(`</t></is></c>`)
+}
+
+// Close finishes the sheet and the workbook. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	w.printf(`</sheetData></worksheet>`)
+	if w.err == nil {
+		w.err = w.sheet.Flush()
+	}
+	if err := w.zw.Close(); w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *Writer) printf(format string, args ...interface{}) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.sheet, format, args...)
+	}
+}
+
+func styleAttr(style int) string {
+	if style == 0 {
+		return ""
+	}
+	return ` s="` + strconv.Itoa(style) + `"`
+}
+
+// cellRef is the reference of the 0-based column col in row, e.g. "AB12".
+func cellRef(col, row int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name) + strconv.Itoa(row)
+}
+
+// serial converts a time to a 1900-system date serial, the inverse of
+// serialTime for dates after February 1900. The wall clock is kept as is.
+func serial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return wall.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+}
+
+// sheetTitle makes name a valid sheet name: at most 31 characters, none of
+// []:*?/\.
+func sheetTitle(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if utf8.RuneCountInString(name) > 31 {
+		name = string([]rune(name)[:31])
+	}
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
+
+// attr escapes s for an XML attribute value.
+func attr(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s)) // quotes too; a Builder does not fail
+	return b.String()
+}
+
+const contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// stylesXML defines the cell styles: default, header (bold), Decimal
+// (builtin format 4), Date and DateTime.
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,98 @@
+-- Migration 012: Fine-grained permissions
+-- Replaces the linear viewer < company_owner < admin < super_admin ladder with
+-- named permissions grouped into admin-editable roles.
+-- The four existing roles are seeded as system roles with grants that match
+-- the previous route guards, so day-1 behavior is unchanged.
+
+-- ── 1. Roles ─────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS roles (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name         VARCHAR(50) NOT NULL UNIQUE,
+    display_name VARCHAR(100) NOT NULL,
+    description  TEXT NOT NULL DEFAULT '',
+    is_system    BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO roles (name, display_name, description, is_system) VALUES
+    ('viewer',        'Viewer',        'Read-only access to assigned companies',                TRUE),
+    ('company_owner', 'Company Owner', 'Manages employees, documents and salary of own companies', TRUE),
+    ('admin',         'Admin',         'Manages all companies, users and settings',             TRUE),
+    ('super_admin',   'Super Admin',   'Full access, including role management',                TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+-- ── 2. Role → permission grants ──────────────────────────────
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role       VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission)
+SELECT r, p FROM (VALUES
+    ('viewer', 'dashboard.read'),
+    ('viewer', 'activity.read'),
+    ('viewer', 'companies.read'),
+    ('viewer', 'employees.read'),
+    ('viewer', 'employees.export'),
+    ('viewer', 'documents.read'),
+    ('viewer', 'documents.download'),
+    ('viewer', 'salary.read'),
+    ('viewer', 'salary.export'),
+
+    ('company_owner', 'dashboard.read'),
+    ('company_owner', 'activity.read'),
+    ('company_owner', 'companies.read'),
+    ('company_owner', 'employees.read'),
+    ('company_owner', 'employees.write'),
+    ('company_owner', 'employees.delete'),
+    ('company_owner', 'employees.export'),
+    ('company_owner', 'documents.read'),
+    ('company_owner', 'documents.write'),
+    ('company_owner', 'documents.delete'),
+    ('company_owner', 'documents.download'),
+    ('company_owner', 'files.upload'),
+    ('company_owner', 'salary.read'),
+    ('company_owner', 'salary.write'),
+    ('company_owner', 'salary.approve'),
+    ('company_owner', 'salary.export'),
+
+    ('admin', 'dashboard.read'),
+    ('admin', 'activity.read'),
+    ('admin', 'companies.read'),
+    ('admin', 'companies.write'),
+    ('admin', 'companies.all'),
+    ('admin', 'employees.read'),
+    ('admin', 'employees.write'),
+    ('admin', 'employees.delete'),
+    ('admin', 'employees.export'),
+    ('admin', 'documents.read'),
+    ('admin', 'documents.write'),
+    ('admin', 'documents.delete'),
+    ('admin', 'documents.download'),
+    ('admin', 'files.upload'),
+    ('admin', 'salary.read'),
+    ('admin', 'salary.write'),
+    ('admin', 'salary.approve'),
+    ('admin', 'salary.export'),
+    ('admin', 'users.manage'),
+    ('admin', 'settings.manage')
+) AS seed(r, p)
+WHERE NOT EXISTS (SELECT 1 FROM role_permissions LIMIT 1);
+
+-- super_admin is granted every permission in code and needs no rows here.
+
+-- ── 3. Link users to roles ───────────────────────────────────
+
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(50);
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
+
+-- Unknown legacy values fall back to viewer before the FK is added
+UPDATE users SET role = 'viewer' WHERE role NOT IN (SELECT name FROM roles);
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey
+    FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
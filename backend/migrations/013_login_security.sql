@@ -0,0 +1,24 @@
+-- Migration 013: Account lockout and login audit trail
+-- Per-account failed-attempt counters with progressive lockout (enforced in
+-- AuthHandler.Login) and a login_events table recording every sign-in attempt.
+
+-- ── 1. Lockout state on users ────────────────────────────────
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+-- ── 2. Login events ──────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS login_events (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID REFERENCES users(id) ON DELETE SET NULL,  -- NULL for unknown emails
+    email      VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    success    BOOLEAN NOT NULL,
+    reason     VARCHAR(50) NOT NULL,  -- success, invalid_password, unknown_email, account_locked
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_events_created ON login_events(created_at DESC);
@@ -0,0 +1,17 @@
+-- Migration 014: Session management
+-- Every login creates a session row; its id travels in the JWT "sid" claim and
+-- middleware.Auth rejects tokens whose session is revoked or expired.
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_address   VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent   TEXT NOT NULL DEFAULT '',
+    device       VARCHAR(100) NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,39 @@
+-- Migration 015: API keys
+-- Long-lived credentials for scripts (payroll, BI exports). Only the SHA-256
+-- hash of a key is stored; the plaintext is shown once at creation.
+-- A key acts as its owner, narrowed to read-only or read-write access and,
+-- unless all_companies is set, to the companies listed in api_key_companies.
+
+-- ── 1. Keys ──────────────────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    prefix        VARCHAR(16) NOT NULL,
+    key_hash      CHAR(64) NOT NULL UNIQUE,
+    access        VARCHAR(10) NOT NULL CHECK (access IN ('read', 'write')),
+    all_companies BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    last_used_ip  VARCHAR(64),
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+-- ── 2. Company restriction ───────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS api_key_companies (
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    PRIMARY KEY (api_key_id, company_id)
+);
+
+-- ── 3. Permission ────────────────────────────────────────────
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('company_owner', 'api_keys.manage'),
+    ('admin',         'api_keys.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,31 @@
+-- Migration 016: OpenID Connect single sign-on
+-- Users can be linked to an identity at the corporate IdP via its stable
+-- subject ("sub") claim. In-flight logins keep their PKCE verifier and nonce
+-- in oidc_auth_requests until the callback. app_settings holds runtime
+-- switches editable by admins, starting with the password-login fallback.
+
+-- ── 1. Link users to IdP identities ──────────────────────────
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc_subject ON users(oidc_subject) WHERE oidc_subject IS NOT NULL;
+
+-- ── 2. Pending authorization requests ────────────────────────
+
+CREATE TABLE IF NOT EXISTS oidc_auth_requests (
+    state         VARCHAR(64) PRIMARY KEY,
+    nonce         VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ── 3. Runtime settings ──────────────────────────────────────
+
+CREATE TABLE IF NOT EXISTS app_settings (
+    key        VARCHAR(100) PRIMARY KEY,
+    value      JSONB NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO app_settings (key, value) VALUES ('password_login_enabled', 'true')
+ON CONFLICT (key) DO NOTHING;
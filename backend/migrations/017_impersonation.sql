@@ -0,0 +1,10 @@
+-- Migration 017: Super-admin impersonation
+-- Impersonation runs in its own short-lived session for the target user,
+-- tagged with the super admin who started it. Activity recorded during that
+-- session keeps both identities: user_id is the impersonated user,
+-- impersonator_id the person actually acting.
+
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS impersonator_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE activity_log ADD COLUMN IF NOT EXISTS impersonator_id UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_activity_log_impersonator ON activity_log(impersonator_id) WHERE impersonator_id IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Migration 018: Store file references as storage keys
+-- Files are no longer publicly reachable; the API mints short-lived signed URLs
+-- (HMAC for local storage, presigned GETs for R2) after a company-scope check.
+-- Existing values are full URLs such as
+--   http://localhost:8080/api/files/documents/1700000000_visa.pdf
+--   https://pub-xxx.r2.dev/documents/1700000000_visa.pdf
+-- and become the bare key "documents/1700000000_visa.pdf".
+
+-- ── 1. Documents ───────────────────────────────────────────────
+UPDATE documents
+SET file_url = regexp_replace(file_url, '^https?://[^/]+/(.*/)?api/files/|^https?://[^/]+/', '')
+WHERE file_url ~ '^https?://';
+
+-- ── 2. Employee photos ─────────────────────────────────────────
+UPDATE employees
+SET photo_url = regexp_replace(photo_url, '^https?://[^/]+/(.*/)?api/files/|^https?://[^/]+/', '')
+WHERE photo_url ~ '^https?://';
@@ -0,0 +1,23 @@
+-- Migration 019: Orphaned file garbage collection
+-- Stored objects that no document or employee references are first moved
+-- under quarantine/ and only deleted once they have sat there for the
+-- quarantine period. Each run (scheduled or triggered by an admin) is
+-- recorded here with what it found and how many bytes it reclaimed.
+
+CREATE TABLE IF NOT EXISTS file_gc_runs (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    triggered_by      UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for the scheduled job
+    dry_run           BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at       TIMESTAMPTZ,
+    objects_scanned   INTEGER NOT NULL DEFAULT 0,
+    orphans_found     INTEGER NOT NULL DEFAULT 0,
+    quarantined       INTEGER NOT NULL DEFAULT 0,
+    restored          INTEGER NOT NULL DEFAULT 0,
+    deleted           INTEGER NOT NULL DEFAULT 0,
+    bytes_quarantined BIGINT NOT NULL DEFAULT 0,
+    bytes_reclaimed   BIGINT NOT NULL DEFAULT 0,
+    errors            JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_gc_runs_started ON file_gc_runs(started_at DESC);
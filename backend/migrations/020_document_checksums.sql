@@ -0,0 +1,14 @@
+-- Migration 020: Content-addressed uploads
+-- Uploads are now stored as <category>/<aa>/<sha256><ext>, so identical
+-- files are stored once. documents.file_sha256 records the checksum the file
+-- must match when it is downloaded. Older uploads have no checksum in their
+-- key; theirs is recorded the first time the file is downloaded.
+
+-- ── 1. Checksum column ──────────────────────────────────────────
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS file_sha256 CHAR(64);
+
+-- ── 2. Backfill from content-addressed keys ─────────────────────
+UPDATE documents
+SET file_sha256 = substring(file_url FROM '([0-9a-f]{64})(\.[A-Za-z0-9]+)?$')
+WHERE file_sha256 IS NULL
+  AND file_url ~ '(^|/)[0-9a-f]{64}(\.[A-Za-z0-9]+)?$';
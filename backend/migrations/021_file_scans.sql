@@ -0,0 +1,20 @@
+-- Migration 021: Malware scanning
+-- One row per stored file (by storage key) with the outcome of its malware
+-- scan. Uploads are scanned before they are saved; files uploaded while the
+-- scanner was unavailable, and files from before scanning existed, are
+-- pending until the background rescan reaches them. Pending and infected
+-- files cannot be downloaded.
+
+-- ── 1. Scan results ─────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS file_scans (
+    key        TEXT PRIMARY KEY,
+    sha256     CHAR(64),
+    status     VARCHAR(10) NOT NULL DEFAULT 'pending'
+               CHECK (status IN ('pending', 'clean', 'infected')),
+    signature  TEXT,            -- detected malware name, when infected
+    scanner    VARCHAR(20) NOT NULL,
+    scanned_at TIMESTAMPTZ,     -- NULL while pending
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_scans_pending ON file_scans(created_at) WHERE status = 'pending';
@@ -0,0 +1,17 @@
+-- Migration 022: Thumbnails and previews
+-- Downscaled JPEG copies of uploaded images and of the first page of PDFs,
+-- stored next to the original ("<key without extension>.<variant>.jpg").
+-- Rows are written by the upload handler and by `api backfill-thumbnails`
+-- for files uploaded earlier; handlers read them to add thumbnailUrl and
+-- previewUrl to documents and employees.
+
+-- ── 1. Derivatives ──────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS file_derivatives (
+    key            TEXT NOT NULL,         -- storage key of the original
+    variant        VARCHAR(20) NOT NULL,  -- thumb, medium, preview
+    derivative_key TEXT NOT NULL,
+    width          INTEGER NOT NULL,
+    height         INTEGER NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (key, variant)
+);
@@ -0,0 +1,20 @@
+-- Migration 023: Resumable uploads
+-- Large scans are sent in chunks: a session records the declared length and
+-- how many bytes have arrived, and each chunk is stored as an object under
+-- staging/<session id>/<offset>. Finalize assembles the chunks into a normal
+-- upload and deletes the session; sessions that stop receiving chunks expire
+-- and are cleaned up with their chunks.
+
+-- ── 1. Upload sessions ──────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category      VARCHAR(32) NOT NULL,
+    file_name     TEXT NOT NULL,
+    upload_length BIGINT NOT NULL CHECK (upload_length > 0),
+    upload_offset BIGINT NOT NULL DEFAULT 0 CHECK (upload_offset <= upload_length),
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires ON upload_sessions(expires_at);
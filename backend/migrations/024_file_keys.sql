@@ -0,0 +1,19 @@
+-- Migration 024: Encryption at rest
+-- With STORAGE_ENCRYPTION_KEYS set, every stored file is encrypted with its
+-- own random data key (AES-256-GCM). The file starts with a header naming
+-- its data key; the data key itself is kept here, wrapped (encrypted) by one
+-- of the configured master keys. Rotating the master key re-wraps these rows
+-- (`api rotate-storage-keys`) and leaves the files untouched; deleting a row
+-- makes its file unreadable.
+
+-- ── 1. Wrapped data keys ────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS file_keys (
+    id            UUID PRIMARY KEY,      -- data key id, as written in the file header
+    master_key_id VARCHAR(64) NOT NULL,  -- which master key wraps it
+    wrapped_key   BYTEA NOT NULL,        -- nonce || AES-GCM(master, data key)
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at    TIMESTAMPTZ
+);
+
+-- Rotation looks for keys not yet wrapped by the current master key
+CREATE INDEX IF NOT EXISTS idx_file_keys_master ON file_keys(master_key_id);
@@ -0,0 +1,23 @@
+-- Migration 025: Document download log
+-- One row per file handed out by GET /api/documents/{id}/download. Issued
+-- copies (with a purpose and recipient) are watermarked with the notice and
+-- reference recorded here, so a copy that turns up elsewhere can be traced
+-- back to who downloaded it and who it was for. Rows outlive the document.
+
+-- ── 1. Downloads ────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS document_downloads (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id   UUID REFERENCES documents(id) ON DELETE SET NULL,
+    employee_id   UUID REFERENCES employees(id) ON DELETE SET NULL,
+    document_type VARCHAR(100) NOT NULL,
+    user_id       UUID REFERENCES users(id) ON DELETE SET NULL,
+    reference     VARCHAR(16) UNIQUE,  -- printed on issued copies; NULL for plain downloads
+    recipient     VARCHAR(200),
+    purpose       VARCHAR(200),
+    redactions    INTEGER NOT NULL DEFAULT 0,
+    source_sha256 CHAR(64),            -- the stored file
+    copy_sha256   CHAR(64) NOT NULL,   -- the bytes sent
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_downloads_document ON document_downloads(document_id, created_at DESC);
@@ -0,0 +1,36 @@
+-- Migration 026: Multiple files per document
+-- A document can have several ordered, labelled attachments: the front and
+-- back of an ID card, the pages of a contract. documents.file_url,
+-- file_name, file_size, file_type and file_sha256 stay as a copy of the
+-- first attachment (position 0), kept in sync by the API, so compliance,
+-- notifications and lists that only need "the file" are unchanged.
+
+-- ── 1. Attachments ──────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS document_files (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL CHECK (position >= 0),
+    label       VARCHAR(50),
+    file_url    TEXT NOT NULL,                -- storage key
+    file_name   VARCHAR(255) NOT NULL DEFAULT '',
+    file_size   BIGINT NOT NULL DEFAULT 0,
+    file_type   VARCHAR(50) NOT NULL DEFAULT '',
+    file_sha256 CHAR(64),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Deferred so a reorder can swap positions within one transaction
+    CONSTRAINT uq_document_files_position UNIQUE (document_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+-- File GC, scans and storage migration look files up by key
+CREATE INDEX IF NOT EXISTS idx_document_files_file_url ON document_files(file_url);
+
+-- ── 2. Existing single-file documents ───────────────────────────
+INSERT INTO document_files (document_id, position, file_url, file_name, file_size, file_type, file_sha256, created_at)
+SELECT d.id, 0, d.file_url, d.file_name, d.file_size, d.file_type, d.file_sha256, d.created_at
+FROM documents d
+WHERE COALESCE(d.file_url, '') <> ''
+  AND NOT EXISTS (SELECT 1 FROM document_files f WHERE f.document_id = d.id);
+
+-- ── 3. Download log ─────────────────────────────────────────────
+-- Set when a single attachment was downloaded; NULL for the whole document
+ALTER TABLE document_downloads ADD COLUMN IF NOT EXISTS file_id UUID REFERENCES document_files(id) ON DELETE SET NULL;
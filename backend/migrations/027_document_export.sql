@@ -0,0 +1,8 @@
+-- Migration 027: Document export permission
+-- GET /api/documents/export lists document numbers, dates and compliance
+-- status across employees, so it gets its own permission. Every role that
+-- may export employees may export their documents too.
+
+INSERT INTO role_permissions (role, permission)
+SELECT role, 'documents.export' FROM role_permissions WHERE permission = 'employees.export'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,44 @@
+-- Migration 028: Employment history
+-- Moving an employee to another company is an explicit transfer with an
+-- effective date and a reason (POST /api/employees/{id}/transfer), recorded
+-- as consecutive periods here. employees.company_id stays the current
+-- company. Salary records keep the company the employee belonged to in the
+-- month they are for, so past payroll still counts against that company.
+-- Both reference companies ON DELETE RESTRICT: a company with employment or
+-- payroll history cannot be deleted, so that history is never lost with it.
+
+-- ── 1. Periods ──────────────────────────────────────────────────
+-- [start_date, end_date): end_date is the effective date of the transfer
+-- that ended the period; NULL for the current one.
+CREATE TABLE IF NOT EXISTS employment_history (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    employee_id    UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    company_id     UUID NOT NULL REFERENCES companies(id) ON DELETE RESTRICT,
+    start_date     DATE NOT NULL,
+    end_date       DATE,
+    reason         TEXT,                -- why the employee moved here; NULL for the first period
+    transferred_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_date IS NULL OR end_date > start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_employment_history_employee ON employment_history(employee_id, start_date);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_employment_history_current
+    ON employment_history(employee_id) WHERE end_date IS NULL;
+
+-- Every existing employee has been with their company since joining
+INSERT INTO employment_history (employee_id, company_id, start_date)
+SELECT e.id, e.company_id, e.joining_date
+FROM employees e
+WHERE NOT EXISTS (SELECT 1 FROM employment_history h WHERE h.employee_id = e.id);
+
+-- ── 2. Salary attribution ───────────────────────────────────────
+ALTER TABLE salary_records ADD COLUMN IF NOT EXISTS company_id UUID REFERENCES companies(id) ON DELETE RESTRICT;
+
+UPDATE salary_records s SET company_id = e.company_id
+FROM employees e
+WHERE e.id = s.employee_id AND s.company_id IS NULL;
+
+ALTER TABLE salary_records ALTER COLUMN company_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_salary_company ON salary_records(company_id, year, month);
@@ -0,0 +1,19 @@
+-- Migration 029: Field-level change history
+-- Edits of employees and documents store what changed, field by field, with
+-- the old and new values ("expiry_date: 2025-01-01 → 2027-01-01"). One row
+-- per edit, written in the same transaction as the edit itself; served by
+-- GET /api/employees/{id}/history and GET /api/documents/{id}/history.
+-- activity_log keeps the who-did-what summary.
+
+CREATE TABLE IF NOT EXISTS change_history (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type     VARCHAR(50) NOT NULL,   -- "employee", "document"
+    entity_id       UUID NOT NULL,          -- no FK: the history outlives the entity
+    action          VARCHAR(50) NOT NULL,   -- "updated"
+    changes         JSONB NOT NULL,         -- [{"field": "expiry_date", "from": "2025-01-01", "to": "2027-01-01"}]
+    user_id         UUID REFERENCES users(id) ON DELETE SET NULL,
+    impersonator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_history_entity ON change_history(entity_type, entity_id, created_at DESC);
@@ -0,0 +1,19 @@
+-- Migration 030: Upload ownership
+-- Uploads are stored before the document or employee that uses them exists,
+-- so a stored key on its own says nothing about who may attach it. Every
+-- upload (or checksum lookup of a file the caller may already use) records
+-- the key and the user here; documents and employees only accept keys their
+-- caller uploaded or that something in their company scope already uses.
+--
+-- Uploads are content-addressed too, so uploading a file that is already
+-- stored reuses the existing object without writing it, and its
+-- modification time stays old. The orphan cleanup judges the grace age by
+-- that time, so it leaves keys recorded within the grace age alone and
+-- prunes older rows; an upload must be attached within the grace age.
+
+CREATE TABLE IF NOT EXISTS file_uploads (
+    key          TEXT NOT NULL,          -- storage key
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    uploaded_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (key, user_id)
+);
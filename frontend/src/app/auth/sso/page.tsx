@@ -0,0 +1,63 @@
+'use client';
+
+import { useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { Loader2 } from 'lucide-react';
+import { useAuth } from '@/context/auth-context';
+
+/**
+ * Landing page of single sign-on. The backend's OIDC callback redirects here
+ * with `#token=…` on success or `#error=…` on failure; the fragment never
+ * reaches server logs. The token is stored like a password login's.
+ */
+export default function SSOCallbackPage() {
+    const { loginWithToken } = useAuth();
+    const [error, setError] = useState('');
+    const handled = useRef(false);
+
+    useEffect(() => {
+        if (handled.current) return;
+        handled.current = true;
+
+        const params = new URLSearchParams(window.location.hash.slice(1));
+        // Keep the token out of the history and the address bar
+        window.history.replaceState(null, '', window.location.pathname);
+
+        const token = params.get('token');
+        if (!token) {
+            setError(params.get('error') || 'Sign-in failed, please try again');
+            return;
+        }
+        loginWithToken(token).catch((err) => {
+            setError(err instanceof Error ? err.message : 'Sign-in failed, please try again');
+        });
+    }, [loginWithToken]);
+
+    return (
+        <div className="min-h-screen flex items-center justify-center bg-background p-4">
+            <Card className="w-full max-w-sm shadow-lg border-border/60">
+                <CardHeader className="space-y-1">
+                    <CardTitle className="text-xl">Single sign-on</CardTitle>
+                    <CardDescription>{error ? 'Sign-in did not complete' : 'Signing you in…'}</CardDescription>
+                </CardHeader>
+                <CardContent>
+                    {error ? (
+                        <div className="space-y-4">
+                            <div className="p-3 rounded-lg bg-red-50 dark:bg-red-950/30 text-red-600 dark:text-red-400 text-sm">
+                                {error}
+                            </div>
+                            <Link href="/login" className="text-sm text-blue-600 dark:text-blue-400 hover:underline font-medium">
+                                Back to sign in
+                            </Link>
+                        </div>
+                    ) : (
+                        <div className="flex justify-center py-4">
+                            <Loader2 className="h-6 w-6 animate-spin text-muted-foreground" />
+                        </div>
+                    )}
+                </CardContent>
+            </Card>
+        </div>
+    );
+}
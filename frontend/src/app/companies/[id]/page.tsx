@@ -17,6 +17,7 @@ interface CompanyEmployee {
     trade: string;
     status: string;
     photoUrl?: string | null;
+    thumbnailUrl?: string | null;
     nationality?: string | null;
     complianceStatus: string;
     urgentDocType?: string | null;
@@ -111,8 +111,8 @@ export default function CompaniesPage() {
             await api.companies.delete(id);
             toast.success('Company deleted');
             fetchCompanies();
-        } catch {
-            toast.error('Failed to delete company');
+        } catch (err) {
+            toast.error(err instanceof Error ? err.message : 'Failed to delete company');
         }
     };
 
@@ -343,8 +343,9 @@ export default function CompaniesPage() {
                                                 <AlertDialogHeader>
                                                     <AlertDialogTitle>Delete {company.name}?</AlertDialogTitle>
                                                     <AlertDialogDescription>
-                                                        This will also delete all {company.employeeCount} employees
-                                                        and their documents. This action cannot be undone.
+                                                        This action cannot be undone. Companies that have or had
+                                                        employees keep their employment and payroll history and
+                                                        cannot be deleted.
                                                     </AlertDialogDescription>
                                                 </AlertDialogHeader>
                                                 <AlertDialogFooter>
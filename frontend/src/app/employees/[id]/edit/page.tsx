@@ -47,6 +47,10 @@ export default function EditEmployeePage() {
         status: 'active',
     });
     const [isCustomTrade, setIsCustomTrade] = useState(false);
+    // Changing the company is a transfer, with its own date and reason
+    const [currentCompanyId, setCurrentCompanyId] = useState('');
+    const [transfer, setTransfer] = useState({ effectiveDate: '', reason: '' });
+    const isTransfer = !!currentCompanyId && form.companyId !== currentCompanyId;
     const [errors, setErrors] = useState<Record<string, string>>({});
 
     const fetchData = useCallback(async () => {
@@ -58,6 +62,7 @@ export default function EditEmployeePage() {
             ]);
             const emp = empRes.data as EmployeeWithCompany;
             setCompanies(compRes.data || []);
+            setCurrentCompanyId(emp.companyId);
             setForm({
                 companyId: emp.companyId,
                 name: emp.name,
@@ -103,6 +108,10 @@ export default function EditEmployeePage() {
             errs.mobile = 'Enter a valid mobile number (e.g. +971501234567)';
         }
         if (!form.joiningDate) errs.joiningDate = 'Joining date is required';
+        if (isTransfer) {
+            if (!transfer.effectiveDate) errs.effectiveDate = 'Effective date is required';
+            if (transfer.reason.trim().length < 3) errs.reason = 'Reason is required (min 3 characters)';
+        }
         setErrors(errs);
         return Object.keys(errs).length === 0;
     };
@@ -113,6 +122,18 @@ export default function EditEmployeePage() {
 
         try {
             setSubmitting(true);
+            if (isTransfer) {
+                const res = await api.employees.transfer(id, {
+                    companyId: form.companyId,
+                    effectiveDate: transfer.effectiveDate,
+                    reason: transfer.reason.trim(),
+                });
+                setCurrentCompanyId(form.companyId);
+                const { addedDocuments, ruleChanges } = res.data;
+                if (addedDocuments.length > 0 || ruleChanges.length > 0) {
+                    toast.info(`Transferred: ${addedDocuments.length} document slot(s) added, ${ruleChanges.length} compliance rule change(s)`);
+                }
+            }
             await api.employees.update(id, form);
             toast.success('Employee updated successfully!');
             router.push(`/employees/${id}`);
@@ -183,6 +204,27 @@ export default function EditEmployeePage() {
                             {errors.companyId && <p className="text-sm text-red-500">{errors.companyId}</p>}
                         </div>
 
+                        {/* Transfer — only when the company changes */}
+                        {isTransfer && (
+                            <div className="grid grid-cols-1 sm:grid-cols-2 gap-4 rounded-md border border-border p-4">
+                                <p className="sm:col-span-2 text-sm text-muted-foreground">
+                                    This transfers the employee. Salary from the effective date on counts for the new company; earlier months stay with the current one.
+                                </p>
+                                <div className="space-y-2">
+                                    <Label htmlFor="effectiveDate">Effective Date *</Label>
+                                    <Input id="effectiveDate" type="date" value={transfer.effectiveDate} max={new Date().toISOString().split('T')[0]}
+                                        onChange={(e) => setTransfer({ ...transfer, effectiveDate: e.target.value })} />
+                                    {errors.effectiveDate && <p className="text-sm text-red-500">{errors.effectiveDate}</p>}
+                                </div>
+                                <div className="space-y-2">
+                                    <Label htmlFor="reason">Reason *</Label>
+                                    <Input id="reason" placeholder="e.g. Project reassignment" value={transfer.reason}
+                                        onChange={(e) => setTransfer({ ...transfer, reason: e.target.value })} />
+                                    {errors.reason && <p className="text-sm text-red-500">{errors.reason}</p>}
+                                </div>
+                            </div>
+                        )}
+
                         {/* Mobile */}
                         <div className="space-y-2">
                             <Label htmlFor="mobile">Mobile *</Label>
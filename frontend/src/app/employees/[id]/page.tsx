@@ -474,16 +474,22 @@ export default function EmployeeDetailPage() {
                                                 <div className="flex items-center gap-2">
                                                     <h4 className="font-medium text-foreground text-sm">{docName}{doc.isMandatory && <span className="text-red-400 text-[10px] ml-0.5">*</span>}</h4>
                                                     {doc.fileUrl && (
-                                                        <a
-                                                            href={doc.fileUrl.startsWith('http') ? doc.fileUrl : `${doc.fileUrl}`}
-                                                            target="_blank"
-                                                            rel="noopener noreferrer"
+                                                        <button
+                                                            type="button"
                                                             className="text-blue-600 hover:text-blue-700 dark:text-blue-400"
                                                             title="View file"
-                                                            onClick={(e) => e.stopPropagation()}
+                                                            onClick={async (e) => {
+                                                                e.stopPropagation();
+                                                                try {
+                                                                    const res = await api.documents.fileUrl(doc.id);
+                                                                    window.open(res.data.url, '_blank', 'noopener,noreferrer');
+                                                                } catch {
+                                                                    toast.error('Could not open file');
+                                                                }
+                                                            }}
                                                         >
                                                             <ExternalLink className="h-3 w-3" />
-                                                        </a>
+                                                        </button>
                                                     )}
                                                 </div>
                                                 <p className="text-xs text-muted-foreground">
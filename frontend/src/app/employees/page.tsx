@@ -218,11 +218,19 @@ export default function EmployeesPage() {
         setPage(1);
     };
 
-    /** Export employees CSV */
-    const handleExport = async () => {
+    /** Export the employees matching the current filters */
+    const handleExport = async (format: 'csv' | 'xlsx') => {
         try {
             setExporting(true);
-            await api.employees.export();
+            await api.employees.export({
+                sort_by: sortBy,
+                sort_order: sortOrder,
+                search: debouncedSearch || undefined,
+                company_id: companyFilter !== 'all' ? companyFilter : undefined,
+                status: docStatusFilter !== 'all' ? docStatusFilter : undefined,
+                emp_status: empStatusFilter,
+                trade: tradeFilter || undefined,
+            }, { format });
             toast.success('Employee data exported');
         } catch {
             toast.error('Failed to export');
@@ -243,10 +251,13 @@ export default function EmployeesPage() {
                     <p className="text-muted-foreground mt-1">{total} employee{total !== 1 ? 's' : ''} found</p>
                 </div>
                 <div className="flex gap-2">
-                    <Button variant="outline" size="sm" onClick={handleExport} disabled={exporting}>
+                    <Button variant="outline" size="sm" onClick={() => handleExport('csv')} disabled={exporting}>
                         {exporting ? <Loader2 className="h-4 w-4 mr-1 animate-spin" /> : <Download className="h-4 w-4 mr-1" />}
                         Export CSV
                     </Button>
+                    <Button variant="outline" size="sm" onClick={() => handleExport('xlsx')} disabled={exporting}>
+                        <Download className="h-4 w-4 mr-1" /> Excel
+                    </Button>
                     {canWrite && (
                         <Link href="/employees/new">
                             <Button size="sm" className="gap-1"><Plus className="h-4 w-4" /> Add Employee</Button>
@@ -403,7 +414,7 @@ export default function EmployeesPage() {
                                                 {/* Avatar */}
                                                 <div className="w-10 h-10 rounded-full overflow-hidden flex-shrink-0">
                                                     {emp.photoUrl ? (
-                                                        <img src={emp.photoUrl} alt={emp.name} className="w-full h-full object-cover" />
+                                                        <img src={emp.thumbnailUrl || emp.photoUrl} alt={emp.name} className="w-full h-full object-cover" />
                                                     ) : (
                                                         <div className="w-full h-full bg-gradient-to-br from-blue-100 to-indigo-100 dark:from-blue-950 dark:to-indigo-950 flex items-center justify-center">
                                                             <span className="text-blue-700 dark:text-blue-300 font-bold text-sm">
@@ -1,20 +1,46 @@
 'use client';
 
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import Link from 'next/link';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
-import { Loader2, Users } from 'lucide-react';
+import { KeyRound, Loader2, Users } from 'lucide-react';
 import { useAuth } from '@/context/auth-context';
 
+const API_BASE = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
+
+// Sign-in methods, from GET /api/auth/providers
+interface AuthProviders {
+    passwordLoginEnabled: boolean;
+    ssoEnabled: boolean;
+    ssoName?: string;
+    ssoLoginUrl?: string;
+}
+
 export default function LoginPage() {
     const { login } = useAuth();
     const [email, setEmail] = useState('');
     const [password, setPassword] = useState('');
     const [error, setError] = useState('');
     const [loading, setLoading] = useState(false);
+    const [providers, setProviders] = useState<AuthProviders | null>(null);
+
+    useEffect(() => {
+        fetch(`${API_BASE}/api/auth/providers`)
+            .then(res => (res.ok ? res.json() : null))
+            .then(body => setProviders(body?.data ?? null))
+            .catch(() => setProviders(null));
+    }, []);
+
+    // The OIDC flow is a full-page redirect through the backend to the
+    // identity provider, ending on /auth/sso
+    const handleSSO = () => {
+        if (providers?.ssoLoginUrl) {
+            window.location.href = `${API_BASE}${providers.ssoLoginUrl}`;
+        }
+    };
 
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
@@ -49,6 +75,21 @@ export default function LoginPage() {
                         <CardDescription>Sign in to your account</CardDescription>
                     </CardHeader>
                     <CardContent>
+                        {providers?.ssoEnabled && (
+                            <div className="space-y-4 mb-4">
+                                <Button type="button" variant="outline" className="w-full" onClick={handleSSO}>
+                                    <KeyRound className="h-4 w-4 mr-2" />
+                                    Sign in with {providers.ssoName || 'SSO'}
+                                </Button>
+                                <div className="relative text-center text-xs text-muted-foreground">
+                                    <span className="bg-card px-2 relative z-10">
+                                        {providers.passwordLoginEnabled ? 'or with your password' : 'administrators only'}
+                                    </span>
+                                    <div className="absolute inset-x-0 top-1/2 border-t border-border" />
+                                </div>
+                            </div>
+                        )}
+
                         <form onSubmit={handleSubmit} className="space-y-4">
                             {error && (
                                 <div className="p-3 rounded-lg bg-red-50 dark:bg-red-950/30 text-red-600 dark:text-red-400 text-sm">
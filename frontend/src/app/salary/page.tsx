@@ -95,7 +95,7 @@ export default function SalaryPage() {
 
     const handleExport = async () => {
         try {
-            await api.salary.export(month, year);
+            await api.salary.export(month, year, { status: statusFilter !== 'all' ? statusFilter : undefined, company_id: companyFilter || undefined });
             toast.success('Salary data exported');
         } catch {
             toast.error('Failed to export salary data');
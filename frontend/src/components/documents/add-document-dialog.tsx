@@ -103,11 +103,41 @@ export function AddDocumentDialog({ employeeId, open, onOpenChange, onSuccess, p
         setMetadata(autoMeta);
     }, [documentType, employee, config]);
 
+    // Passports and Emirates IDs: read the MRZ and fill in what is still empty
+    const [extracting, setExtracting] = useState(false);
+    const prefillFromScan = useCallback(async (scan: File) => {
+        if (!['passport', 'emirates_id'].includes(documentType) || scan.size > 10 * 1024 * 1024) return;
+        setExtracting(true);
+        try {
+            const result = await api.documents.extract(scan);
+            const trusted = (f?: { value: string; confidence: number }) => (f && f.confidence >= 0.5 ? f.value : undefined);
+            let filled = 0;
+            const fill = (current: string, value: string | undefined, set: (v: string) => void) => {
+                if (!current && value) { set(value); filled++; }
+            };
+            fill(documentNumber, trusted(result.fields.documentNumber), setDocumentNumber);
+            fill(issueDate, trusted(result.fields.issueDate), setIssueDate);
+            fill(expiryDate, trusted(result.fields.expiryDate), setExpiryDate);
+            const meta: Record<string, unknown> = {};
+            for (const [key, f] of Object.entries(result.metadata)) {
+                const value = trusted(f);
+                if (value && !metadata[key] && config?.metadataFields?.some(m => m.key === key)) { meta[key] = value; filled++; }
+            }
+            if (Object.keys(meta).length > 0) setMetadata(prev => ({ ...prev, ...meta }));
+            if (filled > 0) toast.success(`Filled ${filled} field${filled === 1 ? '' : 's'} from the scan. Please check them.`);
+        } catch {
+            // Extraction is a convenience: the fields can still be typed in
+        } finally {
+            setExtracting(false);
+        }
+    }, [documentType, documentNumber, issueDate, expiryDate, metadata, config]);
+
     const handleFileChange = (e: React.ChangeEvent<HTMLInputElement>) => {
         const selected = e.target.files?.[0];
         if (selected) {
-            if (selected.size > 10 * 1024 * 1024) { toast.error('File too large. Maximum 10MB.'); return; }
+            if (selected.size > 50 * 1024 * 1024) { toast.error('File too large. Maximum 50MB.'); return; }
             setFile(selected);
+            prefillFromScan(selected);
         }
     };
 
@@ -115,10 +145,11 @@ export function AddDocumentDialog({ employeeId, open, onOpenChange, onSuccess, p
         e.preventDefault();
         const dropped = e.dataTransfer.files[0];
         if (dropped) {
-            if (dropped.size > 10 * 1024 * 1024) { toast.error('File too large. Maximum 10MB.'); return; }
+            if (dropped.size > 50 * 1024 * 1024) { toast.error('File too large. Maximum 50MB.'); return; }
             setFile(dropped);
+            prefillFromScan(dropped);
         }
-    }, []);
+    }, [prefillFromScan]);
 
     const updateMetadata = (key: string, val: unknown) => {
         setMetadata((prev) => ({ ...prev, [key]: val }));
@@ -144,7 +175,7 @@ export function AddDocumentDialog({ employeeId, open, onOpenChange, onSuccess, p
             let fileData = { fileUrl: '', fileName: '', fileSize: 0, fileType: '' };
             if (file) {
                 const uploaded = await api.upload(file, 'documents');
-                fileData = { fileUrl: uploaded.url, fileName: uploaded.fileName, fileSize: uploaded.fileSize, fileType: uploaded.fileType };
+                fileData = { fileUrl: uploaded.key, fileName: uploaded.fileName, fileSize: uploaded.fileSize, fileType: uploaded.fileType };
             }
 
             await api.documents.create(employeeId, {
@@ -293,8 +324,11 @@ export function AddDocumentDialog({ employeeId, open, onOpenChange, onSuccess, p
                                             <FileIcon className="h-8 w-8 text-blue-600 dark:text-blue-400 flex-shrink-0" />
                                             <div className="flex-1 min-w-0">
                                                 <p className="text-sm font-medium truncate">{file.name}</p>
-                                                <p className="text-xs text-muted-foreground">{(file.size / 1024 / 1024).toFixed(2)} MB</p>
+                                                <p className="text-xs text-muted-foreground">
+                                                    {(file.size / 1024 / 1024).toFixed(2)} MB{extracting && ' · Reading document details…'}
+                                                </p>
                                             </div>
+                                            {extracting && <Loader2 className="h-4 w-4 animate-spin text-muted-foreground" />}
                                             <Button variant="ghost" size="icon" className="h-7 w-7" onClick={() => setFile(null)}>
                                                 <X className="h-4 w-4" />
                                             </Button>
@@ -308,7 +342,7 @@ export function AddDocumentDialog({ employeeId, open, onOpenChange, onSuccess, p
                                                 hover:border-blue-400 dark:hover:border-blue-600 hover:bg-blue-50/50 dark:hover:bg-blue-950/20 transition-colors"
                                         >
                                             <Upload className="h-6 w-6 text-muted-foreground" />
-                                            <p className="text-sm text-muted-foreground">Click or drag & drop (PDF, JPG, PNG — max 10MB)</p>
+                                            <p className="text-sm text-muted-foreground">Click or drag & drop (PDF, JPG, PNG — max 50MB)</p>
                                         </div>
                                     )}
                                     <input ref={fileInputRef} type="file" accept=".pdf,.jpg,.jpeg,.png" onChange={handleFileChange} className="hidden" />
@@ -366,7 +400,7 @@ export function EditDocumentDialog({ document, open, onOpenChange, onSuccess }:
             let fileData: Partial<{ fileUrl: string; fileName: string; fileSize: number; fileType: string }> = {};
             if (file) {
                 const uploaded = await api.upload(file, 'documents');
-                fileData = { fileUrl: uploaded.url, fileName: uploaded.fileName, fileSize: uploaded.fileSize, fileType: uploaded.fileType };
+                fileData = { fileUrl: uploaded.key, fileName: uploaded.fileName, fileSize: uploaded.fileSize, fileType: uploaded.fileType };
             }
 
             await api.documents.update(document.id, {
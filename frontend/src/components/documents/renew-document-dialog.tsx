@@ -44,8 +44,8 @@ export function RenewDocumentDialog({ document, employeeId, open, onOpenChange,
     const handleFileChange = (e: React.ChangeEvent<HTMLInputElement>) => {
         const selected = e.target.files?.[0];
         if (selected) {
-            if (selected.size > 10 * 1024 * 1024) {
-                toast.error('File too large. Maximum 10MB.');
+            if (selected.size > 50 * 1024 * 1024) {
+                toast.error('File too large. Maximum 50MB.');
                 return;
             }
             setFile(selected);
@@ -56,8 +56,8 @@ export function RenewDocumentDialog({ document, employeeId, open, onOpenChange,
         e.preventDefault();
         const dropped = e.dataTransfer.files[0];
         if (dropped) {
-            if (dropped.size > 10 * 1024 * 1024) {
-                toast.error('File too large. Maximum 10MB.');
+            if (dropped.size > 50 * 1024 * 1024) {
+                toast.error('File too large. Maximum 50MB.');
                 return;
             }
             setFile(dropped);
@@ -78,7 +78,7 @@ export function RenewDocumentDialog({ document, employeeId, open, onOpenChange,
             if (file) {
                 const uploaded = await api.upload(file, 'documents');
                 fileData = {
-                    fileUrl: uploaded.url,
+                    fileUrl: uploaded.key,
                     fileName: uploaded.fileName,
                     fileSize: uploaded.fileSize,
                     fileType: uploaded.fileType,
@@ -198,7 +198,7 @@ export function RenewDocumentDialog({ document, employeeId, open, onOpenChange,
                             >
                                 <Upload className="h-6 w-6 text-muted-foreground" />
                                 <p className="text-sm text-muted-foreground">
-                                    Click or drag & drop (PDF, JPG, PNG — max 10MB)
+                                    Click or drag & drop (PDF, JPG, PNG — max 50MB)
                                 </p>
                             </div>
                         )}
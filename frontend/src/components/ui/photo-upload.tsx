@@ -44,7 +44,7 @@ export function PhotoUpload({ value, onChange, name, disabled, size = 'md' }: Ph
         setUploading(true);
         try {
             const result = await api.upload(file, 'photos');
-            onChange(result.url);
+            onChange(result.url ?? result.key);
         } catch {
             toast.error('Failed to upload photo');
         } finally {
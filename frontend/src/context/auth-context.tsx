@@ -21,6 +21,7 @@ interface AuthContextValue {
     isViewer: boolean;
     canWrite: boolean;
     login: (email: string, password: string) => Promise<void>;
+    loginWithToken: (token: string) => Promise<void>;
     register: (name: string, email: string, password: string) => Promise<void>;
     logout: () => void;
 }
@@ -30,7 +31,7 @@ const AuthContext = createContext<AuthContextValue | undefined>(undefined);
 const API_BASE = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
 // Pages that don't require authentication
-const PUBLIC_PATHS = ['/login', '/register'];
+const PUBLIC_PATHS = ['/login', '/register', '/auth/sso'];
 
 /**
  * AuthProvider manages authentication state across the entire app.
@@ -135,6 +136,23 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         router.push('/');
     }, [router]);
 
+    // Single sign-on hands over a token issued by the backend (see /auth/sso)
+    const loginWithToken = useCallback(async (newToken: string) => {
+        const res = await fetch(`${API_BASE}/api/auth/me`, {
+            headers: { Authorization: `Bearer ${newToken}` },
+        });
+
+        if (!res.ok) {
+            throw new Error('Sign-in failed, please try again');
+        }
+
+        const userData: User = await res.json();
+        localStorage.setItem('token', newToken);
+        setToken(newToken);
+        setUser(userData);
+        router.replace('/');
+    }, [router]);
+
     const register = useCallback(async (name: string, email: string, password: string) => {
         const res = await fetch(`${API_BASE}/api/auth/register`, {
             method: 'POST',
@@ -168,7 +186,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     const canWrite = isAdmin || isCompanyOwner;
 
     return (
-        <AuthContext.Provider value={{ user, token, loading, isSuperAdmin, isAdmin, isCompanyOwner, isViewer, canWrite, login, register, logout }}>
+        <AuthContext.Provider value={{ user, token, loading, isSuperAdmin, isAdmin, isCompanyOwner, isViewer, canWrite, login, loginWithToken, register, logout }}>
             {children}
         </AuthContext.Provider>
     );
@@ -4,7 +4,10 @@ import type {
     ComplianceRuleRow,
     Employee,
     EmployeeWithCompany,
+    EmployeeFilters,
+    AddDocumentFileRequest,
     Document,
+    DocumentFile,
     DocumentWithCompliance,
     DashboardMetrics,
     ExpiryAlert,
@@ -14,6 +17,9 @@ import type {
     DependencyAlert,
     CreateEmployeeRequest,
     ExitEmployeeRequest,
+    TransferEmployeeRequest,
+    TransferResult,
+    EmploymentPeriod,
     CreateDocumentRequest,
     CreateCompanyRequest,
     SalaryRecordWithEmployee,
@@ -21,6 +27,7 @@ import type {
     SalaryRecord,
     Notification,
     ActivityLog,
+    ChangeSet,
 } from '@/types';
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
@@ -88,9 +95,12 @@ async function fetcher<T>(
 }
 
 // ── Download Helper (for CSV export) ──────────────────────────
-async function downloadFile(endpoint: string, filename: string) {
+// With a body the request is a JSON POST (e.g. the document archive).
+async function downloadFile(endpoint: string, filename: string, body?: unknown) {
     const url = `${API_BASE_URL}${endpoint}`;
-    const response = await fetch(url, { headers: getAuthHeaders() });
+    const response = await fetch(url, body === undefined
+        ? { headers: getAuthHeaders() }
+        : { method: 'POST', headers: { ...getAuthHeaders(), 'Content-Type': 'application/json' }, body: JSON.stringify(body) });
     if (!response.ok) throw new ApiClientError('Download failed', response.status);
     const blob = await response.blob();
     const a = document.createElement('a');
@@ -100,11 +110,100 @@ async function downloadFile(endpoint: string, filename: string) {
     URL.revokeObjectURL(a.href);
 }
 
+// ── Exports ───────────────────────────────────────────────────
+// columns: keys in output order (e.g. 'name', 'passportNumber',
+// 'complianceStatus', 'expiry.visa'); the server's defaults when omitted.
+export interface DocumentExportFilters {
+    company_id?: string;
+    employee_id?: string;
+    document_type?: string;
+    status?: string; // incomplete, valid, expiring_soon, in_grace, penalty_active
+    search?: string;
+    emp_status?: string;
+}
+
+export interface ExportOptions {
+    format?: 'csv' | 'xlsx';
+    columns?: string[];
+}
+
+function exportFile(endpoint: string, name: string, filters: Record<string, string | number | undefined>, opts: ExportOptions = {}) {
+    const queryParams = new URLSearchParams();
+    Object.entries(filters).forEach(([key, value]) => {
+        if (value !== undefined && value !== '') queryParams.append(key, String(value));
+    });
+    const format = opts.format ?? 'csv';
+    queryParams.append('format', format);
+    if (opts.columns?.length) queryParams.append('columns', opts.columns.join(','));
+    return downloadFile(`${endpoint}?${queryParams}`, `${name}.${format}`);
+}
+
+// A watermarked copy for a third party; redact entries are "[page:]x,y,w,h"
+// as fractions of the page.
+export interface IssuedCopy {
+    recipient: string;
+    purpose: string;
+    redact?: string[];
+}
+
+export interface DocumentDownload {
+    id: string;
+    fileId?: string | null;
+    userId: string | null;
+    userName?: string;
+    reference?: string;
+    recipient?: string;
+    purpose?: string;
+    redactions: number;
+    copySha256: string;
+    createdAt: string;
+}
+
+export interface DocumentArchiveFilter {
+    employeeIds?: string[];
+    companyId?: string;
+    documentTypes?: string[];
+    statuses?: string[];
+}
+
 // ── File Upload Fetcher (multipart) ───────────────────────────
+export interface UploadedFile {
+    key: string;
+    url?: string; // signed preview URL; absent when the server already had the file
+    fileName: string;
+    fileSize: number;
+    fileType: string;
+    sha256: string;
+}
+
+async function sha256Hex(file: File): Promise<string> {
+    const digest = await crypto.subtle.digest('SHA-256', await file.arrayBuffer());
+    return Array.from(new Uint8Array(digest), (b) => b.toString(16).padStart(2, '0')).join('');
+}
+
 async function uploadFile(
     file: File,
     category: string = 'documents'
-): Promise<{ url: string; fileName: string; fileSize: number; fileType: string }> {
+): Promise<UploadedFile> {
+    // Files are stored by content hash: skip the transfer if the server has
+    // it and we may use it (uploaded it before, or it is already on a record
+    // we can see). The lookup returns no URL, and photos need one for their
+    // preview.
+    if (category !== 'photos' && typeof crypto !== 'undefined' && crypto.subtle) {
+        const sum = await sha256Hex(file);
+        const params = new URLSearchParams({ category, fileName: file.name });
+        const existing = await fetch(`${API_BASE_URL}/api/upload/${sum}?${params}`, {
+            headers: getAuthHeaders(),
+        });
+        if (existing.ok) {
+            return existing.json();
+        }
+    }
+
+    if (file.size > SINGLE_UPLOAD_LIMIT) {
+        return uploadResumable(file, category);
+    }
+
     const formData = new FormData();
     formData.append('file', file);
     formData.append('category', category);
@@ -123,6 +222,174 @@ async function uploadFile(
     return response.json();
 }
 
+// Files above this go through a resumable upload session, in chunks
+const SINGLE_UPLOAD_LIMIT = 8 * 1024 * 1024;
+const UPLOAD_CHUNK_SIZE = 4 * 1024 * 1024;
+const UPLOAD_CHUNK_RETRIES = 5;
+
+async function uploadFailed(response: Response): Promise<never> {
+    const err = await response.json().catch(() => ({ message: 'Upload failed' }));
+    throw new ApiClientError(err.message || 'Upload failed', response.status);
+}
+
+/** Sends a large file in chunks; a dropped chunk resumes from the server's offset. */
+async function uploadResumable(file: File, category: string): Promise<UploadedFile> {
+    const created = await fetch(`${API_BASE_URL}/api/uploads`, {
+        method: 'POST',
+        headers: { ...getAuthHeaders(), 'Content-Type': 'application/json' },
+        body: JSON.stringify({ fileName: file.name, fileSize: file.size, category }),
+    });
+    if (!created.ok) await uploadFailed(created);
+    const { data: session } = (await created.json()) as { data: { id: string; offset: number } };
+    const sessionUrl = `${API_BASE_URL}/api/uploads/${session.id}`;
+
+    let offset = session.offset;
+    let failures = 0;
+    while (offset < file.size) {
+        try {
+            const response = await fetch(sessionUrl, {
+                method: 'PATCH',
+                headers: {
+                    ...getAuthHeaders(),
+                    'Content-Type': 'application/offset+octet-stream',
+                    'Upload-Offset': String(offset),
+                },
+                body: file.slice(offset, offset + UPLOAD_CHUNK_SIZE),
+            });
+            if (response.ok || response.status === 409) {
+                offset = Number(response.headers.get('Upload-Offset') ?? offset);
+                failures = 0;
+                continue;
+            }
+            if (response.status < 500) await uploadFailed(response);
+        } catch (e) {
+            if (e instanceof ApiClientError) throw e;
+        }
+
+        // Network error or server hiccup: wait, then ask where to resume
+        if (++failures > UPLOAD_CHUNK_RETRIES) {
+            throw new ApiClientError('Upload interrupted. Please try again.', 0);
+        }
+        await new Promise((resolve) => setTimeout(resolve, 1000 * failures));
+        const status = await fetch(sessionUrl, { headers: getAuthHeaders() }).catch(() => null);
+        if (status?.ok) {
+            offset = Number(status.headers.get('Upload-Offset') ?? offset);
+        } else if (status && status.status === 404) {
+            await uploadFailed(status);
+        }
+    }
+
+    const finished = await fetch(`${sessionUrl}/finalize`, {
+        method: 'POST',
+        headers: getAuthHeaders(),
+    });
+    if (!finished.ok) await uploadFailed(finished);
+    return finished.json();
+}
+
+// ── Field Extraction (passport / ID card MRZ) ─────────────────
+export interface ExtractedField {
+    value: string;
+    confidence: number; // 0–1
+}
+
+export interface DocumentExtraction {
+    documentType: string | null;
+    fields: Partial<Record<'documentNumber' | 'issueDate' | 'expiryDate', ExtractedField>>;
+    employee: Partial<Record<'name' | 'nationality' | 'dateOfBirth' | 'gender' | 'passportNumber', ExtractedField>>;
+    metadata: Record<string, ExtractedField>;
+    source: string;
+    valid: boolean;
+}
+
+async function extractDocumentFields(file: File): Promise<DocumentExtraction> {
+    const formData = new FormData();
+    formData.append('file', file);
+
+    const response = await fetch(`${API_BASE_URL}/api/documents/extract`, {
+        method: 'POST',
+        headers: getAuthHeaders(),
+        body: formData,
+    });
+
+    if (!response.ok) {
+        const err = await response.json().catch(() => ({ message: 'Extraction failed' }));
+        throw new ApiClientError(err.message || 'Extraction failed', response.status);
+    }
+
+    const { data } = await response.json();
+    return data;
+}
+
+// ── Spreadsheet Imports ───────────────────────────────────────
+export interface ImportColumn {
+    header: string;
+    field: string; // "" when the column is ignored
+}
+
+export interface ImportRow {
+    row: number; // line in the sheet; the header is line 1
+    label?: string;
+    status: 'valid' | 'created' | 'updated' | 'unchanged' | 'error';
+    id?: string;
+    errors?: Record<string, string>;
+    warnings?: string[];
+    changes?: Record<string, { from: string; to: string }>;
+    files?: string[]; // scans attached from the ZIP
+}
+
+export interface ImportOutstanding {
+    employeeId: string;
+    name: string;
+    documentTypes: string[];
+}
+
+export interface ImportReport {
+    dryRun: boolean;
+    columns: ImportColumn[];
+    fields: string[];
+    total: number;
+    valid: number;
+    created: number;
+    updated: number;
+    unchanged: number;
+    failed: number;
+    rows: ImportRow[];
+    unmatchedFiles?: string[]; // document imports: scans no row took
+    outstanding?: ImportOutstanding[]; // mandatory documents still incomplete
+}
+
+export interface ImportOptions {
+    dryRun?: boolean;
+    skipInvalid?: boolean;
+    mapping?: Record<string, string>; // header → field
+    extra?: Record<string, string | Blob | undefined>;
+}
+
+// Posts a CSV/XLSX import. A 422 that carries a report (rows with errors)
+// resolves with it, so the caller can show the row-level errors.
+async function importSheet(endpoint: string, file: File, opts: ImportOptions = {}) {
+    const formData = new FormData();
+    formData.append('file', file);
+    if (opts.dryRun) formData.append('dryRun', 'true');
+    if (opts.skipInvalid) formData.append('skipInvalid', 'true');
+    if (opts.mapping) formData.append('mapping', JSON.stringify(opts.mapping));
+    Object.entries(opts.extra ?? {}).forEach(([key, value]) => {
+        if (value !== undefined && value !== '') formData.append(key, value);
+    });
+
+    const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+        method: 'POST',
+        headers: getAuthHeaders(),
+        body: formData,
+    });
+    const body = await response.json().catch(() => ({}));
+    if (!response.ok && !(response.status === 422 && body.data)) {
+        throw new ApiClientError(body.error || body.message || 'Import failed', response.status);
+    }
+    return body as { data: ImportReport; message?: string; error?: string };
+}
+
 // ── Pagination Types ──────────────────────────────────────────
 export interface PaginationMeta {
     page: number;
@@ -155,7 +422,7 @@ export const api = {
     companies: {
         list: () => fetcher<{ data: Company[] }>('/api/companies'),
         getById: (id: string) =>
-            fetcher<{ data: { company: Company; employees: { id: string; name: string; trade: string; status: string; photoUrl?: string | null; nationality?: string | null; complianceStatus: string; urgentDocType?: string | null }[] } }>(`/api/companies/${id}`),
+            fetcher<{ data: { company: Company; employees: { id: string; name: string; trade: string; status: string; photoUrl?: string | null; thumbnailUrl?: string | null; nationality?: string | null; complianceStatus: string; urgentDocType?: string | null }[] } }>(`/api/companies/${id}`),
         create: (data: CreateCompanyRequest) =>
             fetcher<{ data: Company; message: string }>('/api/companies', {
                 method: 'POST',
@@ -192,6 +459,8 @@ export const api = {
             fetcher<{ data: EmployeeWithCompany }>(
                 `/api/employees/${id}`
             ),
+        import: (file: File, opts: ImportOptions & { companyId?: string } = {}) =>
+            importSheet('/api/employees/import', file, { ...opts, extra: { companyId: opts.companyId } }),
         create: (data: CreateEmployeeRequest) =>
             fetcher<{ data: Employee; message: string }>('/api/employees', {
                 method: 'POST',
@@ -211,14 +480,27 @@ export const api = {
                 method: 'PATCH',
                 body: JSON.stringify(data),
             }),
+        // Moving to another company goes through transfer, not update
+        transfer: (id: string, data: TransferEmployeeRequest) =>
+            fetcher<{ data: TransferResult; message: string }>(`/api/employees/${id}/transfer`, {
+                method: 'POST',
+                body: JSON.stringify(data),
+            }),
+        employment: (id: string) =>
+            fetcher<{ data: EmploymentPeriod[] }>(`/api/employees/${id}/employment`),
         batchDelete: (ids: string[]) =>
             fetcher<{ message: string; deleted: number }>('/api/employees/batch-delete', {
                 method: 'POST',
                 body: JSON.stringify({ ids }),
             }),
+        // Field-level edits, newest first
+        history: (id: string) =>
+            fetcher<{ data: ChangeSet[] }>(`/api/employees/${id}/history`),
         getDependencyAlerts: (id: string) =>
             fetcher<{ data: DependencyAlert[] }>(`/api/employees/${id}/dependency-alerts`),
-        export: () => downloadFile('/api/employees/export', 'employees.csv'),
+        // Same filters and sort as list; no paging
+        export: (filters: Omit<EmployeeFilters, 'page' | 'limit'> = {}, opts?: ExportOptions) =>
+            exportFile('/api/employees/export', 'employees', { ...filters }, opts),
     },
 
     // ── Documents ─────────────────────────────────────────────
@@ -259,13 +541,55 @@ export const api = {
             fileName?: string;
             fileSize?: number;
             fileType?: string;
+            files?: AddDocumentFileRequest[];
         }) =>
             fetcher<{ data: DocumentWithCompliance; message: string }>(`/api/documents/${id}/renew`, {
                 method: 'POST',
                 body: JSON.stringify(data),
             }),
-        download: (id: string, filename: string = 'document') =>
-            downloadFile(`/api/documents/${id}/download`, filename),
+        // Several attachments download as a ZIP unless fileId picks one
+        download: (id: string, filename: string = 'document', copy?: IssuedCopy, fileId?: string) => {
+            const params = new URLSearchParams();
+            if (fileId) params.set('file', fileId);
+            if (copy) {
+                params.set('recipient', copy.recipient);
+                params.set('purpose', copy.purpose);
+                copy.redact?.forEach((r) => params.append('redact', r));
+            }
+            const qs = params.toString();
+            return downloadFile(`/api/documents/${id}/download${qs ? `?${qs}` : ''}`, filename);
+        },
+        downloads: (id: string) =>
+            fetcher<{ data: DocumentDownload[] }>(`/api/documents/${id}/downloads`),
+        history: (id: string) =>
+            fetcher<{ data: ChangeSet[] }>(`/api/documents/${id}/history`),
+        fileUrl: (id: string, fileId?: string) =>
+            fetcher<{ data: { url: string; expiresAt: string } }>(
+                `/api/documents/${id}/file-url${fileId ? `?file=${encodeURIComponent(fileId)}` : ''}`
+            ),
+        addFile: (id: string, data: AddDocumentFileRequest) =>
+            fetcher<{ data: DocumentFile[]; message: string }>(`/api/documents/${id}/files`, {
+                method: 'POST',
+                body: JSON.stringify(data),
+            }),
+        removeFile: (id: string, fileId: string) =>
+            fetcher<{ data: DocumentFile[]; message: string }>(`/api/documents/${id}/files/${fileId}`, {
+                method: 'DELETE',
+            }),
+        reorderFiles: (id: string, fileIds: string[]) =>
+            fetcher<{ data: DocumentFile[]; message: string }>(`/api/documents/${id}/files/order`, {
+                method: 'PUT',
+                body: JSON.stringify({ fileIds }),
+            }),
+        // scans: ZIP of files named <passport no>_<type>[_<label>].<ext>
+        import: (file: File, opts: ImportOptions & { scans?: File } = {}) =>
+            importSheet('/api/documents/import', file, { ...opts, extra: { scans: opts.scans } }),
+        extract: extractDocumentFields,
+        archive: (filter: DocumentArchiveFilter) =>
+            downloadFile('/api/documents/archive', 'documents.zip', filter),
+        // Filters take comma-separated lists for employee_id, document_type and status
+        export: (filters: DocumentExportFilters = {}, opts?: ExportOptions) =>
+            exportFile('/api/documents/export', 'documents', { ...filters }, opts),
     },
 
     // ── Salary ────────────────────────────────────────────────
@@ -299,8 +623,8 @@ export const api = {
                 method: 'PATCH',
                 body: JSON.stringify({ ids, status }),
             }),
-        export: (month: number, year: number) =>
-            downloadFile(`/api/salary/export?month=${month}&year=${year}`, `salary_${year}_${String(month).padStart(2, '0')}.csv`),
+        export: (month: number, year: number, filters: { status?: string; company_id?: string } = {}, opts?: ExportOptions) =>
+            exportFile('/api/salary/export', `salary_${year}_${String(month).padStart(2, '0')}`, { month, year, ...filters }, opts),
     },
 
     // ── Notifications ─────────────────────────────────────────
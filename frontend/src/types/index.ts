@@ -28,6 +28,8 @@ export interface Employee {
     mobile: string;
     joiningDate: string;
     photoUrl?: string | null;
+    thumbnailUrl?: string | null;
+    previewUrl?: string | null;
     gender?: string | null;
     dateOfBirth?: string | null;
     nationality?: string | null;
@@ -70,8 +72,37 @@ export interface Document {
     fileName: string;
     fileSize: number;
     fileType: string;
+    fileSha256?: string;
+    thumbnailUrl?: string;
+    previewUrl?: string;
     lastUpdated: string;
     createdAt: string;
+    /** Every attached file, in order; fileUrl … fileSha256 mirror the first */
+    files?: DocumentFile[];
+}
+
+/** One attachment of a document, e.g. the front or back of a card */
+export interface DocumentFile {
+    id: string;
+    position: number;
+    label: string | null;
+    fileUrl: string;
+    fileName: string;
+    fileSize: number;
+    fileType: string;
+    fileSha256?: string;
+    thumbnailUrl?: string;
+    previewUrl?: string;
+    createdAt: string;
+}
+
+export interface AddDocumentFileRequest {
+    fileUrl: string;
+    fileName: string;
+    fileSize: number;
+    fileType: string;
+    label?: string;
+    position?: number;
 }
 
 /** Document with computed compliance fields (returned from API) */
@@ -149,6 +180,27 @@ export interface ActivityLog {
     createdAt: string;
 }
 
+/** One field of an edit; null is an empty value */
+export interface FieldChange {
+    field: string; // column name, e.g. "expiry_date"
+    from: string | null;
+    to: string | null;
+}
+
+/** The fields one edit of an employee or document changed */
+export interface ChangeSet {
+    id: string;
+    entityType: 'employee' | 'document';
+    entityId: string;
+    action: string;
+    changes: FieldChange[];
+    userId: string | null;
+    userName?: string | null;
+    impersonatorId?: string | null;
+    impersonatorName?: string | null;
+    createdAt: string;
+}
+
 // ── Dashboard ─────────────────────────────────────────────────
 
 export interface DashboardMetrics {
@@ -232,6 +284,42 @@ export interface ExitEmployeeRequest {
     exitNotes?: string;
 }
 
+export interface TransferEmployeeRequest {
+    companyId: string;
+    effectiveDate: string; // YYYY-MM-DD, not in the future
+    reason: string;
+}
+
+/** A stretch of time with one company; endDate (exclusive) is null for the current one */
+export interface EmploymentPeriod {
+    id: string;
+    employeeId: string;
+    companyId: string;
+    companyName: string;
+    startDate: string;
+    endDate: string | null;
+    reason: string | null; // null for the first period
+    transferredBy: string | null;
+    transferredByName?: string | null;
+    createdAt: string;
+}
+
+/** A compliance rule that judges the employee's documents differently after a transfer */
+export interface RuleChange {
+    documentType: string;
+    field: 'gracePeriodDays' | 'finePerDay' | 'fineType' | 'fineCap' | 'isMandatory';
+    from: string;
+    to: string;
+}
+
+export interface TransferResult {
+    employee: Employee;
+    history: EmploymentPeriod[];
+    ruleChanges: RuleChange[];
+    addedDocuments: string[];
+    salaryRecordsMoved: number;
+}
+
 export interface CreateDocumentRequest {
     documentType: string;
     documentNumber?: string;
@@ -242,6 +330,8 @@ export interface CreateDocumentRequest {
     fileName: string;
     fileSize: number;
     fileType: string;
+    /** Several attachments instead of fileUrl … fileType */
+    files?: AddDocumentFileRequest[];
 }
 
 export interface CreateCompanyRequest {